@@ -6,11 +6,15 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
+	"expvar"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
@@ -488,18 +492,68 @@ func main() {
 	// Add logger middleware
 	handler = logger.Log(handler)
 
+	// Serve the metrics published with expvar on a separate address, so that
+	// they aren't exposed along with the public routes
+	if metricsAddr := os.Getenv("METRICS_ADDR"); metricsAddr != "" {
+		go func() {
+			log.Println(logTag, ": serving metrics on", metricsAddr)
+			log.Errorln(logTag, ": error serving metrics: ", http.ListenAndServe(metricsAddr, expvar.Handler()))
+		}()
+	}
+
 	// Listen and serve ...
 	addr := fmt.Sprintf("%s:%d", address, port)
 	log.Println(logTag, ":listening on", addr)
 	if https {
 		httpsCert := os.Getenv("HTTPS_CERT")
 		httpsKey := os.Getenv("HTTPS_KEY")
-		log.Fatal(http.ListenAndServeTLS(addr, httpsCert, httpsKey, handler))
+		tlsConfig, err := clientTLSConfig()
+		if err != nil {
+			log.Fatal(logTag, ": error configuring client certificate authentication: ", err)
+		}
+		server := &http.Server{
+			Addr:      addr,
+			Handler:   handler,
+			TLSConfig: tlsConfig,
+		}
+		log.Fatal(server.ListenAndServeTLS(httpsCert, httpsKey))
 	} else {
 		log.Fatal(http.ListenAndServe(addr, handler))
 	}
 }
 
+// clientTLSConfig returns the tls config to verify the client certificates against
+// the CA bundle defined by the `HTTPS_CLIENT_CA` env. It returns nil when mTLS is not
+// configured. `HTTPS_CLIENT_AUTH` can be set as `required` to reject the connections
+// without a valid client certificate, by default the certificate is only verified if given.
+func clientTLSConfig() (*tls.Config, error) {
+	clientCA := os.Getenv("HTTPS_CLIENT_CA")
+	if clientCA == "" {
+		return nil, nil
+	}
+	caBundle, err := ioutil.ReadFile(clientCA)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, fmt.Errorf("no valid certificates found in %s", clientCA)
+	}
+	clientAuth := tls.VerifyClientCertIfGiven
+	switch os.Getenv("HTTPS_CLIENT_AUTH") {
+	case "", "optional":
+	case "required":
+		clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("HTTPS_CLIENT_AUTH must be one of `optional` or `required`")
+	}
+	log.Println(logTag, ": client certificate authentication is enabled")
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: clientAuth,
+	}, nil
+}
+
 func syncPluginCache() {
 	// Only run for self hosted arc using arc-enterprise plan
 
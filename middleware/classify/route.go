@@ -0,0 +1,50 @@
+package classify
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+)
+
+// Route is the classification of a request by the plugin that serves it.
+type Route struct {
+	Name     string
+	Category category.Category
+	// ACL is nil for the routes that aren't classified by acl.
+	ACL  *acl.ACL
+	Op   op.Operation
+	Vars map[string]string
+}
+
+// Classifier classifies a request without serving it, it returns false
+// if the request doesn't match any of the plugin routes.
+type Classifier func(req *http.Request) (*Route, bool)
+
+var (
+	classifiersMu sync.RWMutex
+	classifiers   []Classifier
+)
+
+// RegisterClassifier registers the classifier of a plugin. The classifiers
+// are tried in the order of registration, which should follow the order in
+// which the plugin routes are registered to the router.
+func RegisterClassifier(c Classifier) {
+	classifiersMu.Lock()
+	defer classifiersMu.Unlock()
+	classifiers = append(classifiers, c)
+}
+
+// Classify returns the classification of the request by the first matching classifier.
+func Classify(req *http.Request) (*Route, bool) {
+	classifiersMu.RLock()
+	defer classifiersMu.RUnlock()
+	for _, c := range classifiers {
+		if route, ok := c(req); ok {
+			return route, true
+		}
+	}
+	return nil, false
+}
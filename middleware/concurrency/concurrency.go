@@ -0,0 +1,187 @@
+package concurrency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
+)
+
+const (
+	logTag              = "[concurrency]"
+	envLimits           = "CONCURRENCY_LIMITS"
+	envQueueSize        = "CONCURRENCY_QUEUE_SIZE"
+	envQueueTimeout     = "CONCURRENCY_QUEUE_TIMEOUT"
+	defaultLimitKey     = "default"
+	defaultQueueSize    = 100
+	defaultQueueTimeout = time.Second
+	retryAfter          = "1"
+	statsMetric         = "concurrency"
+)
+
+// ErrShed is returned when a request is shed because the limit of the
+// in-flight requests of its category is reached.
+var ErrShed = errors.New("too many concurrent requests, please retry later")
+
+var (
+	instance *Limiter
+	once     sync.Once
+)
+
+// Limiter limits the number of concurrent requests made to elasticsearch per
+// category. Limiter.Instance returns the singleton instance of the Limiter.
+type Limiter struct {
+	pools        map[string]*pool
+	queueTimeout time.Duration
+}
+
+// Instance returns the singleton instance of the limiter, configured by the env vars.
+func Instance() *Limiter {
+	once.Do(func() {
+		instance = newLimiter(getLimits(), getQueueSize(), getQueueTimeout())
+		expvar.Publish(statsMetric, expvar.Func(func() interface{} {
+			return instance.stats()
+		}))
+	})
+	return instance
+}
+
+// newLimiter returns the limiter with a pool per category of the limits, the
+// "default" limit applies to the categories without a limit.
+func newLimiter(limits map[string]int, queueSize int, queueTimeout time.Duration) *Limiter {
+	l := &Limiter{
+		pools:        make(map[string]*pool),
+		queueTimeout: queueTimeout,
+	}
+	for key, max := range limits {
+		l.pools[key] = newPool(max, queueSize)
+	}
+	return l
+}
+
+// getLimits returns the max in-flight requests per category, e.g.
+// `{"search": 50, "reactivesearch": 50, "default": 100}`. No limit applies if not set.
+func getLimits() map[string]int {
+	limits := make(map[string]int)
+	value := os.Getenv(envLimits)
+	if value == "" {
+		return limits
+	}
+	if err := json.Unmarshal([]byte(value), &limits); err != nil {
+		log.Errorln(logTag, ":", envLimits, "must be a JSON object of the max in-flight requests per category, no limit is applied:", err)
+		return map[string]int{}
+	}
+	for key, max := range limits {
+		if max <= 0 {
+			log.Errorln(logTag, ":", envLimits, "must define positive limits, no limit is applied for", key)
+			delete(limits, key)
+		}
+	}
+	return limits
+}
+
+func getQueueSize() int {
+	value := os.Getenv(envQueueSize)
+	if value == "" {
+		return defaultQueueSize
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		log.Errorln(logTag, ":", envQueueSize, "must be a non-negative integer, using the default value")
+		return defaultQueueSize
+	}
+	return size
+}
+
+func getQueueTimeout() time.Duration {
+	value := os.Getenv(envQueueTimeout)
+	if value == "" {
+		return defaultQueueTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		log.Errorln(logTag, ":", envQueueTimeout, "must be a valid duration, using the default value")
+		return defaultQueueTimeout
+	}
+	return timeout
+}
+
+func (l *Limiter) poolFor(c category.Category) *pool {
+	if p, ok := l.pools[c.String()]; ok {
+		return p
+	}
+	return l.pools[defaultLimitKey]
+}
+
+// Acquire waits for a slot of the request category, the returned func must be
+// called to release the slot. The requests made with the user credentials
+// have priority over the ones made with the permissions.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	reqCategory, err := category.FromContext(ctx)
+	if err != nil {
+		// the requests that aren't classified aren't limited
+		return func() {}, nil
+	}
+	p := l.poolFor(*reqCategory)
+	if p == nil {
+		return func() {}, nil
+	}
+	reqCredential, err := credential.FromContext(ctx)
+	highPriority := err == nil && reqCredential == credential.User
+	if err := p.acquire(ctx, highPriority, l.queueTimeout); err != nil {
+		return nil, err
+	}
+	return p.release, nil
+}
+
+func (l *Limiter) stats() map[string]map[string]int {
+	stats := make(map[string]map[string]int)
+	for key, p := range l.pools {
+		stats[key] = p.stats()
+	}
+	return stats
+}
+
+// Acquire waits for a slot of the request category with the singleton limiter.
+func Acquire(ctx context.Context) (func(), error) {
+	return Instance().Acquire(ctx)
+}
+
+// WriteBackShed writes the 503 response of a shed request.
+func WriteBackShed(req *http.Request, w http.ResponseWriter) {
+	w.Header().Set("Retry-After", retryAfter)
+	telemetry.WriteBackErrorWithTelemetry(req, w, ErrShed.Error(), http.StatusServiceUnavailable)
+}
+
+// Limit middleware limits the number of concurrent requests per category,
+// the requests are shed with a 503 status when the limit and its queue are full.
+func Limit() middleware.Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			release, err := Acquire(req.Context())
+			if err != nil {
+				if err == ErrShed {
+					WriteBackShed(req, w)
+					return
+				}
+				// the request is cancelled by the client
+				log.Warnln(logTag, ":", err)
+				return
+			}
+			defer release()
+			h(w, req)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package concurrency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// pool bounds the number of in-flight requests of a category, the requests
+// above the bound wait in a queue where the high priority requests are
+// served first.
+type pool struct {
+	mu        sync.Mutex
+	max       int
+	queueSize int
+	inFlight  int
+	high      *list.List
+	low       *list.List
+}
+
+// waiter is notified with true once it's handed a slot, and with false if it's shed.
+type waiter chan bool
+
+func newPool(max, queueSize int) *pool {
+	return &pool{
+		max:       max,
+		queueSize: queueSize,
+		high:      list.New(),
+		low:       list.New(),
+	}
+}
+
+// acquire waits for a slot until the timeout, ErrShed is returned if the queue
+// is full or the timeout expires. A high priority request arriving at a full
+// queue sheds the latest low priority request to take its place.
+func (p *pool) acquire(ctx context.Context, highPriority bool, timeout time.Duration) error {
+	p.mu.Lock()
+	if p.inFlight < p.max {
+		p.inFlight++
+		p.mu.Unlock()
+		return nil
+	}
+	if p.high.Len()+p.low.Len() >= p.queueSize {
+		if !highPriority || p.low.Len() == 0 {
+			p.mu.Unlock()
+			return ErrShed
+		}
+		shed := p.low.Remove(p.low.Back()).(waiter)
+		shed <- false
+	}
+	queue := p.low
+	if highPriority {
+		queue = p.high
+	}
+	w := make(waiter, 1)
+	el := queue.PushBack(w)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case granted := <-w:
+		if granted {
+			return nil
+		}
+		return ErrShed
+	case <-timer.C:
+		err = ErrShed
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case granted := <-w:
+		// notified while giving up, the slot must be released by the caller
+		if granted {
+			return nil
+		}
+		return ErrShed
+	default:
+		queue.Remove(el)
+		return err
+	}
+}
+
+// release hands the slot to the next waiter, if any.
+func (p *pool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, queue := range []*list.List{p.high, p.low} {
+		if el := queue.Front(); el != nil {
+			queue.Remove(el).(waiter) <- true
+			return
+		}
+	}
+	p.inFlight--
+}
+
+// stats returns the number of in-flight and queued requests.
+func (p *pool) stats() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return map[string]int{
+		"in_flight": p.inFlight,
+		"queued":    p.high.Len() + p.low.Len(),
+	}
+}
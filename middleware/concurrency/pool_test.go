@@ -0,0 +1,112 @@
+package concurrency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("should shed the requests when the queue is full", t, func() {
+		p := newPool(1, 1)
+		So(p.acquire(ctx, false, time.Second), ShouldBeNil)
+
+		done := make(chan error)
+		go func() { done <- p.acquire(ctx, false, time.Second) }()
+		time.Sleep(10 * time.Millisecond)
+		So(p.acquire(ctx, false, time.Second), ShouldEqual, ErrShed)
+
+		p.release()
+		So(<-done, ShouldBeNil)
+		So(p.stats()["in_flight"], ShouldEqual, 1)
+		p.release()
+		So(p.stats()["in_flight"], ShouldEqual, 0)
+	})
+
+	Convey("should shed the requests waiting for longer than the timeout", t, func() {
+		p := newPool(1, 1)
+		So(p.acquire(ctx, false, time.Second), ShouldBeNil)
+		So(p.acquire(ctx, false, 10*time.Millisecond), ShouldEqual, ErrShed)
+		So(p.stats()["queued"], ShouldEqual, 0)
+	})
+
+	Convey("should serve the high priority requests first", t, func() {
+		p := newPool(1, 2)
+		So(p.acquire(ctx, false, time.Second), ShouldBeNil)
+
+		order := make(chan string, 2)
+		go func() {
+			if p.acquire(ctx, false, time.Second) == nil {
+				order <- "low"
+			}
+		}()
+		time.Sleep(10 * time.Millisecond)
+		go func() {
+			if p.acquire(ctx, true, time.Second) == nil {
+				order <- "high"
+			}
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		p.release()
+		So(<-order, ShouldEqual, "high")
+		p.release()
+		So(<-order, ShouldEqual, "low")
+	})
+
+	Convey("should shed a low priority request for a high priority one", t, func() {
+		p := newPool(1, 1)
+		So(p.acquire(ctx, false, time.Second), ShouldBeNil)
+
+		low := make(chan error)
+		go func() { low <- p.acquire(ctx, false, time.Second) }()
+		time.Sleep(10 * time.Millisecond)
+
+		high := make(chan error)
+		go func() { high <- p.acquire(ctx, true, time.Second) }()
+		So(<-low, ShouldEqual, ErrShed)
+
+		p.release()
+		So(<-high, ShouldBeNil)
+	})
+}
+
+func TestLimit(t *testing.T) {
+	Convey("should respond with 503 and Retry-After when shedding", t, func() {
+		l := newLimiter(map[string]int{"search": 1}, 0, time.Second)
+		instance = l
+		once.Do(func() {})
+
+		req := httptest.NewRequest(http.MethodPost, "/books/_search", nil)
+		req.Header.Set("X-Enable-Telemetry", "false")
+		c := category.Search
+		ctx := category.NewContext(req.Context(), &c)
+		ctx = credential.NewContext(ctx, credential.Permission)
+		req = req.WithContext(ctx)
+
+		release, err := l.Acquire(ctx)
+		So(err, ShouldBeNil)
+		defer release()
+
+		w := httptest.NewRecorder()
+		Limit()(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})(w, req)
+		So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+		So(w.Header().Get("Retry-After"), ShouldEqual, retryAfter)
+
+		docs := category.Docs
+		release, err = l.Acquire(category.NewContext(context.Background(), &docs))
+		So(err, ShouldBeNil)
+		release()
+	})
+}
@@ -0,0 +1,148 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
+	"github.com/appbaseio/reactivesearch-api/util"
+	"github.com/appbaseio/reactivesearch-api/util/iplookup"
+	"github.com/ulule/limiter"
+)
+
+const (
+	envUserLimit            = "RATE_LIMIT_USER"
+	envIndexLimit           = "RATE_LIMIT_INDEX"
+	envUnauthenticatedLimit = "RATE_LIMIT_UNAUTHENTICATED"
+)
+
+// globalLimits are the limits applied regardless of the permission limits,
+// a nil rate isn't applied.
+type globalLimits struct {
+	// user limits the requests made with the credentials of each user.
+	user *limiter.Rate
+	// index limits the requests made to each index by all the credentials.
+	index *limiter.Rate
+	// unauthenticated limits the requests failing the authentication per client ip.
+	unauthenticated *limiter.Rate
+}
+
+// getGlobalLimits returns the limits defined by the env vars, formatted as
+// "<limit>-<period>" where the period is one of S, M, H or D, e.g. "100-S".
+func getGlobalLimits() globalLimits {
+	return globalLimits{
+		user:            getRate(envUserLimit),
+		index:           getRate(envIndexLimit),
+		unauthenticated: getRate(envUnauthenticatedLimit),
+	}
+}
+
+func getRate(env string) *limiter.Rate {
+	value := os.Getenv(env)
+	if value == "" {
+		return nil
+	}
+	rate, err := limiter.NewRateFromFormatted(value)
+	if err != nil || rate.Limit <= 0 {
+		log.Errorln(logTag, ":", env, `must be formatted as "<limit>-<S|M|H|D>", the limit isn't applied`)
+		return nil
+	}
+	return &rate
+}
+
+func userKey(username string) string {
+	return fmt.Sprintf("_user:%s", username)
+}
+
+func indexKey(index string) string {
+	return fmt.Sprintf("_index:%s", index)
+}
+
+func unauthenticatedKey(remoteIP string) string {
+	return fmt.Sprintf("_unauthenticated:%s", remoteIP)
+}
+
+// resolveIndices resolves the aliases and the index patterns of the request indices.
+var resolveIndices = util.ResolveIndices
+
+// concreteIndices returns the concrete indices of the request indices, so that a
+// request naming an alias and its index is counted once per index. The names are
+// only deduplicated when the indices can't be resolved.
+func concreteIndices(ctx context.Context, indices []string) []string {
+	if len(indices) == 0 {
+		return nil
+	}
+	resolved, err := resolveIndices(ctx, indices)
+	if err == nil {
+		return resolved
+	}
+	log.Errorln(logTag, ": unable to resolve the request indices :", err)
+	seen := make(map[string]bool)
+	var unique []string
+	for _, name := range indices {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// statusWriter records the status code written by the handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush sends the buffered data to the client, if the wrapped writer supports it.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// LimitUnauthenticated middleware limits the requests that fail the authentication
+// per client ip, it must precede the authentication middleware. Once the limit is
+// reached, the requests from the ip are rejected before their credentials are verified.
+func LimitUnauthenticated() middleware.Middleware {
+	return Instance().limitUnauthenticated
+}
+
+func (rl *Ratelimiter) limitUnauthenticated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		rate := rl.limits.unauthenticated
+		if rate == nil {
+			h(w, req)
+			return
+		}
+		key := unauthenticatedKey(iplookup.FromRequest(req))
+		l := rl.getLimiter(key, rate.Limit, rate.Period)
+		limitCtx, err := l.Peek(req.Context(), key)
+		if err != nil && rl.rejectOnStoreError(err) {
+			telemetry.WriteBackErrorWithTelemetry(req, w, "Rate limit store is unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if err == nil && limitCtx.Remaining <= 0 {
+			writeLimitExceeded(w, UnauthenticatedLimit, limitCtx)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, req)
+		if sw.status == http.StatusUnauthorized {
+			if _, err := l.Get(req.Context(), key); err != nil {
+				log.Errorln(logTag, ": unable to count the unauthenticated request :", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package ratelimiter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/index"
+	"github.com/appbaseio/reactivesearch-api/model/user"
+	"github.com/appbaseio/reactivesearch-api/util"
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/memory"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func serve(h http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h(w, req)
+	return w
+}
+
+func TestGlobalLimits(t *testing.T) {
+	aliases := map[string][]string{"books-alias": {"books"}}
+	resolveIndices = func(ctx context.Context, indices []string) ([]string, error) {
+		seen := make(map[string]bool)
+		var resolved []string
+		for _, name := range indices {
+			concrete, ok := aliases[name]
+			if !ok {
+				concrete = []string{name}
+			}
+			for _, index := range concrete {
+				if !seen[index] {
+					seen[index] = true
+					resolved = append(resolved, index)
+				}
+			}
+		}
+		return resolved, nil
+	}
+	defer func() { resolveIndices = util.ResolveIndices }()
+	ok := func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	unauthorized := func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+
+	Convey("should parse the global limits", t, func() {
+		rate := getRate("")
+		So(rate, ShouldBeNil)
+		defer os.Unsetenv(envUserLimit)
+		os.Setenv(envUserLimit, "10-M")
+		rate = getRate(envUserLimit)
+		So(rate.Limit, ShouldEqual, 10)
+		So(rate.Period, ShouldEqual, time.Minute)
+		os.Setenv(envUserLimit, "ten")
+		So(getRate(envUserLimit), ShouldBeNil)
+	})
+
+	Convey("should limit the unauthenticated requests per ip", t, func() {
+		rl := newRatelimiter(memory.NewStore(), true, 10, time.Minute)
+		rl.limits.unauthenticated = &limiter.Rate{Limit: 2, Period: time.Minute}
+		req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+
+		So(serve(rl.limitUnauthenticated(ok), req).Code, ShouldEqual, http.StatusOK)
+		So(serve(rl.limitUnauthenticated(unauthorized), req).Code, ShouldEqual, http.StatusUnauthorized)
+		So(serve(rl.limitUnauthenticated(ok), req).Code, ShouldEqual, http.StatusOK)
+		So(serve(rl.limitUnauthenticated(unauthorized), req).Code, ShouldEqual, http.StatusUnauthorized)
+		w := serve(rl.limitUnauthenticated(ok), req)
+		So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+		So(w.Header().Get(HeaderRetryAfter), ShouldNotBeEmpty)
+
+		other := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		other.RemoteAddr = "10.0.0.2:1234"
+		So(serve(rl.limitUnauthenticated(ok), other).Code, ShouldEqual, http.StatusOK)
+	})
+
+	Convey("should limit the requests of the users and to the indices", t, func() {
+		rl := newRatelimiter(memory.NewStore(), true, 10, time.Minute)
+		rl.limits.user = &limiter.Rate{Limit: 1, Period: time.Minute}
+		rl.limits.index = &limiter.Rate{Limit: 2, Period: time.Minute}
+		newRequest := func(username string) *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+			ctx := credential.NewContext(req.Context(), credential.User)
+			ctx = user.NewContext(ctx, &user.User{Username: username})
+			ctx = index.NewContext(ctx, []string{"books"})
+			return req.WithContext(ctx)
+		}
+
+		w := serve(rl.rateLimit(ok), newRequest("foo"))
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get(HeaderLimit), ShouldEqual, "1")
+		So(w.Header().Get(HeaderRemaining), ShouldEqual, "0")
+		So(serve(rl.rateLimit(ok), newRequest("foo")).Code, ShouldEqual, http.StatusTooManyRequests)
+
+		So(serve(rl.rateLimit(ok), newRequest("bar")).Code, ShouldEqual, http.StatusOK)
+		So(serve(rl.rateLimit(ok), newRequest("baz")).Code, ShouldEqual, http.StatusTooManyRequests)
+	})
+	Convey("should count an alias and its index once", t, func() {
+		rl := newRatelimiter(memory.NewStore(), true, 10, time.Minute)
+		rl.limits.index = &limiter.Rate{Limit: 1, Period: time.Minute}
+		newRequest := func(indices ...string) *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/_search", nil)
+			ctx := credential.NewContext(req.Context(), credential.User)
+			ctx = user.NewContext(ctx, &user.User{Username: "foo"})
+			ctx = index.NewContext(ctx, indices)
+			return req.WithContext(ctx)
+		}
+
+		So(serve(rl.rateLimit(ok), newRequest("books", "books-alias")).Code, ShouldEqual, http.StatusOK)
+		So(serve(rl.rateLimit(ok), newRequest("books-alias")).Code, ShouldEqual, http.StatusTooManyRequests)
+	})
+
+	Convey("should delegate the flushes to the wrapped writer", t, func() {
+		w := httptest.NewRecorder()
+		var sw http.ResponseWriter = &statusWriter{ResponseWriter: w}
+		flusher, ok := sw.(http.Flusher)
+		So(ok, ShouldBeTrue)
+		flusher.Flush()
+		So(w.Flushed, ShouldBeTrue)
+	})
+}
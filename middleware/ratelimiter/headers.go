@@ -0,0 +1,63 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/appbaseio/reactivesearch-api/util"
+	"github.com/ulule/limiter"
+)
+
+// Limits that can be exceeded by a request.
+const (
+	CategoryLimit        = "category"
+	IPLimit              = "ip"
+	UserLimit            = "user"
+	IndexLimit           = "index"
+	UnauthenticatedLimit = "unauthenticated"
+)
+
+// Headers describing the rate limit of the request category.
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// secondsUntil returns the number of seconds until the limit is reset.
+func secondsUntil(reset int64) int64 {
+	seconds := reset - time.Now().Unix()
+	if seconds < 0 {
+		return 0
+	}
+	return seconds
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, c limiter.Context) {
+	w.Header().Set(HeaderLimit, strconv.FormatInt(c.Limit, 10))
+	w.Header().Set(HeaderRemaining, strconv.FormatInt(c.Remaining, 10))
+	w.Header().Set(HeaderReset, strconv.FormatInt(secondsUntil(c.Reset), 10))
+}
+
+// writeLimitExceeded writes the 429 response with the exceeded limit and the
+// number of seconds after which the request can be retried.
+func writeLimitExceeded(w http.ResponseWriter, exceededLimit string, c limiter.Context) {
+	retryAfter := secondsUntil(c.Reset)
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set(HeaderRetryAfter, strconv.FormatInt(retryAfter, 10))
+
+	code := http.StatusTooManyRequests
+	raw, _ := json.Marshal(map[string]interface{}{
+		"code":        code,
+		"status":      http.StatusText(code),
+		"message":     "Rate limit exceeded",
+		"limit":       exceededLimit,
+		"retry_after": retryAfter,
+	})
+	util.WriteBackRaw(w, raw, code)
+}
@@ -0,0 +1,57 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/memory"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	Convey("should count the requests against the limit", t, func() {
+		rl := newRatelimiter(nil, true, 0, time.Minute)
+		rl.SetStore(memory.NewStore(), true)
+
+		c, exceeded, err := rl.limitExceeded("foo:search", 2, time.Second)
+		So(err, ShouldBeNil)
+		So(exceeded, ShouldBeFalse)
+		So(c.Limit, ShouldEqual, 2)
+		So(c.Remaining, ShouldEqual, 1)
+
+		c, exceeded, err = rl.limitExceeded("foo:search", 2, time.Second)
+		So(err, ShouldBeNil)
+		So(exceeded, ShouldBeFalse)
+		So(c.Remaining, ShouldEqual, 0)
+
+		c, exceeded, err = rl.limitExceeded("foo:search", 2, time.Second)
+		So(err, ShouldBeNil)
+		So(exceeded, ShouldBeTrue)
+		So(c.Remaining, ShouldEqual, 0)
+	})
+
+	Convey("should set the headers of the limit", t, func() {
+		w := httptest.NewRecorder()
+		setRateLimitHeaders(w, limiter.Context{Limit: 10, Remaining: 4, Reset: 0})
+		So(w.Header().Get(HeaderLimit), ShouldEqual, "10")
+		So(w.Header().Get(HeaderRemaining), ShouldEqual, "4")
+		So(w.Header().Get(HeaderReset), ShouldEqual, "0")
+	})
+
+	Convey("should write the exceeded limit", t, func() {
+		w := httptest.NewRecorder()
+		writeLimitExceeded(w, IPLimit, limiter.Context{Limit: 10, Reached: true})
+		So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+		So(w.Header().Get(HeaderRetryAfter), ShouldEqual, "1")
+
+		var body map[string]interface{}
+		So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+		So(body["limit"], ShouldEqual, IPLimit)
+		So(body["message"], ShouldEqual, "Rate limit exceeded")
+	})
+}
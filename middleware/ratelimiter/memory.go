@@ -0,0 +1,100 @@
+package ratelimiter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/common"
+)
+
+// memoryStore keeps the counters in the memory of the instance. The number of
+// counters is capped, the least recently used counters are dropped above the cap
+// and the expired counters are dropped as soon as they are reached.
+type memoryStore struct {
+	sync.Mutex
+	counters map[string]*list.Element
+	lru      *list.List
+	maxKeys  int
+}
+
+type memoryCounter struct {
+	key        string
+	value      int64
+	expiration time.Time
+}
+
+func newMemoryStore(maxKeys int) *memoryStore {
+	return &memoryStore{
+		counters: make(map[string]*list.Element),
+		lru:      list.New(),
+		maxKeys:  maxKeys,
+	}
+}
+
+// Get increments the counter of the key and returns its limit context.
+func (s *memoryStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	counter := s.counter(key, now)
+	if counter == nil {
+		counter = &memoryCounter{key: key, expiration: now.Add(rate.Period)}
+		s.counters[key] = s.lru.PushFront(counter)
+	}
+	counter.value++
+	s.evict(now)
+	return common.GetContextFromState(now, rate, counter.expiration, counter.value), nil
+}
+
+// Peek returns the limit context of the key without incrementing its counter.
+func (s *memoryStore) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	if counter := s.counter(key, now); counter != nil {
+		return common.GetContextFromState(now, rate, counter.expiration, counter.value), nil
+	}
+	return common.GetContextFromState(now, rate, now.Add(rate.Period), 0), nil
+}
+
+// Keys returns the number of counters kept by the store.
+func (s *memoryStore) Keys() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.lru.Len()
+}
+
+// counter returns the live counter of the key, the expired counter is dropped.
+func (s *memoryStore) counter(key string, now time.Time) *memoryCounter {
+	el, ok := s.counters[key]
+	if !ok {
+		return nil
+	}
+	counter := el.Value.(*memoryCounter)
+	if now.After(counter.expiration) {
+		s.remove(el)
+		return nil
+	}
+	s.lru.MoveToFront(el)
+	return counter
+}
+
+// evict drops the expired counters at the back of the list and the least
+// recently used counters above the cap.
+func (s *memoryStore) evict(now time.Time) {
+	for el := s.lru.Back(); el != nil; el = s.lru.Back() {
+		expired := now.After(el.Value.(*memoryCounter).expiration)
+		if !expired && (s.maxKeys <= 0 || s.lru.Len() <= s.maxKeys) {
+			return
+		}
+		s.remove(el)
+	}
+}
+
+func (s *memoryStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.counters, el.Value.(*memoryCounter).key)
+}
@@ -1,7 +1,9 @@
 package ratelimiter
 
 import (
+	"container/list"
 	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"sync"
@@ -12,21 +14,16 @@ import (
 	"github.com/appbaseio/reactivesearch-api/middleware"
 	"github.com/appbaseio/reactivesearch-api/model/category"
 	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/index"
 	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/model/user"
 	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
-	"github.com/appbaseio/reactivesearch-api/util"
 	"github.com/appbaseio/reactivesearch-api/util/iplookup"
+	"github.com/robfig/cron"
 	"github.com/ulule/limiter"
-	"github.com/ulule/limiter/drivers/store/memory"
 )
 
-const (
-	logTag          = "[ratelimiter]"
-	defaultRedisDB  = 0
-	defaultMaxRetry = 4
-	redisAddr       = ""
-	redisPassword   = ""
-)
+const logTag = "[ratelimiter]"
 
 var (
 	instance *Ratelimiter
@@ -38,20 +35,34 @@ var (
 // ratelimiter.Instance returns the singleton instance of the Ratelimiter.
 type Ratelimiter struct {
 	sync.Mutex
-	limiters map[string]*limiter.Limiter
+	limiters    map[string]*list.Element
+	lru         *list.List
+	maxKeys     int
+	idleTimeout time.Duration
+	store       Store
+	failOpen    bool
+	limits      globalLimits
 }
 
 // Instance returns the singleton instance of ratelimiter.
 func Instance() *Ratelimiter {
 	once.Do(func() {
-		instance = &Ratelimiter{
-			limiters: make(map[string]*limiter.Limiter),
-		}
+		maxKeys := getMaxKeys()
+		instance = newRatelimiter(newStore(maxKeys), isFailOpen(), maxKeys, getIdleTimeout())
+		instance.limits = getGlobalLimits()
+		expvar.Publish(keysMetric, expvar.Func(func() interface{} {
+			return instance.Keys()
+		}))
+
+		cronjob := cron.New()
+		cronjob.AddFunc("@every "+evictionInterval, instance.evictIdle)
+		cronjob.Start()
 	})
 	return instance
 }
 
-// Limit middleware limits the requests made to elasticsearch for each permission.
+// Limit middleware limits the requests made to elasticsearch for each permission,
+// as well as for each user and index when the global limits are defined.
 func Limit() middleware.Middleware {
 	return Instance().rateLimit
 }
@@ -93,109 +104,91 @@ func (rl *Ratelimiter) rateLimit(h http.HandlerFunc) http.HandlerFunc {
 			}
 
 			key := fmt.Sprintf("%s:%s", reqPermission.Username, *reqCategory)
-			if rl.limitExceededByACL(key, categoryLimit) {
-				util.WriteBackMessage(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			if !rl.allow(w, req, key, categoryLimit, 1*time.Second, CategoryLimit, true) {
 				return
 			}
 
 			// limit on IP per hour
 			ipLimit := reqPermission.GetIPLimit()
 			key = fmt.Sprintf("%s:%s", reqPermission.Username, remoteIP)
-			if rl.limitExceededByIP(key, ipLimit) {
-				util.WriteBackMessage(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			if !rl.allow(w, req, key, ipLimit, 1*time.Hour, IPLimit, false) {
 				return
 			}
 		}
 
-		h(w, req)
-	}
-}
-
-func (rl *Ratelimiter) limitExceededByACL(key string, aclLimit int64) bool {
-	period := 1 * time.Second
-	rem, _ := rl.peekLimit(key, aclLimit, period)
-	if rem <= 0 {
-		return true
-	}
-	rl.limit(key, aclLimit, period)
-	return false
-}
+		if reqCredential == credential.User && rl.limits.user != nil {
+			reqUser, err := user.FromContext(ctx)
+			if err != nil {
+				log.Errorln(logTag, ":", err)
+				telemetry.WriteBackErrorWithTelemetry(req, w, "An error occurred while validating rate limit", http.StatusInternalServerError)
+				return
+			}
+			rate := rl.limits.user
+			if !rl.allow(w, req, userKey(reqUser.Username), rate.Limit, rate.Period, UserLimit, true) {
+				return
+			}
+		}
 
-func (rl *Ratelimiter) limitExceededByIP(key string, ipLimit int64) bool {
-	period := 1 * time.Hour
-	rem, _ := rl.peekLimit(key, ipLimit, period)
-	if rem <= 0 {
-		return true
-	}
-	rl.limit(key, ipLimit, period)
-	return false
-}
+		if rl.limits.index != nil {
+			// the requests without indices aren't limited per index
+			indices, _ := index.FromContext(ctx)
+			rate := rl.limits.index
+			for _, name := range concreteIndices(ctx, indices) {
+				if !rl.allow(w, req, indexKey(name), rate.Limit, rate.Period, IndexLimit, false) {
+					return
+				}
+			}
+		}
 
-func (rl *Ratelimiter) peekLimit(key string, limit int64, period time.Duration) (int64, bool) {
-	l := rl.getLimiter(key, limit, period)
-	if c, err := l.Peek(context.Background(), key); err == nil {
-		return c.Remaining, c.Reached
+		h(w, req)
 	}
-	// an error getting the limiter context ...
-	return -1, false
 }
 
-func (rl *Ratelimiter) limit(key string, limit int64, period time.Duration) (int64, bool) {
-	l := rl.getLimiter(key, limit, period)
-	if c, err := l.Get(context.Background(), key); err == nil {
-		return c.Remaining, c.Reached
-	}
-	// an error getting the limiter context ...
-	return -1, false
+// SetStore replaces the store of the rate limits, the counters of the previous store are discarded.
+func (rl *Ratelimiter) SetStore(store Store, failOpen bool) {
+	rl.Lock()
+	defer rl.Unlock()
+	rl.store = store
+	rl.failOpen = failOpen
+	rl.limiters = make(map[string]*list.Element)
+	rl.lru.Init()
 }
 
-func (rl *Ratelimiter) getLimiter(key string, limit int64, period time.Duration) *limiter.Limiter {
+// rejectOnStoreError returns whether the request must be rejected because
+// the store of the rate limits is unavailable.
+func (rl *Ratelimiter) rejectOnStoreError(err error) bool {
+	log.Errorln(logTag, ": unable to read the rate limit from the store :", err)
 	rl.Lock()
 	defer rl.Unlock()
-	l, exists := rl.limiters[key]
-	if !exists {
-		l = rl.newLimiter(key, limit, period)
+	return !rl.failOpen
+}
+
+// allow counts the request against the limit of the key, the response is written
+// and false is returned if the request must be rejected.
+func (rl *Ratelimiter) allow(w http.ResponseWriter, req *http.Request, key string, limit int64, period time.Duration, limitName string, setHeaders bool) bool {
+	limitCtx, exceeded, err := rl.limitExceeded(key, limit, period)
+	if err != nil && rl.rejectOnStoreError(err) {
+		telemetry.WriteBackErrorWithTelemetry(req, w, "Rate limit store is unavailable", http.StatusServiceUnavailable)
+		return false
+	}
+	if err == nil && setHeaders {
+		setRateLimitHeaders(w, limitCtx)
 	}
-	if l.Rate.Limit != limit {
-		l.Rate.Limit = limit
+	if exceeded {
+		writeLimitExceeded(w, limitName, limitCtx)
+		return false
 	}
-	return l
+	return true
 }
 
-// A new instance for the given key is stored in the map each time this method is invoked.
-// The access must be mediated by some kind of synchronization mechanism to prevent concurrent
-// read/write operations to the map and vars.
-func (rl *Ratelimiter) newLimiter(key string, limit int64, period time.Duration) *limiter.Limiter {
-	store := memory.NewStore()
-	rate := limiter.Rate{
-		Limit:  limit,
-		Period: period,
+// limitExceeded counts the request against the limit, the returned context reflects the
+// state after the request is counted. The increment is atomic in the store, the decision
+// is made on its result so that the concurrent requests can't exceed the limit.
+func (rl *Ratelimiter) limitExceeded(key string, limit int64, period time.Duration) (limiter.Context, bool, error) {
+	l := rl.getLimiter(key, limit, period)
+	c, err := l.Get(context.Background(), key)
+	if err != nil {
+		return c, false, err
 	}
-	instance := limiter.New(store, rate)
-	rl.limiters[key] = instance
-	return instance
+	return c, c.Reached, nil
 }
-
-// func (rl *Ratelimiter) newLimiterWithRedis(key string, limit int64, period time.Duration) *limiter.Limiter {
-// 	option := &goredis.Options{
-// 		Addr:     redisAddr,
-// 		Password: redisPassword,
-// 		DB:       defaultRedisDB,
-// 	}
-// 	client := goredis.NewClient(option)
-// 	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
-// 		Prefix:   key,
-// 		MaxRetry: defaultMaxRetry,
-// 	})
-// 	if err != nil {
-// 		log.Printf("%s: cannot create redis store for the rate limiter: %v", logTag, err)
-// 		return nil
-// 	}
-// 	rate := limiter.Rate{
-// 		Limit:  limit,
-// 		Period: period,
-// 	}
-// 	instance := limiter.New(store, rate)
-// 	rl.limiters[key] = instance
-// 	return instance
-// }
@@ -0,0 +1,278 @@
+package ratelimiter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/common"
+)
+
+const maxIdleRedisConns = 16
+
+// redisError is an error reply of the redis server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisStore keeps the counters of the rate limits in a server speaking the
+// redis protocol, so that the limits are shared by all the instances.
+type redisStore struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	prefix   string
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+// NewRedisStore returns a store that keeps the counters in the redis server
+// at addr. The connections are made lazily, the store methods return an
+// error while the server is unavailable.
+func NewRedisStore(addr, password string, db int, timeout time.Duration) Store {
+	return &redisStore{
+		addr:     addr,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+		prefix:   redisKeyPrefix,
+	}
+}
+
+// Get increments the counter of the key, the counter expires after the rate period.
+func (s *redisStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	key = fmt.Sprintf("%s:%s", s.prefix, key)
+	now := time.Now()
+	period := strconv.FormatInt(int64(rate.Period/time.Millisecond), 10)
+
+	replies, err := s.pipeline(ctx,
+		[]string{"MULTI"},
+		[]string{"SET", key, "0", "PX", period, "NX"},
+		[]string{"INCR", key},
+		[]string{"PTTL", key},
+		[]string{"EXEC"},
+	)
+	if err != nil {
+		return limiter.Context{}, fmt.Errorf("limiter: cannot get value for %s: %v", key, err)
+	}
+	results, ok := replies[4].([]interface{})
+	if !ok || len(results) != 3 {
+		return limiter.Context{}, fmt.Errorf("limiter: cannot get value for %s: transaction aborted", key)
+	}
+	count, err := toInt64(results[1])
+	if err != nil {
+		return limiter.Context{}, fmt.Errorf("limiter: cannot get value for %s: %v", key, err)
+	}
+	ttl, err := toInt64(results[2])
+	if err != nil {
+		return limiter.Context{}, fmt.Errorf("limiter: cannot get value for %s: %v", key, err)
+	}
+	if ttl < 0 {
+		// the counter was set without an expiry, it must not be kept forever
+		if _, err := s.pipeline(ctx, []string{"PEXPIRE", key, period}); err != nil {
+			return limiter.Context{}, fmt.Errorf("limiter: cannot set expiry for %s: %v", key, err)
+		}
+		ttl = int64(rate.Period / time.Millisecond)
+	}
+	expiration := now.Add(time.Duration(ttl) * time.Millisecond)
+	return common.GetContextFromState(now, rate, expiration, count), nil
+}
+
+// Peek returns the counter of the key without incrementing it.
+func (s *redisStore) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	key = fmt.Sprintf("%s:%s", s.prefix, key)
+	now := time.Now()
+
+	replies, err := s.pipeline(ctx,
+		[]string{"GET", key},
+		[]string{"PTTL", key},
+	)
+	if err != nil {
+		return limiter.Context{}, fmt.Errorf("limiter: cannot peek value for %s: %v", key, err)
+	}
+	var count int64
+	if replies[0] != nil {
+		count, err = toInt64(replies[0])
+		if err != nil {
+			return limiter.Context{}, fmt.Errorf("limiter: cannot peek value for %s: %v", key, err)
+		}
+	}
+	expiration := now.Add(rate.Period)
+	if ttl, err := toInt64(replies[1]); err == nil && ttl > 0 {
+		expiration = now.Add(time.Duration(ttl) * time.Millisecond)
+	}
+	return common.GetContextFromState(now, rate, expiration, count), nil
+}
+
+// pipeline sends the commands at once and returns their replies, an error
+// reply to any of the commands is returned as the error.
+func (s *redisStore) pipeline(ctx context.Context, cmds ...[]string) ([]interface{}, error) {
+	conn, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	replies, err := conn.do(cmds...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// the state of the connection is unknown after a network error
+		conn.Close()
+		return nil, err
+	}
+	s.release(conn)
+	return replies, err
+}
+
+func (s *redisStore) conn() (*redisConn, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		conn := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return conn, nil
+	}
+	s.mu.Unlock()
+
+	netConn, err := net.DialTimeout("tcp", s.addr, s.timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: netConn, r: bufio.NewReader(netConn)}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+	var setup [][]string
+	if s.password != "" {
+		setup = append(setup, []string{"AUTH", s.password})
+	}
+	if s.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.db)})
+	}
+	if len(setup) > 0 {
+		if _, err := conn.do(setup...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (s *redisStore) release(conn *redisConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.idle) >= maxIdleRedisConns {
+		conn.Close()
+		return
+	}
+	s.idle = append(s.idle, conn)
+}
+
+// redisConn is a connection speaking the redis serialization protocol.
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// do writes the commands and reads a reply for each of them, all the replies
+// are read even if one of them is an error so the connection can be reused.
+func (c *redisConn) do(cmds ...[]string) ([]interface{}, error) {
+	w := bufio.NewWriter(c.Conn)
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "*%d\r\n", len(cmd))
+		for _, arg := range cmd {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(cmds))
+	var replyErr error
+	for i := range cmds {
+		reply, err := readReply(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := reply.(redisError); ok && replyErr == nil {
+			replyErr = e
+		}
+		replies[i] = reply
+	}
+	return replies, replyErr
+}
+
+// readReply reads a reply, the error replies are returned as a redisError value.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: invalid reply %q", line)
+	}
+	kind, value := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return redisError(value), nil
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", value)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid array length %q", value)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: invalid reply %q", line)
+}
+
+func toInt64(reply interface{}) (int64, error) {
+	switch value := reply.(type) {
+	case int64:
+		return value, nil
+	case []byte:
+		return strconv.ParseInt(string(value), 10, 64)
+	case redisError:
+		return 0, value
+	}
+	return 0, fmt.Errorf("redis: unexpected reply %v", reply)
+}
@@ -0,0 +1,253 @@
+package ratelimiter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/memory"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeRedis is a stand-in for a redis server supporting the commands used by the store.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu      sync.Mutex
+	values  map[string]int64
+	expires map[string]time.Time
+}
+
+func newFakeRedis(password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	s := &fakeRedis{
+		listener: listener,
+		password: password,
+		values:   make(map[string]int64),
+		expires:  make(map[string]time.Time),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) close() {
+	s.listener.Close()
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authenticated := s.password == ""
+	var queued [][]string
+	inMulti := false
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		cmd := make([]string, len(items))
+		for i, item := range items {
+			cmd[i] = string(item.([]byte))
+		}
+		name := strings.ToUpper(cmd[0])
+
+		var out string
+		switch {
+		case name == "AUTH":
+			authenticated = cmd[1] == s.password
+			out = "+OK\r\n"
+			if !authenticated {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			out = "-NOAUTH Authentication required.\r\n"
+		case name == "MULTI":
+			inMulti = true
+			out = "+OK\r\n"
+		case name == "EXEC":
+			out = fmt.Sprintf("*%d\r\n", len(queued))
+			for _, c := range queued {
+				out += s.exec(c)
+			}
+			queued, inMulti = nil, false
+		case inMulti:
+			queued = append(queued, cmd)
+			out = "+QUEUED\r\n"
+		default:
+			out = s.exec(cmd)
+		}
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) exec(cmd []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := ""
+	if len(cmd) > 1 {
+		key = cmd[1]
+	}
+	if expiry, ok := s.expires[key]; ok && time.Now().After(expiry) {
+		delete(s.values, key)
+		delete(s.expires, key)
+	}
+	switch strings.ToUpper(cmd[0]) {
+	case "SELECT":
+		return "+OK\r\n"
+	case "SET":
+		if _, ok := s.values[key]; ok {
+			return "$-1\r\n"
+		}
+		value, _ := strconv.ParseInt(cmd[2], 10, 64)
+		s.values[key] = value
+		ms, _ := strconv.Atoi(cmd[4])
+		s.expires[key] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return "+OK\r\n"
+	case "INCR":
+		s.values[key]++
+		return fmt.Sprintf(":%d\r\n", s.values[key])
+	case "GET":
+		value, ok := s.values[key]
+		if !ok {
+			return "$-1\r\n"
+		}
+		str := strconv.FormatInt(value, 10)
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(str), str)
+	case "PTTL":
+		if _, ok := s.values[key]; !ok {
+			return ":-2\r\n"
+		}
+		expiry, ok := s.expires[key]
+		if !ok {
+			return ":-1\r\n"
+		}
+		return fmt.Sprintf(":%d\r\n", time.Until(expiry).Milliseconds())
+	case "PEXPIRE":
+		ms, _ := strconv.Atoi(cmd[2])
+		s.expires[key] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return ":1\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	rate := limiter.Rate{Limit: 3, Period: time.Minute}
+
+	Convey("should share the counters between the stores", t, func() {
+		server := newFakeRedis("secret")
+		defer server.close()
+		first := NewRedisStore(server.addr(), "secret", 1, time.Second)
+		second := NewRedisStore(server.addr(), "secret", 1, time.Second)
+
+		c, err := first.Peek(ctx, "foo", rate)
+		So(err, ShouldBeNil)
+		So(c.Remaining, ShouldEqual, 3)
+
+		for i := 1; i <= 3; i++ {
+			store := first
+			if i%2 == 0 {
+				store = second
+			}
+			c, err = store.Get(ctx, "foo", rate)
+			So(err, ShouldBeNil)
+			So(c.Remaining, ShouldEqual, 3-i)
+			So(c.Reached, ShouldBeFalse)
+		}
+		c, err = second.Get(ctx, "foo", rate)
+		So(err, ShouldBeNil)
+		So(c.Reached, ShouldBeTrue)
+		So(c.Reset, ShouldBeGreaterThan, time.Now().Unix())
+
+		c, err = first.Peek(ctx, "bar", rate)
+		So(err, ShouldBeNil)
+		So(c.Remaining, ShouldEqual, 3)
+	})
+
+	Convey("should return the error replies", t, func() {
+		server := newFakeRedis("secret")
+		defer server.close()
+		store := NewRedisStore(server.addr(), "wrong", 0, time.Second)
+		_, err := store.Get(ctx, "foo", rate)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("should return an error when the server is unavailable", t, func() {
+		server := newFakeRedis("")
+		addr := server.addr()
+		server.close()
+		store := NewRedisStore(addr, "", 0, 100*time.Millisecond)
+		_, err := store.Peek(ctx, "foo", rate)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestFailMode(t *testing.T) {
+	server := newFakeRedis("")
+	addr := server.addr()
+	server.close()
+
+	Convey("should allow the requests when failing open", t, func() {
+		rl := newRatelimiter(nil, true, 0, time.Minute)
+		rl.SetStore(NewRedisStore(addr, "", 0, 100*time.Millisecond), true)
+		_, exceeded, err := rl.limitExceeded("foo:search", 1, time.Second)
+		So(err, ShouldNotBeNil)
+		So(exceeded, ShouldBeFalse)
+		So(rl.rejectOnStoreError(err), ShouldBeFalse)
+	})
+
+	Convey("should reject the requests when failing closed", t, func() {
+		rl := newRatelimiter(nil, true, 0, time.Minute)
+		rl.SetStore(NewRedisStore(addr, "", 0, 100*time.Millisecond), false)
+		_, _, err := rl.limitExceeded("foo:search", 1, time.Second)
+		So(rl.rejectOnStoreError(err), ShouldBeTrue)
+	})
+}
+
+func TestLimitExceeded(t *testing.T) {
+	Convey("should not exceed the limit with concurrent requests", t, func() {
+		rl := newRatelimiter(memory.NewStore(), true, 0, time.Minute)
+
+		var allowed int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, exceeded, err := rl.limitExceeded("bar:search", 5, time.Minute); err == nil && !exceeded {
+					atomic.AddInt32(&allowed, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		So(atomic.LoadInt32(&allowed), ShouldEqual, 5)
+	})
+}
@@ -0,0 +1,122 @@
+package ratelimiter
+
+import (
+	"container/list"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ulule/limiter"
+)
+
+const (
+	envMaxKeys         = "RATE_LIMIT_MAX_KEYS"
+	envIdleTimeout     = "RATE_LIMIT_IDLE_TIMEOUT"
+	defaultMaxKeys     = 100000
+	defaultIdleTimeout = 10 * time.Minute
+	evictionInterval   = "1m"
+	keysMetric         = "ratelimiter_keys"
+)
+
+// limiterEntry is a limiter of the registry along with its last use.
+type limiterEntry struct {
+	key      string
+	limiter  *limiter.Limiter
+	lastUsed time.Time
+}
+
+func newRatelimiter(store Store, failOpen bool, maxKeys int, idleTimeout time.Duration) *Ratelimiter {
+	return &Ratelimiter{
+		limiters:    make(map[string]*list.Element),
+		lru:         list.New(),
+		maxKeys:     maxKeys,
+		idleTimeout: idleTimeout,
+		store:       store,
+		failOpen:    failOpen,
+	}
+}
+
+func getMaxKeys() int {
+	value := os.Getenv(envMaxKeys)
+	if value == "" {
+		return defaultMaxKeys
+	}
+	maxKeys, err := strconv.Atoi(value)
+	if err != nil || maxKeys <= 0 {
+		log.Errorln(logTag, ":", envMaxKeys, "must be a positive integer, using the default value")
+		return defaultMaxKeys
+	}
+	return maxKeys
+}
+
+func getIdleTimeout() time.Duration {
+	value := os.Getenv(envIdleTimeout)
+	if value == "" {
+		return defaultIdleTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		log.Errorln(logTag, ":", envIdleTimeout, "must be a valid duration, using the default value")
+		return defaultIdleTimeout
+	}
+	return timeout
+}
+
+// Keys returns the number of keys tracked by the rate limiter.
+func (rl *Ratelimiter) Keys() int {
+	rl.Lock()
+	defer rl.Unlock()
+	return rl.lru.Len()
+}
+
+// getLimiter returns the limiter of the key, the least recently used keys are
+// evicted when the number of keys exceeds the cap. The counters are kept by the
+// store, so an evicted key starts over from the counter of the store. The limiters
+// are shared by the concurrent requests, so a changed rate gets a new limiter.
+func (rl *Ratelimiter) getLimiter(key string, limit int64, period time.Duration) *limiter.Limiter {
+	rl.Lock()
+	defer rl.Unlock()
+	now := time.Now()
+	rate := limiter.Rate{
+		Limit:  limit,
+		Period: period,
+	}
+	if el, ok := rl.limiters[key]; ok {
+		entry := el.Value.(*limiterEntry)
+		entry.lastUsed = now
+		rl.lru.MoveToFront(el)
+		if entry.limiter.Rate != rate {
+			entry.limiter = limiter.New(rl.store, rate)
+		}
+		return entry.limiter
+	}
+
+	l := limiter.New(rl.store, rate)
+	rl.limiters[key] = rl.lru.PushFront(&limiterEntry{key: key, limiter: l, lastUsed: now})
+	for rl.maxKeys > 0 && rl.lru.Len() > rl.maxKeys {
+		rl.remove(rl.lru.Back())
+	}
+	return l
+}
+
+// evictIdle removes the keys that haven't been used for the idle timeout.
+func (rl *Ratelimiter) evictIdle() {
+	rl.Lock()
+	defer rl.Unlock()
+	cutoff := time.Now().Add(-rl.idleTimeout)
+	evicted := 0
+	for el := rl.lru.Back(); el != nil && el.Value.(*limiterEntry).lastUsed.Before(cutoff); el = rl.lru.Back() {
+		rl.remove(el)
+		evicted++
+	}
+	if evicted > 0 {
+		log.Debugln(logTag, ": evicted", evicted, "idle keys,", rl.lru.Len(), "keys remaining")
+	}
+}
+
+func (rl *Ratelimiter) remove(el *list.Element) {
+	rl.lru.Remove(el)
+	delete(rl.limiters, el.Value.(*limiterEntry).key)
+}
@@ -0,0 +1,82 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/memory"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistry(t *testing.T) {
+	Convey("should evict the least recently used keys above the cap", t, func() {
+		rl := newRatelimiter(memory.NewStore(), true, 2, time.Minute)
+		rl.getLimiter("a", 1, time.Second)
+		rl.getLimiter("b", 1, time.Second)
+		rl.getLimiter("a", 1, time.Second)
+		rl.getLimiter("c", 1, time.Second)
+		So(rl.Keys(), ShouldEqual, 2)
+		_, ok := rl.limiters["b"]
+		So(ok, ShouldBeFalse)
+		_, ok = rl.limiters["a"]
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("should evict the idle keys", t, func() {
+		rl := newRatelimiter(memory.NewStore(), true, 10, time.Minute)
+		rl.getLimiter("a", 1, time.Second)
+		rl.getLimiter("b", 1, time.Second)
+		rl.limiters["a"].Value.(*limiterEntry).lastUsed = time.Now().Add(-2 * time.Minute)
+		rl.lru.MoveToBack(rl.limiters["a"])
+		rl.evictIdle()
+		So(rl.Keys(), ShouldEqual, 1)
+		_, ok := rl.limiters["b"]
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("should keep the counters of the evicted keys in the store", t, func() {
+		rl := newRatelimiter(memory.NewStore(), true, 1, time.Minute)
+		_, exceeded, err := rl.limitExceeded("a", 1, time.Second)
+		So(err, ShouldBeNil)
+		So(exceeded, ShouldBeFalse)
+		rl.getLimiter("b", 1, time.Second)
+		_, exceeded, err = rl.limitExceeded("a", 1, time.Second)
+		So(err, ShouldBeNil)
+		So(exceeded, ShouldBeTrue)
+	})
+	Convey("should replace the limiter of a key when its rate changes", t, func() {
+		rl := newRatelimiter(memory.NewStore(), true, 10, time.Minute)
+		l := rl.getLimiter("a", 1, time.Second)
+		So(rl.getLimiter("a", 1, time.Second), ShouldEqual, l)
+		updated := rl.getLimiter("a", 2, time.Second)
+		So(updated, ShouldNotEqual, l)
+		So(l.Rate.Limit, ShouldEqual, 1)
+		So(updated.Rate.Limit, ShouldEqual, 2)
+	})
+
+	Convey("should cap the counters of the memory store", t, func() {
+		store := newMemoryStore(2)
+		rate := limiter.Rate{Limit: 1, Period: time.Minute}
+		store.Get(context.Background(), "a", rate)
+		store.Get(context.Background(), "b", rate)
+		store.Get(context.Background(), "a", rate)
+		store.Get(context.Background(), "c", rate)
+		So(store.Keys(), ShouldEqual, 2)
+		lctx, _ := store.Peek(context.Background(), "a", rate)
+		So(lctx.Reached, ShouldBeTrue)
+		lctx, _ = store.Peek(context.Background(), "b", rate)
+		So(lctx.Remaining, ShouldEqual, 1)
+	})
+
+	Convey("should drop the expired counters of the memory store", t, func() {
+		store := newMemoryStore(10)
+		store.Get(context.Background(), "a", limiter.Rate{Limit: 1, Period: time.Millisecond})
+		time.Sleep(2 * time.Millisecond)
+		lctx, _ := store.Get(context.Background(), "b", limiter.Rate{Limit: 1, Period: time.Minute})
+		So(lctx.Reached, ShouldBeFalse)
+		So(store.Keys(), ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,102 @@
+package ratelimiter
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ulule/limiter"
+)
+
+const (
+	envStore            = "RATE_LIMIT_STORE"
+	envFailMode         = "RATE_LIMIT_FAIL_MODE"
+	envRedisAddr        = "RATE_LIMIT_REDIS_ADDR"
+	envRedisPassword    = "RATE_LIMIT_REDIS_PASSWORD"
+	envRedisDB          = "RATE_LIMIT_REDIS_DB"
+	envRedisTimeout     = "RATE_LIMIT_REDIS_TIMEOUT"
+	defaultRedisAddr    = "localhost:6379"
+	defaultRedisDB      = 0
+	defaultRedisTimeout = 500 * time.Millisecond
+	redisKeyPrefix      = "reactivesearch:ratelimit"
+)
+
+// Types of the stores of the rate limits.
+const (
+	MemoryStore = "memory"
+	RedisStore  = "redis"
+)
+
+// Modes of handling the requests when the store of the rate limits is unavailable.
+const (
+	FailOpen   = "open"
+	FailClosed = "closed"
+)
+
+// Store persists the counters of the rate limits. The counters kept in the
+// memory are limited per instance, a shared store must be used to limit them
+// across the instances.
+type Store interface {
+	limiter.Store
+}
+
+// newStore returns the store configured by the env vars, it defaults to the memory
+// store which keeps at most maxKeys counters.
+func newStore(maxKeys int) Store {
+	storeType := strings.ToLower(os.Getenv(envStore))
+	switch storeType {
+	case "", MemoryStore:
+		return newMemoryStore(maxKeys)
+	case RedisStore:
+		addr := os.Getenv(envRedisAddr)
+		if addr == "" {
+			addr = defaultRedisAddr
+		}
+		return NewRedisStore(addr, os.Getenv(envRedisPassword), getRedisDB(), getRedisTimeout())
+	default:
+		log.Errorln(logTag, ":", envStore, "must be one of", MemoryStore, "or", RedisStore, ", using the memory store")
+		return newMemoryStore(maxKeys)
+	}
+}
+
+func getRedisDB() int {
+	value := os.Getenv(envRedisDB)
+	if value == "" {
+		return defaultRedisDB
+	}
+	db, err := strconv.Atoi(value)
+	if err != nil || db < 0 {
+		log.Errorln(logTag, ":", envRedisDB, "must be a non-negative integer, using the default value")
+		return defaultRedisDB
+	}
+	return db
+}
+
+func getRedisTimeout() time.Duration {
+	value := os.Getenv(envRedisTimeout)
+	if value == "" {
+		return defaultRedisTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		log.Errorln(logTag, ":", envRedisTimeout, "must be a valid duration, using the default value")
+		return defaultRedisTimeout
+	}
+	return timeout
+}
+
+// isFailOpen returns whether the requests are allowed when the store is unavailable.
+func isFailOpen() bool {
+	switch strings.ToLower(os.Getenv(envFailMode)) {
+	case "", FailOpen:
+		return true
+	case FailClosed:
+		return false
+	default:
+		log.Errorln(logTag, ":", envFailMode, "must be one of", FailOpen, "or", FailClosed, ", failing open")
+		return true
+	}
+}
@@ -1,18 +1,22 @@
 package validate
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/model/acl"
 	"github.com/appbaseio/reactivesearch-api/model/credential"
 	"github.com/appbaseio/reactivesearch-api/model/index"
 	"github.com/appbaseio/reactivesearch-api/model/permission"
 	"github.com/appbaseio/reactivesearch-api/model/user"
 	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
+	"github.com/appbaseio/reactivesearch-api/util"
 )
 
 // Indices returns a middleware that validates the request indices against the credential indices.
@@ -68,6 +72,36 @@ func indices(h http.HandlerFunc) http.HandlerFunc {
 			}
 		}
 
+		// the index rules of a permission are applied to the permission in the
+		// context, the following middleware validate the request against it
+		if reqCredential == credential.Permission {
+			reqPermission, err := permission.FromContext(ctx)
+			if err != nil {
+				log.Errorln(logTag, ":", err)
+				telemetry.WriteBackErrorWithTelemetry(req, w, errMsg, http.StatusInternalServerError)
+				return
+			}
+			ruleIndices := reqIndices
+			if len(reqPermission.IndexRules) > 0 {
+				ruleIndices, err = addressedIndices(req, reqIndices)
+				if err == nil {
+					ruleIndices, err = util.ResolveIndices(ctx, ruleIndices)
+				}
+				if err != nil {
+					log.Errorln(logTag, ": unable to resolve the request indices:", err)
+					telemetry.WriteBackErrorWithTelemetry(req, w, errMsg, http.StatusInternalServerError)
+					return
+				}
+			}
+			effective, err := reqPermission.ForIndices(ruleIndices...)
+			if err != nil {
+				log.Errorln(logTag, ":", err)
+				telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			req = req.WithContext(permission.NewContext(ctx, effective))
+		}
+
 		h(w, req)
 	}
 }
@@ -109,3 +143,21 @@ func allowedIndexAccess(ctx context.Context, c credential.Credential, indices []
 		return false, fmt.Errorf("illegal credential state reached")
 	}
 }
+
+// addressedIndices returns the indices of the path and the ones named in the body of
+// the request, the body is restored for the next handlers.
+func addressedIndices(req *http.Request, reqIndices []string) ([]string, error) {
+	reqACL, err := acl.FromContext(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if req.Body != nil {
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return index.Addressed(*reqACL, req.URL.Path, reqIndices, body), nil
+}
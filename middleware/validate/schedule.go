@@ -0,0 +1,59 @@
+package validate
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
+)
+
+// PermissionSchedule returns a middleware that checks whether a permission is used within its schedule.
+func PermissionSchedule() middleware.Middleware {
+	return validateSchedule
+}
+
+func validateSchedule(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		reqCredential, err := credential.FromContext(ctx)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if reqCredential == credential.Permission {
+			reqPermission, err := permission.FromContext(ctx)
+			if err != nil {
+				log.Errorln(logTag, ":", err)
+				telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if reqPermission.Schedule != nil {
+				active, err := reqPermission.Schedule.IsActive(time.Now())
+				if err != nil {
+					log.Errorln(logTag, ":", err)
+					telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if !active {
+					msg := fmt.Sprintf("permission with username=%s can't be used outside of its schedule", reqPermission.Username)
+					w.Header().Set("www-authenticate", "Basic realm=\"Authentication Required\"")
+					telemetry.WriteBackErrorWithTelemetry(req, w, msg, http.StatusUnauthorized)
+					return
+				}
+			}
+		}
+
+		h(w, req)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/appbaseio/reactivesearch-api/errors"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/model/user"
 )
 
 type contextKey string
@@ -44,3 +46,23 @@ func FromContext(ctx context.Context) (Credential, error) {
 	}
 	return reqCredential, nil
 }
+
+// IDFromContext returns the id of the user or permission that made the request,
+// an empty string is returned if the request isn't authenticated.
+func IDFromContext(ctx context.Context) string {
+	reqCredential, err := FromContext(ctx)
+	if err != nil {
+		return ""
+	}
+	switch reqCredential {
+	case User:
+		if reqUser, err := user.FromContext(ctx); err == nil {
+			return reqUser.Id()
+		}
+	case Permission:
+		if reqPermission, err := permission.FromContext(ctx); err == nil {
+			return reqPermission.Id()
+		}
+	}
+	return ""
+}
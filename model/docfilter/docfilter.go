@@ -0,0 +1,109 @@
+package docfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrSuggest is returned for the searches with suggesters, the suggestions are built
+// from the terms of the index and can't be restricted to the documents of the filter.
+var ErrSuggest = errors.New("suggest isn't allowed along with a document filter")
+
+// ErrGlobalAggregation is returned for the searches with global aggregations, which
+// ignore the query and so the filter.
+var ErrGlobalAggregation = errors.New("global aggregations aren't allowed along with a document filter")
+
+// ErrUnfiltered is returned for the explain and term vectors requests, which can't be
+// restricted to the documents of the filter.
+var ErrUnfiltered = errors.New("the explain and term vectors APIs aren't allowed along with a document filter")
+
+// ApplyToSearch adds the filter to the query of a search request body, the original
+// query is preserved as a `must` clause so that the scoring isn't affected. The query
+// string of the `q` param, if any, must be passed in order to be combined with the filter.
+// It returns ErrSuggest if the body has a suggest section and ErrGlobalAggregation if
+// it has a global aggregation at any level.
+func ApplyToSearch(body map[string]interface{}, filter map[string]interface{}, queryString string) error {
+	if _, ok := body["suggest"]; ok {
+		return ErrSuggest
+	}
+	if hasGlobalAggregation(body) {
+		return ErrGlobalAggregation
+	}
+	must := []interface{}{}
+	if query, ok := body["query"]; ok && query != nil {
+		must = append(must, query)
+	}
+	if queryString != "" {
+		must = append(must, map[string]interface{}{
+			"query_string": map[string]interface{}{
+				"query": queryString,
+			},
+		})
+	}
+	if len(must) == 0 {
+		must = append(must, map[string]interface{}{
+			"match_all": map[string]interface{}{},
+		})
+	}
+	body["query"] = map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must":   must,
+			"filter": []interface{}{filter},
+		},
+	}
+	return nil
+}
+
+// hasGlobalAggregation walks the aggregations of a search body, or of an aggregation,
+// looking for a global aggregation.
+func hasGlobalAggregation(body map[string]interface{}) bool {
+	for _, key := range []string{"aggs", "aggregations"} {
+		aggs, ok := body[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, agg := range aggs {
+			aggBody, ok := agg.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, ok := aggBody["global"]; ok {
+				return true
+			}
+			if hasGlobalAggregation(aggBody) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ApplyToMsearch adds the filter to the query of each search in an `_msearch` request body.
+func ApplyToMsearch(body []byte, filter map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	isHeader := true
+	for _, line := range bytes.Split(bytes.TrimRight(body, "\r\n"), []byte("\n")) {
+		if isHeader {
+			// an empty header line is allowed and targets the indices of the url
+			buf.Write(line)
+		} else {
+			search := make(map[string]interface{})
+			if err := json.Unmarshal(line, &search); err != nil {
+				return nil, fmt.Errorf("can't parse the msearch body: %v", err)
+			}
+			if err := ApplyToSearch(search, filter, ""); err != nil {
+				return nil, err
+			}
+			raw, err := json.Marshal(search)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(raw)
+		}
+		buf.WriteByte('\n')
+		isHeader = !isHeader
+	}
+	return buf.Bytes(), nil
+}
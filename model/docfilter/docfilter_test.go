@@ -0,0 +1,98 @@
+package docfilter
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var tenantFilter = map[string]interface{}{
+	"term": map[string]interface{}{"tenant_id": "acme"},
+}
+
+func toJSON(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+func TestApplyToSearch(t *testing.T) {
+	Convey("should wrap the query of the body", t, func() {
+		body := map[string]interface{}{
+			"query": map[string]interface{}{"match": map[string]interface{}{"title": "go"}},
+			"size":  10,
+		}
+		So(ApplyToSearch(body, tenantFilter, ""), ShouldBeNil)
+		So(toJSON(body), ShouldEqual, `{"query":{"bool":{"filter":[{"term":{"tenant_id":"acme"}}],"must":[{"match":{"title":"go"}}]}},"size":10}`)
+	})
+	Convey("should match all the documents without a query", t, func() {
+		body := map[string]interface{}{}
+		So(ApplyToSearch(body, tenantFilter, ""), ShouldBeNil)
+		So(toJSON(body), ShouldEqual, `{"query":{"bool":{"filter":[{"term":{"tenant_id":"acme"}}],"must":[{"match_all":{}}]}}}`)
+	})
+	Convey("should combine the query string", t, func() {
+		body := map[string]interface{}{}
+		So(ApplyToSearch(body, tenantFilter, "title:go"), ShouldBeNil)
+		So(toJSON(body), ShouldEqual, `{"query":{"bool":{"filter":[{"term":{"tenant_id":"acme"}}],"must":[{"query_string":{"query":"title:go"}}]}}}`)
+	})
+	Convey("should reject the suggesters", t, func() {
+		body := map[string]interface{}{
+			"suggest": map[string]interface{}{
+				"titles": map[string]interface{}{"text": "go", "term": map[string]interface{}{"field": "title"}},
+			},
+		}
+		So(ApplyToSearch(body, tenantFilter, ""), ShouldEqual, ErrSuggest)
+	})
+	Convey("should reject the global aggregations at any level", t, func() {
+		body := map[string]interface{}{
+			"aggs": map[string]interface{}{
+				"all": map[string]interface{}{
+					"global": map[string]interface{}{},
+					"aggs": map[string]interface{}{
+						"top": map[string]interface{}{"top_hits": map[string]interface{}{}},
+					},
+				},
+			},
+		}
+		So(ApplyToSearch(body, tenantFilter, ""), ShouldEqual, ErrGlobalAggregation)
+		body = map[string]interface{}{
+			"aggregations": map[string]interface{}{
+				"genres": map[string]interface{}{
+					"terms": map[string]interface{}{"field": "genre"},
+					"aggregations": map[string]interface{}{
+						"all": map[string]interface{}{"global": map[string]interface{}{}},
+					},
+				},
+			},
+		}
+		So(ApplyToSearch(body, tenantFilter, ""), ShouldEqual, ErrGlobalAggregation)
+	})
+	Convey("should accept the other aggregations", t, func() {
+		body := map[string]interface{}{
+			"aggs": map[string]interface{}{
+				"global": map[string]interface{}{"terms": map[string]interface{}{"field": "genre"}},
+			},
+		}
+		So(ApplyToSearch(body, tenantFilter, ""), ShouldBeNil)
+	})
+}
+
+func TestApplyToMsearch(t *testing.T) {
+	Convey("should filter each search of the body", t, func() {
+		body := "{\"index\":\"books\"}\n{\"query\":{\"match_all\":{}}}\n\n{}\n"
+		filtered, err := ApplyToMsearch([]byte(body), tenantFilter)
+		So(err, ShouldBeNil)
+		So(string(filtered), ShouldEqual, "{\"index\":\"books\"}\n"+
+			`{"query":{"bool":{"filter":[{"term":{"tenant_id":"acme"}}],"must":[{"match_all":{}}]}}}`+"\n"+
+			"\n"+
+			`{"query":{"bool":{"filter":[{"term":{"tenant_id":"acme"}}],"must":[{"match_all":{}}]}}}`+"\n")
+	})
+	Convey("should reject the suggesters of any search", t, func() {
+		_, err := ApplyToMsearch([]byte("{}\n{}\n{}\n{\"suggest\":{}}\n"), tenantFilter)
+		So(err, ShouldEqual, ErrSuggest)
+	})
+	Convey("should fail with an invalid body", t, func() {
+		_, err := ApplyToMsearch([]byte("{}\nnot-json\n"), tenantFilter)
+		So(err, ShouldNotBeNil)
+	})
+}
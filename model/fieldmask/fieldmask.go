@@ -0,0 +1,415 @@
+package fieldmask
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Type defines how the value of a masked field is redacted.
+type Type string
+
+const (
+	// Hash replaces the value with its sha256 hex digest, equal values
+	// remain comparable without exposing them.
+	Hash Type = "hash"
+	// Partial keeps the first character of the value, and the domain of an
+	// email, e.g. `john@x.com` becomes `j***@x.com`.
+	Partial Type = "partial"
+	// Replace replaces the value with a fixed value.
+	Replace Type = "replace"
+	// Null replaces the value with null.
+	Null Type = "null"
+)
+
+const maskChars = "***"
+
+// Rule masks the fields that match its patterns. The patterns are matched against
+// the complete dot notated path of a field, e.g. `user.email` or `user.*`.
+type Rule struct {
+	Fields []string    `json:"fields"`
+	Type   Type        `json:"type"`
+	Value  interface{} `json:"value,omitempty"`
+}
+
+var highlightTags = regexp.MustCompile(`<[^>]*>`)
+
+// Validate checks that the rule can be applied.
+func (r Rule) Validate() error {
+	if len(r.Fields) == 0 {
+		return fmt.Errorf("field mask must define at least one field")
+	}
+	for _, field := range r.Fields {
+		if _, err := fieldRegexp(field); err != nil {
+			return fmt.Errorf(`invalid field mask pattern "%s": %v`, field, err)
+		}
+	}
+	switch r.Type {
+	case Hash, Partial, Null:
+	case Replace:
+		if r.Value == nil {
+			return fmt.Errorf(`field mask of type "replace" must define a value`)
+		}
+	default:
+		return fmt.Errorf(`invalid field mask type "%s", must be one of "hash", "partial", "replace" or "null"`, r.Type)
+	}
+	return nil
+}
+
+// Validate checks that all the rules can be applied.
+func Validate(rules []Rule) error {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldRegexps caches the compiled field patterns, the rules are matched
+// against every field of every returned document.
+var fieldRegexps = struct {
+	mu    sync.RWMutex
+	cache map[string]*regexp.Regexp
+}{
+	cache: make(map[string]*regexp.Regexp),
+}
+
+func fieldRegexp(pattern string) (*regexp.Regexp, error) {
+	fieldRegexps.mu.RLock()
+	re, ok := fieldRegexps.cache[pattern]
+	fieldRegexps.mu.RUnlock()
+	if ok {
+		return re, nil
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return nil, err
+	}
+	fieldRegexps.mu.Lock()
+	fieldRegexps.cache[pattern] = re
+	fieldRegexps.mu.Unlock()
+	return re, nil
+}
+
+func (r Rule) matches(field string) bool {
+	for _, pattern := range r.Fields {
+		re, err := fieldRegexp(pattern)
+		if err == nil && re.MatchString(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMasked returns true if the rules mask the field.
+func IsMasked(rules []Rule, field string) bool {
+	return ruleFor(rules, field) != nil
+}
+
+// ruleFor returns the first rule matching the field, sub fields of
+// a masked field, e.g. `email.keyword` of `email` are masked too.
+func ruleFor(rules []Rule, field string) *Rule {
+	for path := field; path != ""; {
+		for i := range rules {
+			if rules[i].matches(path) {
+				return &rules[i]
+			}
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return nil
+}
+
+// maskValue redacts a value, the values of objects and arrays are redacted one by one.
+func (r Rule) maskValue(value interface{}) interface{} {
+	switch r.Type {
+	case Null:
+		return nil
+	case Replace:
+		return r.Value
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = r.maskValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.maskValue(item)
+		}
+		return v
+	case string:
+		return r.maskString(v)
+	default:
+		return r.maskString(fmt.Sprint(v))
+	}
+}
+
+func (r Rule) maskString(value string) string {
+	if r.Type == Hash {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:])
+	}
+	local, domain := value, ""
+	if i := strings.LastIndex(value, "@"); i > 0 {
+		local, domain = value[:i], value[i:]
+	}
+	runes := []rune(local)
+	if len(runes) <= 1 {
+		return maskChars + domain
+	}
+	return string(runes[0]) + maskChars + domain
+}
+
+// ApplyToSource masks the fields of a document source in place.
+func ApplyToSource(source map[string]interface{}, rules []Rule) {
+	applyToObject(source, rules, "")
+}
+
+func applyToObject(object map[string]interface{}, rules []Rule, prefix string) {
+	for key, value := range object {
+		path := prefix + key
+		if rule := ruleFor(rules, path); rule != nil {
+			object[key] = rule.maskValue(value)
+			continue
+		}
+		applyToValue(value, rules, path)
+	}
+}
+
+func applyToValue(value interface{}, rules []Rule, path string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		applyToObject(v, rules, path+".")
+	case []interface{}:
+		for _, item := range v {
+			applyToValue(item, rules, path)
+		}
+	}
+}
+
+// applyToFields masks the values of a map keyed by the complete field paths, such
+// as the `fields` of a hit. The highlight fragments are masked as plain text.
+func applyToFields(fields map[string]interface{}, rules []Rule, isHighlight bool) {
+	for field, value := range fields {
+		rule := ruleFor(rules, field)
+		if rule == nil {
+			continue
+		}
+		if isHighlight {
+			if rule.Type == Null {
+				delete(fields, field)
+				continue
+			}
+			if fragments, ok := value.([]interface{}); ok {
+				for i, fragment := range fragments {
+					if text, ok := fragment.(string); ok {
+						fragments[i] = highlightTags.ReplaceAllString(text, "")
+					}
+				}
+			}
+		}
+		fields[field] = rule.maskValue(value)
+	}
+}
+
+// ApplyToHit masks the source, the fields, the highlights and the inner hits of a document.
+func ApplyToHit(hit map[string]interface{}, rules []Rule) {
+	if source, ok := hit["_source"].(map[string]interface{}); ok {
+		// the source of a nested inner hit is the nested object
+		applyToObject(source, rules, nestedPath(hit))
+	}
+	if fields, ok := hit["fields"].(map[string]interface{}); ok {
+		applyToFields(fields, rules, false)
+	}
+	if highlight, ok := hit["highlight"].(map[string]interface{}); ok {
+		applyToFields(highlight, rules, true)
+	}
+	if innerHits, ok := hit["inner_hits"].(map[string]interface{}); ok {
+		for _, item := range innerHits {
+			if search, ok := item.(map[string]interface{}); ok {
+				ApplyToResponse(search, rules)
+			}
+		}
+	}
+}
+
+// nestedPath returns the dot notated path prefix of the object of a nested inner hit.
+func nestedPath(hit map[string]interface{}) string {
+	path := ""
+	nested, ok := hit["_nested"].(map[string]interface{})
+	for ok {
+		field, _ := nested["field"].(string)
+		path += field + "."
+		nested, ok = nested["_nested"].(map[string]interface{})
+	}
+	return path
+}
+
+// applyToAggregations masks the hits of the top_hits aggregations.
+func applyToAggregations(value interface{}, rules []Rule) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if hits, ok := v["hits"].(map[string]interface{}); ok {
+			if _, ok := hits["hits"].([]interface{}); ok {
+				ApplyToResponse(v, rules)
+				return
+			}
+		}
+		for _, item := range v {
+			applyToAggregations(item, rules)
+		}
+	case []interface{}:
+		for _, item := range v {
+			applyToAggregations(item, rules)
+		}
+	}
+}
+
+// ApplyToResponse masks the hits of a search response, or of each search of a `_msearch` response.
+func ApplyToResponse(response map[string]interface{}, rules []Rule) {
+	if responses, ok := response["responses"].([]interface{}); ok {
+		for _, item := range responses {
+			if search, ok := item.(map[string]interface{}); ok {
+				ApplyToResponse(search, rules)
+			}
+		}
+	}
+	if aggs, ok := response["aggregations"]; ok {
+		applyToAggregations(aggs, rules)
+	}
+	hits, ok := response["hits"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	docs, _ := hits["hits"].([]interface{})
+	for _, doc := range docs {
+		if hit, ok := doc.(map[string]interface{}); ok {
+			ApplyToHit(hit, rules)
+		}
+	}
+}
+
+// ValidateSearch rejects the aggregations and the sorts of a search body on the masked
+// fields, their bucket keys and sort values would expose the values of the fields. The
+// scripts of the aggregations and the sorts are rejected as they can access any field.
+func ValidateSearch(body map[string]interface{}, rules []Rule) error {
+	for _, key := range []string{"aggs", "aggregations"} {
+		if aggs, ok := body[key]; ok {
+			if err := validateAggregations(aggs, rules); err != nil {
+				return err
+			}
+		}
+	}
+	if sort, ok := body["sort"]; ok {
+		return validateSort(sort, rules)
+	}
+	return nil
+}
+
+// ValidateMsearch validates each search of an `_msearch` request body with ValidateSearch.
+func ValidateMsearch(body []byte, rules []Rule) error {
+	isHeader := true
+	for _, line := range bytes.Split(bytes.TrimRight(body, "\r\n"), []byte("\n")) {
+		if !isHeader {
+			search := make(map[string]interface{})
+			if err := json.Unmarshal(line, &search); err != nil {
+				return fmt.Errorf("can't parse the msearch body: %v", err)
+			}
+			if err := ValidateSearch(search, rules); err != nil {
+				return err
+			}
+		}
+		isHeader = !isHeader
+	}
+	return nil
+}
+
+// validateAggregations walks the whole aggregations, the names of the aggregations
+// are chosen by the request and can collide with the keys checked here.
+func validateAggregations(value interface{}, rules []Rule) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			switch key {
+			case "script":
+				return fmt.Errorf("scripts aren't allowed in the aggregations along with field masks")
+			case "sort":
+				if err := validateSort(item, rules); err != nil {
+					return err
+				}
+			case "field", "fields":
+				if field := maskedField(item, rules); field != "" {
+					return fmt.Errorf(`aggregations on the masked field "%s" aren't allowed`, field)
+				}
+			}
+			if err := validateAggregations(item, rules); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := validateAggregations(item, rules); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// maskedField returns the first masked field of a field name or a list of field names.
+func maskedField(value interface{}, rules []Rule) string {
+	switch v := value.(type) {
+	case string:
+		if IsMasked(rules, v) {
+			return v
+		}
+	case []interface{}:
+		for _, item := range v {
+			if field := maskedField(item, rules); field != "" {
+				return field
+			}
+		}
+	}
+	return ""
+}
+
+func validateSort(value interface{}, rules []Rule) error {
+	switch v := value.(type) {
+	case string:
+		if IsMasked(rules, v) {
+			return fmt.Errorf(`sorting on the masked field "%s" isn't allowed`, v)
+		}
+	case map[string]interface{}:
+		for field := range v {
+			if field == "_script" {
+				return fmt.Errorf("script sorts aren't allowed along with field masks")
+			}
+			if IsMasked(rules, field) {
+				return fmt.Errorf(`sorting on the masked field "%s" isn't allowed`, field)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := validateSort(item, rules); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
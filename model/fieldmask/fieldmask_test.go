@@ -0,0 +1,98 @@
+package fieldmask
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func parse(raw string) map[string]interface{} {
+	m := make(map[string]interface{})
+	json.Unmarshal([]byte(raw), &m)
+	return m
+}
+
+func toJSON(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+func TestValidate(t *testing.T) {
+	Convey("should validate the rules", t, func() {
+		So(Validate([]Rule{{Fields: []string{"email"}, Type: Partial}}), ShouldBeNil)
+		So(Validate([]Rule{{Fields: []string{}, Type: Hash}}), ShouldNotBeNil)
+		So(Validate([]Rule{{Fields: []string{"email"}, Type: "blur"}}), ShouldNotBeNil)
+		So(Validate([]Rule{{Fields: []string{"email"}, Type: Replace}}), ShouldNotBeNil)
+	})
+}
+
+func TestApplyToSource(t *testing.T) {
+	Convey("should mask the matching fields", t, func() {
+		source := parse(`{"email":"john@x.com","name":"John","phone":5550100,"address":{"city":"Paris","zip":"75001"},"tags":["a","b"],"emails":[{"email":"jane@y.com"}]}`)
+		ApplyToSource(source, []Rule{
+			{Fields: []string{"email", "emails.email"}, Type: Partial},
+			{Fields: []string{"phone"}, Type: Null},
+			{Fields: []string{"address.*"}, Type: Replace, Value: "redacted"},
+			{Fields: []string{"tags"}, Type: Hash},
+		})
+		So(toJSON(source), ShouldEqual, `{"address":{"city":"redacted","zip":"redacted"},"email":"j***@x.com","emails":[{"email":"j***@y.com"}],`+
+			`"name":"John","phone":null,"tags":["ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb","3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"]}`)
+	})
+	Convey("should only match complete field paths", t, func() {
+		source := parse(`{"email":"john@x.com","email_verified":true}`)
+		ApplyToSource(source, []Rule{{Fields: []string{"email"}, Type: Null}})
+		So(toJSON(source), ShouldEqual, `{"email":null,"email_verified":true}`)
+	})
+}
+
+func TestApplyToResponse(t *testing.T) {
+	Convey("should mask the hits and the highlights of each search", t, func() {
+		response := parse(`{"responses":[{"hits":{"hits":[{"_id":"1","_source":{"email":"john@x.com"},` +
+			`"fields":{"email.keyword":["john@x.com"]},"highlight":{"email":["<em>john</em>@x.com"],"name":["John"]}}]}}]}`)
+		ApplyToResponse(response, []Rule{{Fields: []string{"email"}, Type: Partial}})
+		So(toJSON(response), ShouldEqual, `{"responses":[{"hits":{"hits":[{"_id":"1","_source":{"email":"j***@x.com"},`+
+			`"fields":{"email.keyword":["j***@x.com"]},"highlight":{"email":["j***@x.com"],"name":["John"]}}]}}]}`)
+	})
+	Convey("should drop the highlights of nulled fields", t, func() {
+		response := parse(`{"hits":{"hits":[{"_source":{"email":"john@x.com"},"highlight":{"email":["<em>john</em>@x.com"]}}]}}`)
+		ApplyToResponse(response, []Rule{{Fields: []string{"email"}, Type: Null}})
+		So(toJSON(response), ShouldEqual, `{"hits":{"hits":[{"_source":{"email":null},"highlight":{}}]}}`)
+	})
+}
+
+func TestApplyToInnerHits(t *testing.T) {
+	Convey("should mask the nested inner hits by their complete paths", t, func() {
+		response := parse(`{"hits":{"hits":[{"_source":{"comments":[{"email":"john@x.com"}]},"inner_hits":{"comments":{"hits":{"hits":[` +
+			`{"_nested":{"field":"comments","offset":0},"_source":{"email":"john@x.com","text":"hi"}}]}}}}]}}`)
+		ApplyToResponse(response, []Rule{{Fields: []string{"comments.email"}, Type: Null}})
+		So(toJSON(response), ShouldEqual, `{"hits":{"hits":[{"_source":{"comments":[{"email":null}]},"inner_hits":{"comments":{"hits":{"hits":[`+
+			`{"_nested":{"field":"comments","offset":0},"_source":{"email":null,"text":"hi"}}]}}}}]}}`)
+	})
+	Convey("should mask the hits of the top_hits aggregations", t, func() {
+		response := parse(`{"hits":{"hits":[]},"aggregations":{"by_city":{"buckets":[{"key":"Paris","top":{"hits":{"hits":[{"_source":{"email":"john@x.com"}}]}}}]}}}`)
+		ApplyToResponse(response, []Rule{{Fields: []string{"email"}, Type: Null}})
+		So(toJSON(response), ShouldEqual, `{"aggregations":{"by_city":{"buckets":[{"key":"Paris","top":{"hits":{"hits":[{"_source":{"email":null}}]}}}]}},"hits":{"hits":[]}}`)
+	})
+}
+
+func TestValidateSearch(t *testing.T) {
+	rules := []Rule{{Fields: []string{"email"}, Type: Partial}}
+	Convey("should reject the aggregations on the masked fields", t, func() {
+		So(ValidateSearch(parse(`{"aggs":{"cities":{"terms":{"field":"city"}}}}`), rules), ShouldBeNil)
+		So(ValidateSearch(parse(`{"aggs":{"emails":{"terms":{"field":"email.keyword"}}}}`), rules), ShouldNotBeNil)
+		So(ValidateSearch(parse(`{"aggregations":{"cities":{"terms":{"field":"city"},"aggs":{"field":{"terms":{"field":"email"}}}}}}`), rules), ShouldNotBeNil)
+		So(ValidateSearch(parse(`{"aggs":{"emails":{"terms":{"script":"doc['email'].value"}}}}`), rules), ShouldNotBeNil)
+	})
+	Convey("should reject the sorts on the masked fields", t, func() {
+		So(ValidateSearch(parse(`{"sort":["_score",{"city":"asc"}]}`), rules), ShouldBeNil)
+		So(ValidateSearch(parse(`{"sort":["email"]}`), rules), ShouldNotBeNil)
+		So(ValidateSearch(parse(`{"sort":{"email.keyword":{"order":"desc"}}}`), rules), ShouldNotBeNil)
+		So(ValidateSearch(parse(`{"sort":{"_script":{"type":"string","script":"doc['email'].value"}}}`), rules), ShouldNotBeNil)
+		So(ValidateSearch(parse(`{"aggs":{"top":{"top_hits":{"sort":[{"email":"asc"}]}}}}`), rules), ShouldNotBeNil)
+	})
+	Convey("should validate each search of the body", t, func() {
+		So(ValidateMsearch([]byte("{}\n{\"sort\":[\"city\"]}\n{}\n{\"sort\":[\"email\"]}\n"), rules), ShouldNotBeNil)
+		So(ValidateMsearch([]byte("{}\n{\"sort\":[\"city\"]}\n"), rules), ShouldBeNil)
+	})
+}
@@ -0,0 +1,139 @@
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+)
+
+// Addressed returns the indices addressed by a request, the indices named in the body
+// of the bulk, msearch, mget and reindex requests are added to the indices of the path.
+// The searches without indices run on all the indices, which is expressed by the "*"
+// wildcard, except the scroll requests that continue a search.
+func Addressed(reqACL acl.ACL, path string, pathIndices []string, body []byte) []string {
+	indices := append([]string{}, pathIndices...)
+	switch reqACL {
+	case acl.Bulk:
+		indices = append(indices, bulkIndices(body)...)
+	case acl.Msearch:
+		indices = append(indices, msearchIndices(body, len(pathIndices) > 0)...)
+	case acl.Mget:
+		var reqBody struct {
+			Docs []struct {
+				Index string `json:"_index"`
+			} `json:"docs"`
+		}
+		if json.Unmarshal(body, &reqBody) == nil {
+			for _, doc := range reqBody.Docs {
+				if doc.Index != "" {
+					indices = append(indices, doc.Index)
+				}
+			}
+		}
+	case acl.Reindex:
+		var reqBody struct {
+			Source struct {
+				Index  interface{}            `json:"index"`
+				Remote map[string]interface{} `json:"remote"`
+			} `json:"source"`
+			Dest struct {
+				Index string `json:"index"`
+			} `json:"dest"`
+		}
+		if json.Unmarshal(body, &reqBody) == nil {
+			// the source indices of a remote reindex aren't served by the upstream
+			if reqBody.Source.Remote == nil {
+				indices = append(indices, toIndices(reqBody.Source.Index)...)
+			}
+			if reqBody.Dest.Index != "" {
+				indices = append(indices, reqBody.Dest.Index)
+			}
+		}
+	case acl.Search, acl.Count:
+		if len(indices) == 0 && !strings.Contains(path, "_search/scroll") {
+			indices = append(indices, "*")
+		}
+	}
+	return indices
+}
+
+// bulkIndices returns the _index of the action lines of a bulk request, the
+// index, create and update actions are followed by a source line.
+func bulkIndices(body []byte) []string {
+	var indices []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	skipSource := false
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if skipSource {
+			skipSource = false
+			continue
+		}
+		var action map[string]struct {
+			Index string `json:"_index"`
+		}
+		if err := json.Unmarshal(line, &action); err != nil {
+			continue
+		}
+		for name, meta := range action {
+			if meta.Index != "" {
+				indices = append(indices, meta.Index)
+			}
+			skipSource = name != "delete"
+		}
+	}
+	return indices
+}
+
+// msearchIndices returns the indices of the header lines of a msearch request,
+// a header without indices searches the path indices or else all the indices.
+func msearchIndices(body []byte, hasPathIndices bool) []string {
+	var indices []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	isHeader := true
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if isHeader {
+			var header struct {
+				Index interface{} `json:"index"`
+			}
+			json.Unmarshal(line, &header)
+			headerIndices := toIndices(header.Index)
+			if len(headerIndices) == 0 && !hasPathIndices {
+				headerIndices = []string{"*"}
+			}
+			indices = append(indices, headerIndices...)
+		}
+		isHeader = !isHeader
+	}
+	return indices
+}
+
+// toIndices converts a comma separated string or an array of indices to a slice.
+func toIndices(value interface{}) []string {
+	var indices []string
+	switch v := value.(type) {
+	case string:
+		for _, index := range strings.Split(v, ",") {
+			if index = strings.TrimSpace(index); index != "" {
+				indices = append(indices, index)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			indices = append(indices, toIndices(item)...)
+		}
+	}
+	return indices
+}
@@ -0,0 +1,55 @@
+package index
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+)
+
+func TestAddressed(t *testing.T) {
+	Convey("should add the indices of the bulk action lines", t, func() {
+		body := []byte(`{"index":{"_index":"products","_id":"1"}}
+{"delete":{"_index":"source"}}
+{"delete":{"_index":".logs"}}
+{"update":{"_id":"2"}}
+{"doc":{"delete":{"_index":"source"}}}
+`)
+		indices := Addressed(acl.Bulk, "/books/_bulk", []string{"books"}, body)
+		So(indices, ShouldResemble, []string{"books", "products", ".logs"})
+	})
+
+	Convey("should add the indices of the msearch headers", t, func() {
+		body := []byte(`{"index":"products,books"}
+{"query":{"match_all":{}}}
+{"index":[".logs"]}
+{}
+{}
+{"index":"source"}
+`)
+		indices := Addressed(acl.Msearch, "/_msearch", nil, body)
+		So(indices, ShouldResemble, []string{"products", "books", ".logs", "*"})
+		indices = Addressed(acl.Msearch, "/books/_msearch", []string{"books"}, []byte("{}\n{}\n"))
+		So(indices, ShouldResemble, []string{"books"})
+	})
+
+	Convey("should add the indices of the mget docs", t, func() {
+		body := []byte(`{"docs":[{"_index":"products","_id":"1"},{"_id":"2"}]}`)
+		indices := Addressed(acl.Mget, "/books/_mget", []string{"books"}, body)
+		So(indices, ShouldResemble, []string{"books", "products"})
+	})
+
+	Convey("should add the source and the dest indices of a reindex", t, func() {
+		body := []byte(`{"source":{"index":["products","books"]},"dest":{"index":".logs"}}`)
+		So(Addressed(acl.Reindex, "/_reindex", nil, body), ShouldResemble, []string{"products", "books", ".logs"})
+		body = []byte(`{"source":{"index":"products","remote":{"host":"http://remote:9200"}},"dest":{"index":"books"}}`)
+		So(Addressed(acl.Reindex, "/_reindex", nil, body), ShouldResemble, []string{"books"})
+	})
+
+	Convey("should search all the indices without the path indices", t, func() {
+		So(Addressed(acl.Search, "/_search", nil, nil), ShouldResemble, []string{"*"})
+		So(Addressed(acl.Search, "/books/_search", []string{"books"}, nil), ShouldResemble, []string{"books"})
+		So(Addressed(acl.Search, "/_search/scroll", nil, nil), ShouldBeEmpty)
+	})
+}
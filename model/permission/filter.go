@@ -0,0 +1,122 @@
+package permission
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Filter defines the documents a permission can access. The clauses are combined
+// with a logical AND and applied to every search made with the permission.
+type Filter struct {
+	// Query is an elasticsearch query DSL, e.g. {"term": {"tenant_id": "acme"}}
+	Query map[string]interface{} `json:"query,omitempty"`
+	// Term matches the documents where the field is equal to the value, or to
+	// any of the values if an array is provided
+	Term map[string]interface{} `json:"term,omitempty"`
+	// Range matches the documents where the field is within the bounds, e.g.
+	// {"created_at": {"gte": "now-30d"}}
+	Range map[string]map[string]interface{} `json:"range,omitempty"`
+}
+
+var rangeParams = map[string]bool{
+	"gt":        true,
+	"gte":       true,
+	"lt":        true,
+	"lte":       true,
+	"format":    true,
+	"time_zone": true,
+}
+
+// SetFilter sets the filter applied to the searches made with the permission.
+func SetFilter(filter *Filter) Options {
+	return func(p *Permission) error {
+		if filter == nil {
+			return fmt.Errorf("permission filter cannot be nil")
+		}
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+		p.Filter = filter
+		return nil
+	}
+}
+
+// Validate checks that the filter has at least one valid clause.
+func (f *Filter) Validate() error {
+	if len(f.Query) == 0 && len(f.Term) == 0 && len(f.Range) == 0 {
+		return fmt.Errorf("filter must define at least one of query, term or range")
+	}
+	for field, value := range f.Term {
+		switch v := value.(type) {
+		case string, float64, bool:
+		case []interface{}:
+			if len(v) == 0 {
+				return fmt.Errorf(`filter term values of "%s" cannot be empty`, field)
+			}
+			for _, item := range v {
+				switch item.(type) {
+				case string, float64, bool:
+				default:
+					return fmt.Errorf(`filter term values of "%s" must be strings, numbers or booleans`, field)
+				}
+			}
+		default:
+			return fmt.Errorf(`filter term value of "%s" must be a string, number, boolean or an array of them`, field)
+		}
+	}
+	for field, bounds := range f.Range {
+		if len(bounds) == 0 {
+			return fmt.Errorf(`filter range of "%s" cannot be empty`, field)
+		}
+		for param := range bounds {
+			if !rangeParams[param] {
+				return fmt.Errorf(`filter range of "%s" has an unsupported key "%s"`, field, param)
+			}
+		}
+	}
+	return nil
+}
+
+// ToQuery returns the filter as an elasticsearch query DSL.
+func (f *Filter) ToQuery() map[string]interface{} {
+	var clauses []interface{}
+	if len(f.Query) > 0 {
+		clauses = append(clauses, f.Query)
+	}
+	for _, field := range sortedKeys(f.Term) {
+		value := f.Term[field]
+		if values, ok := value.([]interface{}); ok {
+			clauses = append(clauses, map[string]interface{}{
+				"terms": map[string]interface{}{field: values},
+			})
+		} else {
+			clauses = append(clauses, map[string]interface{}{
+				"term": map[string]interface{}{field: value},
+			})
+		}
+	}
+	rangeFields := make([]string, 0, len(f.Range))
+	for field := range f.Range {
+		rangeFields = append(rangeFields, field)
+	}
+	sort.Strings(rangeFields)
+	for _, field := range rangeFields {
+		clauses = append(clauses, map[string]interface{}{
+			"range": map[string]interface{}{field: f.Range[field]},
+		})
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": clauses,
+		},
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -14,7 +14,9 @@ import (
 	"github.com/appbaseio/reactivesearch-api/errors"
 	"github.com/appbaseio/reactivesearch-api/model/acl"
 	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/fieldmask"
 	"github.com/appbaseio/reactivesearch-api/model/op"
+	"github.com/appbaseio/reactivesearch-api/model/querypolicy"
 	"github.com/appbaseio/reactivesearch-api/util"
 	"github.com/google/uuid"
 )
@@ -35,6 +37,52 @@ type ReactiveSearchConfig struct {
 	MaxSize            *int  `json:"maxSize,omitempty"`
 	MaxAggregationSize *int  `json:"maxAggregationSize,omitempty"`
 	DisbaleQueryDSL    *bool `json:"disableQueryDSL,omitempty"`
+	// MaxQueries is the maximum number of queries in a request.
+	MaxQueries *int `json:"maxQueries,omitempty"`
+	// MaxFrom is the maximum value of the from (pagination offset) of a query.
+	MaxFrom *int `json:"maxFrom,omitempty"`
+	// AllowedQueryTypes restricts the type of the queries, all the types are allowed if empty.
+	AllowedQueryTypes []string `json:"allowedQueryTypes,omitempty"`
+	// AllowedDataFields restricts the data fields of the queries to the fields
+	// matching the patterns, all the fields are allowed if empty.
+	AllowedDataFields   []string `json:"allowedDataFields,omitempty"`
+	DisableCustomQuery  *bool    `json:"disableCustomQuery,omitempty"`
+	DisableDefaultQuery *bool    `json:"disableDefaultQuery,omitempty"`
+	// MaxHighlightFields is the maximum number of fields highlighted by a query.
+	MaxHighlightFields *int `json:"maxHighlightFields,omitempty"`
+	// MaxReactDepth is the maximum nesting of the and/or/not clauses of the react prop.
+	MaxReactDepth *int `json:"maxReactDepth,omitempty"`
+}
+
+// queryTypes are the types of the reactivesearch queries.
+var queryTypes = []string{"search", "term", "range", "geo", "suggestion"}
+
+// Validate checks the limits of the reactivesearch config.
+func (c ReactiveSearchConfig) Validate() error {
+	limits := map[string]*int{
+		"maxSize":            c.MaxSize,
+		"maxAggregationSize": c.MaxAggregationSize,
+		"maxQueries":         c.MaxQueries,
+		"maxFrom":            c.MaxFrom,
+		"maxHighlightFields": c.MaxHighlightFields,
+		"maxReactDepth":      c.MaxReactDepth,
+	}
+	for name, limit := range limits {
+		if limit != nil && *limit < 0 {
+			return fmt.Errorf(`reactivesearchConfig "%s" cannot be negative`, name)
+		}
+	}
+	for _, queryType := range c.AllowedQueryTypes {
+		if !util.Contains(queryTypes, queryType) {
+			return fmt.Errorf(`invalid query type "%s" in reactivesearchConfig "allowedQueryTypes"`, queryType)
+		}
+	}
+	for _, pattern := range c.AllowedDataFields {
+		if pattern == "" {
+			return fmt.Errorf(`reactivesearchConfig "allowedDataFields" cannot contain an empty pattern`)
+		}
+	}
+	return nil
 }
 
 // Permission defines a permission type.
@@ -58,6 +106,12 @@ type Permission struct {
 	Excludes             []string              `json:"exclude_fields"`
 	Expired              bool                  `json:"expired"`
 	ReactiveSearchConfig *ReactiveSearchConfig `json:"reactivesearchConfig,omitempty"`
+	Filter               *Filter               `json:"filter,omitempty"`
+	IndexRules           []IndexRule           `json:"index_rules,omitempty"`
+	FieldMasks           []fieldmask.Rule      `json:"field_masks,omitempty"`
+	Schedule             *Schedule             `json:"schedule,omitempty"`
+	Quotas               []Quota               `json:"quotas,omitempty"`
+	QueryPolicy          *querypolicy.Policy   `json:"query_policy,omitempty"`
 }
 
 // Limits defines the rate limits for each category.
@@ -196,6 +250,34 @@ func SetExcludes(excludes []string) Options {
 	}
 }
 
+// SetFieldMasks sets the masks applied to the fields of the documents returned to the permission.
+func SetFieldMasks(masks []fieldmask.Rule) Options {
+	return func(p *Permission) error {
+		if masks == nil {
+			return fmt.Errorf("permission field masks cannot be nil")
+		}
+		if err := fieldmask.Validate(masks); err != nil {
+			return err
+		}
+		p.FieldMasks = masks
+		return nil
+	}
+}
+
+// SetQueryPolicy sets the policy applied to the query DSL of the search requests of the permission.
+func SetQueryPolicy(policy *querypolicy.Policy) Options {
+	return func(p *Permission) error {
+		if policy == nil {
+			return fmt.Errorf("permission query policy cannot be nil")
+		}
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		p.QueryPolicy = policy
+		return nil
+	}
+}
+
 func validateSources(sources []string) error {
 	for _, source := range sources {
 		_, _, err := net.ParseCIDR(source)
@@ -288,6 +370,9 @@ func SetDescription(description string) Options {
 // SetDescription sets the permission reactivesearchConfig.
 func SetReactivesearchConfig(config ReactiveSearchConfig) Options {
 	return func(p *Permission) error {
+		if err := config.Validate(); err != nil {
+			return err
+		}
 		p.ReactiveSearchConfig = &config
 		return nil
 	}
@@ -482,6 +567,10 @@ func (p *Permission) CanAccessIndex(name string) (bool, error) {
 		}
 		indices = append(indices, suggestionsIndex)
 	}
+	// the indices of the index rules are accessible too
+	for _, rule := range p.IndexRules {
+		indices = append(indices, rule.Indices...)
+	}
 	for _, pattern := range indices {
 		matched, err := util.ValidateIndex(pattern, name)
 		if err != nil {
@@ -695,6 +784,9 @@ func (p *Permission) GetPatch(rolePatched bool) (map[string]interface{}, error)
 		patch["description"] = p.Description
 	}
 	if p.ReactiveSearchConfig != nil {
+		if err := p.ReactiveSearchConfig.Validate(); err != nil {
+			return nil, err
+		}
 		patch["reactivesearchConfig"] = p.ReactiveSearchConfig
 	}
 	if p.Includes != nil {
@@ -703,6 +795,42 @@ func (p *Permission) GetPatch(rolePatched bool) (map[string]interface{}, error)
 	if p.Excludes != nil {
 		patch["exclude_fields"] = p.Excludes
 	}
+	if p.Filter != nil {
+		if err := p.Filter.Validate(); err != nil {
+			return nil, err
+		}
+		patch["filter"] = p.Filter
+	}
+	if p.IndexRules != nil {
+		if err := p.validateIndexRules(p.IndexRules); err != nil {
+			return nil, err
+		}
+		patch["index_rules"] = p.IndexRules
+	}
+	if p.FieldMasks != nil {
+		if err := fieldmask.Validate(p.FieldMasks); err != nil {
+			return nil, err
+		}
+		patch["field_masks"] = p.FieldMasks
+	}
+	if p.Schedule != nil {
+		if err := p.Schedule.Validate(); err != nil {
+			return nil, err
+		}
+		patch["schedule"] = p.Schedule
+	}
+	if p.Quotas != nil {
+		if err := validateQuotas(p.Quotas); err != nil {
+			return nil, err
+		}
+		patch["quotas"] = p.Quotas
+	}
+	if p.QueryPolicy != nil {
+		if err := p.QueryPolicy.Validate(); err != nil {
+			return nil, err
+		}
+		patch["query_policy"] = p.QueryPolicy
+	}
 
 	return patch, nil
 }
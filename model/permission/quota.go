@@ -0,0 +1,101 @@
+package permission
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appbaseio/reactivesearch-api/model/category"
+)
+
+// QuotaMetric defines what is counted against a quota.
+type QuotaMetric string
+
+// QuotaPeriod defines the period after which the usage of a quota is reset.
+type QuotaPeriod string
+
+const (
+	// Requests counts the requests made with the permission.
+	Requests QuotaMetric = "requests"
+	// Documents counts the documents written with the permission, each
+	// action of a bulk request is counted as a document.
+	Documents QuotaMetric = "documents"
+
+	// Day quotas are reset at midnight UTC.
+	Day QuotaPeriod = "day"
+	// Month quotas are reset at midnight UTC of the first day of the month.
+	Month QuotaPeriod = "month"
+)
+
+// Quota defines a budget of requests or documents for a period, e.g. 1M search
+// requests per month. The budget applies to all the categories if category isn't set.
+type Quota struct {
+	Metric   QuotaMetric        `json:"metric"`
+	Category *category.Category `json:"category,omitempty"`
+	Period   QuotaPeriod        `json:"period"`
+	Limit    int64              `json:"limit"`
+}
+
+// SetQuotas sets the usage quotas of a permission.
+func SetQuotas(quotas []Quota) Options {
+	return func(p *Permission) error {
+		if quotas == nil {
+			return fmt.Errorf("permission quotas cannot be nil")
+		}
+		if err := validateQuotas(quotas); err != nil {
+			return err
+		}
+		p.Quotas = quotas
+		return nil
+	}
+}
+
+func validateQuotas(quotas []Quota) error {
+	keys := make(map[string]bool)
+	for _, quota := range quotas {
+		if quota.Metric != Requests && quota.Metric != Documents {
+			return fmt.Errorf(`invalid quota metric "%s", must be "requests" or "documents"`, quota.Metric)
+		}
+		if quota.Period != Day && quota.Period != Month {
+			return fmt.Errorf(`invalid quota period "%s", must be "day" or "month"`, quota.Period)
+		}
+		if quota.Limit <= 0 {
+			return fmt.Errorf("quota limit must be greater than 0")
+		}
+		if keys[quota.Key()] {
+			return fmt.Errorf(`quota "%s" is defined more than once`, quota.Key())
+		}
+		keys[quota.Key()] = true
+	}
+	return nil
+}
+
+// Key returns the identifier of the quota, e.g. `requests:search:month`.
+func (q Quota) Key() string {
+	c := "all"
+	if q.Category != nil {
+		c = q.Category.String()
+	}
+	return fmt.Sprintf("%s:%s:%s", q.Metric, c, q.Period)
+}
+
+// AppliesTo checks whether the requests of the category are counted against the quota.
+func (q Quota) AppliesTo(c category.Category) bool {
+	return q.Category == nil || *q.Category == c
+}
+
+// PeriodStart returns the start of the quota period that contains the given time.
+func (q Quota) PeriodStart(t time.Time) time.Time {
+	t = t.UTC()
+	if q.Period == Month {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// PeriodEnd returns the time at which the quota period that contains the given time is reset.
+func (q Quota) PeriodEnd(t time.Time) time.Time {
+	if q.Period == Month {
+		return q.PeriodStart(t).AddDate(0, 1, 0)
+	}
+	return q.PeriodStart(t).AddDate(0, 0, 1)
+}
@@ -0,0 +1,32 @@
+package permission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQuota(t *testing.T) {
+	search := category.Search
+	Convey("should validate the quotas", t, func() {
+		So(validateQuotas([]Quota{{Metric: Requests, Category: &search, Period: Month, Limit: 1000000}}), ShouldBeNil)
+		So(validateQuotas([]Quota{{Metric: "bytes", Period: Day, Limit: 1}}), ShouldNotBeNil)
+		So(validateQuotas([]Quota{{Metric: Documents, Period: "week", Limit: 1}}), ShouldNotBeNil)
+		So(validateQuotas([]Quota{{Metric: Documents, Period: Day}}), ShouldNotBeNil)
+		So(validateQuotas([]Quota{{Metric: Documents, Period: Day, Limit: 1}, {Metric: Documents, Period: Day, Limit: 2}}), ShouldNotBeNil)
+	})
+	Convey("should compute the quota periods", t, func() {
+		now := time.Date(2021, time.March, 15, 13, 30, 0, 0, time.UTC)
+		monthly := Quota{Metric: Requests, Category: &search, Period: Month, Limit: 1}
+		So(monthly.Key(), ShouldEqual, "requests:search:month")
+		So(monthly.PeriodStart(now), ShouldResemble, time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC))
+		So(monthly.PeriodEnd(now), ShouldResemble, time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC))
+		daily := Quota{Metric: Documents, Period: Day, Limit: 1}
+		So(daily.Key(), ShouldEqual, "documents:all:day")
+		So(daily.PeriodEnd(now), ShouldResemble, time.Date(2021, time.March, 16, 0, 0, 0, 0, time.UTC))
+		So(daily.AppliesTo(category.Docs), ShouldBeTrue)
+		So(monthly.AppliesTo(category.Docs), ShouldBeFalse)
+	})
+}
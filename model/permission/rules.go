@@ -0,0 +1,184 @@
+package permission
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/fieldmask"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+	"github.com/appbaseio/reactivesearch-api/util"
+)
+
+// IndexRule defines the access of a permission to the indices matching its patterns. The
+// fields that are set override the respective fields of the permission for those indices.
+type IndexRule struct {
+	Indices              []string              `json:"indices"`
+	ACLs                 []acl.ACL             `json:"acls,omitempty"`
+	Ops                  []op.Operation        `json:"ops,omitempty"`
+	Includes             []string              `json:"include_fields,omitempty"`
+	Excludes             []string              `json:"exclude_fields,omitempty"`
+	ReactiveSearchConfig *ReactiveSearchConfig `json:"reactivesearchConfig,omitempty"`
+	FieldMasks           []fieldmask.Rule      `json:"field_masks,omitempty"`
+}
+
+// indexAccess is the access of a permission to a concrete index that must be
+// consistent across the indices of a request.
+type indexAccess struct {
+	Includes             []string
+	Excludes             []string
+	ReactiveSearchConfig *ReactiveSearchConfig
+	FieldMasks           []fieldmask.Rule
+}
+
+// SetIndexRules sets the per index rules of a permission.
+// Categories must always be set before setting the index rules.
+func SetIndexRules(rules []IndexRule) Options {
+	return func(p *Permission) error {
+		if rules == nil {
+			return fmt.Errorf("permission index rules cannot be nil")
+		}
+		if err := p.validateIndexRules(rules); err != nil {
+			return err
+		}
+		p.IndexRules = rules
+		return nil
+	}
+}
+
+func (p *Permission) validateIndexRules(rules []IndexRule) error {
+	for i, rule := range rules {
+		if len(rule.Indices) == 0 {
+			return fmt.Errorf("index rule %d must define at least one index pattern", i)
+		}
+		for _, pattern := range rule.Indices {
+			pattern = strings.Replace(pattern, "*", ".*", -1)
+			if _, err := regexp.Compile(pattern); err != nil {
+				return err
+			}
+		}
+		if err := p.ValidateACLs(rule.ACLs...); err != nil {
+			return fmt.Errorf("index rule %d: %v", i, err)
+		}
+		if err := fieldmask.Validate(rule.FieldMasks); err != nil {
+			return fmt.Errorf("index rule %d: %v", i, err)
+		}
+		if rule.ReactiveSearchConfig != nil {
+			if err := rule.ReactiveSearchConfig.Validate(); err != nil {
+				return fmt.Errorf("index rule %d: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ruleFor returns the first rule that matches the index, if any.
+func (p *Permission) ruleFor(index string) (*IndexRule, error) {
+	for i, rule := range p.IndexRules {
+		for _, pattern := range rule.Indices {
+			matched, err := util.ValidateIndex(pattern, index)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				return &p.IndexRules[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// IsIndexPattern returns true if the index of a request matches multiple indices.
+func IsIndexPattern(index string) bool {
+	return index == "_all" || strings.Contains(index, "*")
+}
+
+// ForIndices returns a copy of the permission with the index rules of the given indices
+// applied. The acls and ops are restricted to the ones allowed for every index, the source
+// filters and the reactivesearch config must be the same for all the indices. The index
+// patterns and the aliases must be resolved to the concrete indices, the rules of the
+// indices they point to are unknown otherwise.
+func (p *Permission) ForIndices(indices ...string) (*Permission, error) {
+	if len(p.IndexRules) == 0 || len(indices) == 0 {
+		return p, nil
+	}
+	effective := *p
+	var access *indexAccess
+	for i, index := range indices {
+		if IsIndexPattern(index) {
+			return nil, fmt.Errorf("index pattern %q must be resolved to the concrete indices", index)
+		}
+		rule, err := p.ruleFor(index)
+		if err != nil {
+			return nil, err
+		}
+		acls, ops := p.ACLs, p.Ops
+		indexAccess := indexAccess{
+			Includes:             p.Includes,
+			Excludes:             p.Excludes,
+			ReactiveSearchConfig: p.ReactiveSearchConfig,
+			FieldMasks:           p.FieldMasks,
+		}
+		if rule != nil {
+			if rule.ACLs != nil {
+				acls = rule.ACLs
+			}
+			if rule.Ops != nil {
+				ops = rule.Ops
+			}
+			if rule.Includes != nil || rule.Excludes != nil {
+				indexAccess.Includes = rule.Includes
+				indexAccess.Excludes = rule.Excludes
+			}
+			if rule.ReactiveSearchConfig != nil {
+				indexAccess.ReactiveSearchConfig = rule.ReactiveSearchConfig
+			}
+			if rule.FieldMasks != nil {
+				indexAccess.FieldMasks = rule.FieldMasks
+			}
+		}
+		if i == 0 {
+			effective.ACLs, effective.Ops = acls, ops
+			access = &indexAccess
+			continue
+		}
+		effective.ACLs = intersectACLs(effective.ACLs, acls)
+		effective.Ops = intersectOps(effective.Ops, ops)
+		if !reflect.DeepEqual(*access, indexAccess) {
+			return nil, fmt.Errorf("indices %v have conflicting field rules, they must be requested separately", indices)
+		}
+	}
+	effective.Includes = access.Includes
+	effective.Excludes = access.Excludes
+	effective.ReactiveSearchConfig = access.ReactiveSearchConfig
+	effective.FieldMasks = access.FieldMasks
+	return &effective, nil
+}
+
+func intersectACLs(a, b []acl.ACL) []acl.ACL {
+	result := []acl.ACL{}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				result = append(result, x)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func intersectOps(a, b []op.Operation) []op.Operation {
+	result := []op.Operation{}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				result = append(result, x)
+				break
+			}
+		}
+	}
+	return result
+}
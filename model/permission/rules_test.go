@@ -0,0 +1,85 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func rulesPermission() *Permission {
+	return &Permission{
+		ACLs:     []acl.ACL{acl.Search, acl.Get},
+		Ops:      []op.Operation{op.Read},
+		Indices:  []string{"logs"},
+		Excludes: []string{"secret"},
+		IndexRules: []IndexRule{
+			{
+				Indices:  []string{"products-*"},
+				ACLs:     []acl.ACL{acl.Search},
+				Excludes: []string{"cost"},
+			},
+			{
+				Indices: []string{"cart-*"},
+				ACLs:    []acl.ACL{acl.Doc, acl.Search},
+				Ops:     []op.Operation{op.Read, op.Write},
+			},
+		},
+	}
+}
+
+func TestForIndices(t *testing.T) {
+	Convey("should apply the matching rule", t, func() {
+		p, err := rulesPermission().ForIndices("products-2021")
+		So(err, ShouldBeNil)
+		So(p.ACLs, ShouldResemble, []acl.ACL{acl.Search})
+		So(p.Ops, ShouldResemble, []op.Operation{op.Read})
+		So(p.Excludes, ShouldResemble, []string{"cost"})
+	})
+	Convey("should use the permission without a matching rule", t, func() {
+		p, err := rulesPermission().ForIndices("logs")
+		So(err, ShouldBeNil)
+		So(p.ACLs, ShouldResemble, []acl.ACL{acl.Search, acl.Get})
+		So(p.Excludes, ShouldResemble, []string{"secret"})
+	})
+	Convey("should restrict the access to the one allowed for every index", t, func() {
+		p, err := rulesPermission().ForIndices("cart-1", "cart-2")
+		So(err, ShouldBeNil)
+		So(p.ACLs, ShouldResemble, []acl.ACL{acl.Doc, acl.Search})
+		So(p.Ops, ShouldResemble, []op.Operation{op.Read, op.Write})
+
+		p, err = rulesPermission().ForIndices("cart-1", "logs")
+		So(err, ShouldBeNil)
+		So(p.ACLs, ShouldResemble, []acl.ACL{acl.Search})
+		So(p.Ops, ShouldResemble, []op.Operation{op.Read})
+	})
+	Convey("should fail with conflicting field rules", t, func() {
+		_, err := rulesPermission().ForIndices("products-2021", "logs")
+		So(err, ShouldNotBeNil)
+	})
+	Convey("should fail with the unresolved index patterns", t, func() {
+		_, err := rulesPermission().ForIndices("products-*")
+		So(err, ShouldNotBeNil)
+		_, err = rulesPermission().ForIndices("logs", "_all")
+		So(err, ShouldNotBeNil)
+	})
+	Convey("should allow the access to the rule indices", t, func() {
+		ok, err := rulesPermission().CanAccessIndices("logs", "cart-1", "products-2021")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		ok, err = rulesPermission().CanAccessIndices("users")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestReactiveSearchConfigValidate(t *testing.T) {
+	negative := -1
+	Convey("should validate the reactivesearch config", t, func() {
+		So(ReactiveSearchConfig{AllowedQueryTypes: []string{"search", "suggestion"}}.Validate(), ShouldBeNil)
+		So(ReactiveSearchConfig{AllowedQueryTypes: []string{"match"}}.Validate(), ShouldNotBeNil)
+		So(ReactiveSearchConfig{MaxReactDepth: &negative}.Validate(), ShouldNotBeNil)
+		So(ReactiveSearchConfig{AllowedDataFields: []string{""}}.Validate(), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,169 @@
+package permission
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	scheduleDateLayout = "2006-01-02"
+	scheduleHourLayout = "15:04"
+)
+
+// Schedule restricts the time windows in which a permission can be used. The dates, weekdays
+// and hours are evaluated in the time zone of the schedule, which defaults to UTC.
+type Schedule struct {
+	// NotBefore is the RFC3339 time before which the permission can't be used
+	NotBefore string `json:"notBefore,omitempty"`
+	// StartDate and EndDate are the first and the last day the permission can be used on, e.g. 2021-03-01
+	StartDate string `json:"startDate,omitempty"`
+	EndDate   string `json:"endDate,omitempty"`
+	// Weekdays are the days of the week the permission can be used on, e.g. ["monday", "friday"]
+	Weekdays []string `json:"weekdays,omitempty"`
+	// Hours are the time ranges of the day the permission can be used in
+	Hours []HourRange `json:"hours,omitempty"`
+	// TimeZone is an IANA time zone name, e.g. Europe/Berlin
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// HourRange is a range of the day, e.g. {"start": "09:00", "end": "17:00"}. The end is
+// exclusive, a range that ends before it starts spans over midnight.
+type HourRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// SetSchedule sets the time windows in which the permission can be used.
+func SetSchedule(schedule *Schedule) Options {
+	return func(p *Permission) error {
+		if schedule == nil {
+			return fmt.Errorf("permission schedule cannot be nil")
+		}
+		if err := schedule.Validate(); err != nil {
+			return err
+		}
+		p.Schedule = schedule
+		return nil
+	}
+}
+
+// Validate checks that the values of the schedule are well formed.
+func (s *Schedule) Validate() error {
+	if _, err := s.location(); err != nil {
+		return fmt.Errorf(`invalid schedule time zone "%s": %v`, s.TimeZone, err)
+	}
+	if s.NotBefore != "" {
+		if _, err := time.Parse(time.RFC3339, s.NotBefore); err != nil {
+			return fmt.Errorf(`invalid schedule "notBefore", must be a RFC3339 time: %v`, err)
+		}
+	}
+	var start, end time.Time
+	var err error
+	if s.StartDate != "" {
+		if start, err = time.Parse(scheduleDateLayout, s.StartDate); err != nil {
+			return fmt.Errorf(`invalid schedule "startDate", must be formatted as YYYY-MM-DD: %v`, err)
+		}
+	}
+	if s.EndDate != "" {
+		if end, err = time.Parse(scheduleDateLayout, s.EndDate); err != nil {
+			return fmt.Errorf(`invalid schedule "endDate", must be formatted as YYYY-MM-DD: %v`, err)
+		}
+	}
+	if s.StartDate != "" && s.EndDate != "" && end.Before(start) {
+		return fmt.Errorf(`schedule "endDate" cannot be before "startDate"`)
+	}
+	for _, day := range s.Weekdays {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf(`invalid schedule weekday "%s"`, day)
+		}
+	}
+	for _, hours := range s.Hours {
+		if _, err := time.Parse(scheduleHourLayout, hours.Start); err != nil {
+			return fmt.Errorf(`invalid schedule hour "%s", must be formatted as HH:MM`, hours.Start)
+		}
+		if _, err := time.Parse(scheduleHourLayout, hours.End); err != nil {
+			return fmt.Errorf(`invalid schedule hour "%s", must be formatted as HH:MM`, hours.End)
+		}
+	}
+	return nil
+}
+
+func (s *Schedule) location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.TimeZone)
+}
+
+// IsActive checks whether the permission can be used at the given time.
+func (s *Schedule) IsActive(t time.Time) (bool, error) {
+	loc, err := s.location()
+	if err != nil {
+		return false, err
+	}
+	t = t.In(loc)
+	if s.NotBefore != "" {
+		notBefore, err := time.Parse(time.RFC3339, s.NotBefore)
+		if err != nil {
+			return false, err
+		}
+		if t.Before(notBefore) {
+			return false, nil
+		}
+	}
+	// the dates are compared as YYYY-MM-DD strings in the schedule time zone
+	date := t.Format(scheduleDateLayout)
+	if s.StartDate != "" && date < s.StartDate {
+		return false, nil
+	}
+	if s.EndDate != "" && date > s.EndDate {
+		return false, nil
+	}
+	if len(s.Weekdays) > 0 {
+		allowed := false
+		for _, day := range s.Weekdays {
+			if weekdays[strings.ToLower(day)] == t.Weekday() {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false, nil
+		}
+	}
+	if len(s.Hours) > 0 {
+		now := t.Hour()*60 + t.Minute()
+		for _, hours := range s.Hours {
+			if hours.contains(now) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// contains checks whether the minute of the day is in the range.
+func (h HourRange) contains(now int) bool {
+	start, end := minuteOfDay(h.Start), minuteOfDay(h.End)
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+func minuteOfDay(hour string) int {
+	t, _ := time.Parse(scheduleHourLayout, hour)
+	return t.Hour()*60 + t.Minute()
+}
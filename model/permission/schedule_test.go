@@ -0,0 +1,60 @@
+package permission
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func at(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
+func TestSchedule(t *testing.T) {
+	Convey("should validate the schedule", t, func() {
+		So((&Schedule{StartDate: "2021-03-01", EndDate: "2021-03-31", Weekdays: []string{"Monday"}}).Validate(), ShouldBeNil)
+		So((&Schedule{StartDate: "2021-03-31", EndDate: "2021-03-01"}).Validate(), ShouldNotBeNil)
+		So((&Schedule{Weekdays: []string{"someday"}}).Validate(), ShouldNotBeNil)
+		So((&Schedule{Hours: []HourRange{{Start: "9am", End: "17:00"}}}).Validate(), ShouldNotBeNil)
+		So((&Schedule{NotBefore: "2021-03-01"}).Validate(), ShouldNotBeNil)
+		So((&Schedule{TimeZone: "Nowhere/Unknown"}).Validate(), ShouldNotBeNil)
+	})
+	Convey("should apply the date window", t, func() {
+		s := &Schedule{NotBefore: "2021-03-01T12:00:00Z", EndDate: "2021-03-31"}
+		active, err := s.IsActive(at("2021-03-01T11:59:00Z"))
+		So(err, ShouldBeNil)
+		So(active, ShouldBeFalse)
+		active, _ = s.IsActive(at("2021-03-31T23:59:00Z"))
+		So(active, ShouldBeTrue)
+		active, _ = s.IsActive(at("2021-04-01T00:00:00Z"))
+		So(active, ShouldBeFalse)
+	})
+	Convey("should apply the weekdays and the hours", t, func() {
+		s := &Schedule{Weekdays: []string{"monday"}, Hours: []HourRange{{Start: "9:00", End: "17:00"}}}
+		// 2021-03-01 is a monday
+		active, _ := s.IsActive(at("2021-03-01T09:00:00Z"))
+		So(active, ShouldBeTrue)
+		active, _ = s.IsActive(at("2021-03-01T17:00:00Z"))
+		So(active, ShouldBeFalse)
+		active, _ = s.IsActive(at("2021-03-02T10:00:00Z"))
+		So(active, ShouldBeFalse)
+	})
+	Convey("should apply the hours spanning over midnight", t, func() {
+		s := &Schedule{Hours: []HourRange{{Start: "22:00", End: "06:00"}}}
+		active, _ := s.IsActive(at("2021-03-01T23:30:00Z"))
+		So(active, ShouldBeTrue)
+		active, _ = s.IsActive(at("2021-03-01T05:59:00Z"))
+		So(active, ShouldBeTrue)
+		active, _ = s.IsActive(at("2021-03-01T12:00:00Z"))
+		So(active, ShouldBeFalse)
+	})
+	Convey("should evaluate the schedule in its time zone", t, func() {
+		s := &Schedule{Hours: []HourRange{{Start: "09:00", End: "17:00"}}, TimeZone: "UTC"}
+		active, _ := s.IsActive(at("2021-03-01T15:00:00+05:00"))
+		So(active, ShouldBeTrue)
+		active, _ = s.IsActive(at("2021-03-01T10:00:00-08:00"))
+		So(active, ShouldBeFalse)
+	})
+}
@@ -0,0 +1,535 @@
+package querypolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Action defines what happens to the requests that violate a policy.
+type Action string
+
+const (
+	// Reject rejects the requests that violate the policy.
+	Reject Action = "reject"
+	// Rewrite rewrites the requests to comply with the policy: the sizes are
+	// clamped, the disallowed query clauses are replaced with a `match_none`
+	// query and the script fields, script sorts and script aggregations are removed.
+	Rewrite Action = "rewrite"
+)
+
+// Types of the violations of a policy.
+const (
+	ScriptNotAllowed          = "script_not_allowed"
+	RegexpNotAllowed          = "regexp_not_allowed"
+	LeadingWildcardNotAllowed = "leading_wildcard_not_allowed"
+	TermsLookupNotAllowed     = "terms_lookup_not_allowed"
+	MaxSizeExceeded           = "max_size_exceeded"
+	MaxBucketsExceeded        = "max_buckets_exceeded"
+	TemplateNotAllowed        = "template_not_allowed"
+)
+
+// Policy restricts the constructs of the query DSL that can be used in the
+// search, msearch and count requests, e.g. to prevent the public credentials
+// from being used to overload the cluster. The search templates are rejected
+// as their queries can't be checked before being rendered.
+type Policy struct {
+	Action                  Action `json:"action,omitempty"`
+	DisableScripts          bool   `json:"disable_scripts,omitempty"`
+	DisableRegexp           bool   `json:"disable_regexp,omitempty"`
+	DisableLeadingWildcards bool   `json:"disable_leading_wildcards,omitempty"`
+	DisableTermsLookup      bool   `json:"disable_terms_lookup,omitempty"`
+	// MaxSize is the maximum number of hits of a search and of a top_hits aggregation.
+	MaxSize *int `json:"max_size,omitempty"`
+	// MaxBuckets is the maximum size of the terms, multi_terms, significant_terms
+	// and composite aggregations.
+	MaxBuckets *int `json:"max_buckets,omitempty"`
+}
+
+// Violation is returned when a request violates a policy.
+type Violation struct {
+	Type    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return v.Message
+}
+
+// Validate checks that the policy can be applied.
+func (p *Policy) Validate() error {
+	switch p.Action {
+	case "", Reject, Rewrite:
+	default:
+		return fmt.Errorf(`invalid query policy action "%s", must be "reject" or "rewrite"`, p.Action)
+	}
+	if p.MaxSize != nil && *p.MaxSize < 0 {
+		return fmt.Errorf("query policy max_size cannot be negative")
+	}
+	if p.MaxBuckets != nil && *p.MaxBuckets < 0 {
+		return fmt.Errorf("query policy max_buckets cannot be negative")
+	}
+	return nil
+}
+
+var leadingWildcard = regexp.MustCompile(`(^|[\s(:])[*?]`)
+
+// queryStringRegexp matches the regular expressions of the lucene query syntax, the
+// terms enclosed in forward slashes.
+var queryStringRegexp = regexp.MustCompile(`(^|[\s(:+\-!])/`)
+
+// spanQueries are the keys of the span queries that wrap other span queries.
+var spanQueries = map[string][]string{
+	"span_multi":         {"match"},
+	"span_first":         {"match"},
+	"span_near":          {"clauses"},
+	"span_or":            {"clauses"},
+	"span_not":           {"include", "exclude"},
+	"span_containing":    {"big", "little"},
+	"span_within":        {"big", "little"},
+	"field_masking_span": {"query"},
+}
+
+// bucketAggs are the aggregations whose number of buckets is set by their size.
+var bucketAggs = map[string]bool{
+	"terms":             true,
+	"multi_terms":       true,
+	"significant_terms": true,
+	"significant_text":  true,
+	"composite":         true,
+}
+
+// scriptAggs are the aggregations that are defined by a script.
+var scriptAggs = map[string]bool{
+	"scripted_metric": true,
+	"bucket_script":   true,
+	"bucket_selector": true,
+}
+
+// enforcer walks a request body, the first violation is recorded if the
+// policy rejects the requests, the body is rewritten otherwise.
+type enforcer struct {
+	policy    *Policy
+	violation *Violation
+}
+
+func (e *enforcer) rewrite() bool {
+	return e.policy.Action == Rewrite
+}
+
+// reject records the violation of a construct that can't be rewritten.
+func (e *enforcer) reject(errType string, format string, a ...interface{}) {
+	if e.violation == nil {
+		e.violation = &Violation{Type: errType, Message: fmt.Sprintf(format, a...)}
+	}
+}
+
+// violates records the violation and reports whether the construct must be removed.
+func (e *enforcer) violates(errType string, format string, a ...interface{}) bool {
+	if !e.rewrite() && e.violation == nil {
+		e.violation = &Violation{Type: errType, Message: fmt.Sprintf(format, a...)}
+	}
+	return e.rewrite()
+}
+
+// checkSize clamps the size of an object if it exceeds the limit. Elasticsearch accepts
+// the numeric strings as sizes too, the other values are considered to exceed the limit.
+func (e *enforcer) checkSize(object map[string]interface{}, key string, limit *int, errType string, name string) {
+	if limit == nil {
+		return
+	}
+	var size float64
+	switch value := object[key].(type) {
+	case nil:
+		return
+	case float64:
+		size = value
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			if e.violates(errType, "%s must be a number", name) {
+				object[key] = *limit
+			}
+			return
+		}
+		size = parsed
+	default:
+		if e.violates(errType, "%s must be a number", name) {
+			object[key] = *limit
+		}
+		return
+	}
+	if size <= float64(*limit) {
+		return
+	}
+	if e.violates(errType, "maximum allowed %s is %d", name, *limit) {
+		object[key] = *limit
+	}
+}
+
+// ApplyToSearch applies the policy to the body of a search or count request, the
+// query string of the `q` param, if any, must be passed in order to be validated.
+// The body is modified in place if the policy rewrites the requests, the leading
+// wildcards of the `q` param must then be disabled with the `allow_leading_wildcard` param.
+// The regular expressions of the `q` param are always rejected as it can't be rewritten.
+func (p *Policy) ApplyToSearch(body map[string]interface{}, queryString string) error {
+	e := &enforcer{policy: p}
+	e.search(body)
+	if p.DisableLeadingWildcards && leadingWildcard.MatchString(queryString) {
+		e.violates(LeadingWildcardNotAllowed, "leading wildcards are not allowed in the query string")
+	}
+	if p.DisableRegexp && queryStringRegexp.MatchString(queryString) {
+		e.reject(RegexpNotAllowed, "regular expressions are not allowed in the query string")
+	}
+	if e.violation != nil {
+		return e.violation
+	}
+	return nil
+}
+
+// ApplyToMsearch applies the policy to each search of an `_msearch` request body.
+func (p *Policy) ApplyToMsearch(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	isHeader := true
+	for _, line := range bytes.Split(bytes.TrimRight(body, "\r\n"), []byte("\n")) {
+		if isHeader {
+			buf.Write(line)
+		} else {
+			search := make(map[string]interface{})
+			if err := json.Unmarshal(line, &search); err != nil {
+				return nil, fmt.Errorf("can't parse the msearch body: %v", err)
+			}
+			if err := p.ApplyToSearch(search, ""); err != nil {
+				return nil, err
+			}
+			raw, err := json.Marshal(search)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(raw)
+		}
+		buf.WriteByte('\n')
+		isHeader = !isHeader
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *enforcer) search(body map[string]interface{}) {
+	e.checkSize(body, "size", e.policy.MaxSize, MaxSizeExceeded, "size")
+	for _, key := range []string{"query", "post_filter"} {
+		if query, ok := body[key]; ok {
+			body[key] = e.query(query)
+		}
+	}
+	if e.policy.DisableScripts {
+		for _, key := range []string{"script_fields", "runtime_mappings"} {
+			if _, ok := body[key]; ok && e.violates(ScriptNotAllowed, `"%s" is not allowed`, key) {
+				delete(body, key)
+			}
+		}
+		if sort, ok := body["sort"]; ok {
+			body["sort"] = e.sort(sort)
+		}
+	}
+	for _, key := range []string{"aggs", "aggregations"} {
+		if aggs, ok := body[key].(map[string]interface{}); ok {
+			e.aggs(aggs)
+		}
+	}
+	if rescore, ok := body["rescore"]; ok {
+		for _, r := range asArray(rescore) {
+			if r, ok := r.(map[string]interface{}); ok {
+				if query, ok := r["query"].(map[string]interface{}); ok {
+					if rescoreQuery, ok := query["rescore_query"]; ok {
+						query["rescore_query"] = e.query(rescoreQuery)
+					}
+				}
+			}
+		}
+	}
+}
+
+// sort removes the script based sorts.
+func (e *enforcer) sort(sort interface{}) interface{} {
+	sorts, isArray := sort.([]interface{})
+	if !isArray {
+		sorts = []interface{}{sort}
+	}
+	allowed := []interface{}{}
+	for _, s := range sorts {
+		if s, ok := s.(map[string]interface{}); ok {
+			if _, ok := s["_script"]; ok && e.violates(ScriptNotAllowed, "script based sorting is not allowed") {
+				continue
+			}
+		}
+		allowed = append(allowed, s)
+	}
+	if !isArray && len(allowed) == 1 {
+		return allowed[0]
+	}
+	return allowed
+}
+
+func asArray(value interface{}) []interface{} {
+	if values, ok := value.([]interface{}); ok {
+		return values
+	}
+	return []interface{}{value}
+}
+
+// queries applies the policy to a query or an array of queries.
+func (e *enforcer) queries(value interface{}) interface{} {
+	if values, ok := value.([]interface{}); ok {
+		for i, v := range values {
+			values[i] = e.query(v)
+		}
+		return values
+	}
+	return e.query(value)
+}
+
+// query applies the policy to a query clause, the clause is replaced with a
+// `match_none` query if it isn't allowed by the policy.
+func (e *enforcer) query(value interface{}) interface{} {
+	clause, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	for queryType, v := range clause {
+		if !e.allowsClause(queryType, v) {
+			return map[string]interface{}{"match_none": map[string]interface{}{}}
+		}
+		body, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch queryType {
+		case "bool":
+			for _, key := range []string{"must", "should", "filter", "must_not"} {
+				if q, ok := body[key]; ok {
+					body[key] = e.queries(q)
+				}
+			}
+		case "dis_max":
+			if q, ok := body["queries"]; ok {
+				body["queries"] = e.queries(q)
+			}
+		case "constant_score":
+			if q, ok := body["filter"]; ok {
+				body["filter"] = e.query(q)
+			}
+		case "boosting":
+			for _, key := range []string{"positive", "negative"} {
+				if q, ok := body[key]; ok {
+					body[key] = e.query(q)
+				}
+			}
+		case "function_score":
+			if q, ok := body["query"]; ok {
+				body["query"] = e.query(q)
+			}
+			if functions, ok := body["functions"].([]interface{}); ok {
+				body["functions"] = e.functions(functions)
+			}
+			if _, ok := body["script_score"]; ok && e.policy.DisableScripts &&
+				e.violates(ScriptNotAllowed, `"script_score" is not allowed`) {
+				delete(body, "script_score")
+			}
+		case "nested", "has_child", "has_parent":
+			if q, ok := body["query"]; ok {
+				body["query"] = e.query(q)
+			}
+		case "query_string":
+			if e.policy.DisableLeadingWildcards && e.rewrite() {
+				body["allow_leading_wildcard"] = false
+			}
+		}
+		// the span queries can only wrap span queries, the whole span query is
+		// replaced if one of the wrapped queries isn't allowed
+		for _, key := range spanQueries[queryType] {
+			q, ok := body[key]
+			if !ok {
+				continue
+			}
+			body[key] = e.queries(q)
+			for _, wrapped := range asArray(body[key]) {
+				if isMatchNone(wrapped) {
+					return map[string]interface{}{"match_none": map[string]interface{}{}}
+				}
+			}
+		}
+	}
+	return clause
+}
+
+// isMatchNone checks whether a query clause was replaced by the policy.
+func isMatchNone(value interface{}) bool {
+	clause, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = clause["match_none"]
+	return ok && len(clause) == 1
+}
+
+// functions removes the script score functions of a function_score query.
+func (e *enforcer) functions(functions []interface{}) []interface{} {
+	allowed := []interface{}{}
+	for _, f := range functions {
+		function, ok := f.(map[string]interface{})
+		if !ok {
+			allowed = append(allowed, f)
+			continue
+		}
+		if _, ok := function["script_score"]; ok && e.policy.DisableScripts &&
+			e.violates(ScriptNotAllowed, `"script_score" is not allowed`) {
+			continue
+		}
+		if filter, ok := function["filter"]; ok {
+			function["filter"] = e.query(filter)
+		}
+		allowed = append(allowed, function)
+	}
+	return allowed
+}
+
+// allowsClause checks whether a leaf query clause is allowed by the policy.
+func (e *enforcer) allowsClause(queryType string, body interface{}) bool {
+	switch queryType {
+	case "script", "script_score":
+		if e.policy.DisableScripts {
+			return !e.violates(ScriptNotAllowed, `"%s" query is not allowed`, queryType)
+		}
+	case "regexp":
+		if e.policy.DisableRegexp {
+			return !e.violates(RegexpNotAllowed, `"regexp" query is not allowed`)
+		}
+	case "wildcard":
+		if e.policy.DisableLeadingWildcards && hasLeadingWildcard(body) {
+			return !e.violates(LeadingWildcardNotAllowed, "leading wildcards are not allowed")
+		}
+	case "query_string":
+		query := queryText(body)
+		if e.policy.DisableRegexp && queryStringRegexp.MatchString(query) {
+			return !e.violates(RegexpNotAllowed, "regular expressions are not allowed in the query string")
+		}
+		if e.policy.DisableLeadingWildcards && !e.rewrite() && leadingWildcard.MatchString(query) {
+			e.violates(LeadingWildcardNotAllowed, "leading wildcards are not allowed in the query string")
+		}
+	case "simple_query_string":
+		// the simple query string can't disable the leading wildcards
+		if e.policy.DisableLeadingWildcards && leadingWildcard.MatchString(queryText(body)) {
+			return !e.violates(LeadingWildcardNotAllowed, "leading wildcards are not allowed in the query string")
+		}
+	case "terms":
+		if e.policy.DisableTermsLookup && isTermsLookup(body) {
+			return !e.violates(TermsLookupNotAllowed, "terms lookup is not allowed")
+		}
+	}
+	return true
+}
+
+// queryText returns the query of a query_string or a simple_query_string query.
+func queryText(body interface{}) string {
+	if q, ok := body.(map[string]interface{}); ok {
+		query, _ := q["query"].(string)
+		return query
+	}
+	return ""
+}
+
+// hasLeadingWildcard checks whether the pattern of a wildcard query starts with a wildcard.
+func hasLeadingWildcard(body interface{}) bool {
+	fields, ok := body.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, v := range fields {
+		var pattern string
+		switch field := v.(type) {
+		case string:
+			pattern = field
+		case map[string]interface{}:
+			if value, ok := field["value"].(string); ok {
+				pattern = value
+			} else if value, ok := field["wildcard"].(string); ok {
+				pattern = value
+			}
+		}
+		if strings.HasPrefix(pattern, "*") || strings.HasPrefix(pattern, "?") {
+			return true
+		}
+	}
+	return false
+}
+
+// isTermsLookup checks whether a terms query fetches its terms from a document.
+func isTermsLookup(body interface{}) bool {
+	fields, ok := body.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, v := range fields {
+		if lookup, ok := v.(map[string]interface{}); ok {
+			if _, ok := lookup["path"]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// aggs applies the policy to the aggregations, the aggregations defined by a
+// script are removed if the policy rewrites the requests.
+func (e *enforcer) aggs(aggs map[string]interface{}) {
+	for name, a := range aggs {
+		agg, ok := a.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for aggType, v := range agg {
+			switch aggType {
+			case "aggs", "aggregations":
+				if subAggs, ok := v.(map[string]interface{}); ok {
+					e.aggs(subAggs)
+				}
+				continue
+			case "meta":
+				continue
+			}
+			body, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if e.policy.DisableScripts {
+				_, hasScript := body["script"]
+				if (scriptAggs[aggType] || hasScript) &&
+					e.violates(ScriptNotAllowed, `aggregation "%s" can't use a script`, name) {
+					delete(aggs, name)
+					break
+				}
+			}
+			if bucketAggs[aggType] {
+				e.checkSize(body, "size", e.policy.MaxBuckets, MaxBucketsExceeded, "aggregation size")
+				e.checkSize(body, "shard_size", e.policy.MaxBuckets, MaxBucketsExceeded, "aggregation shard size")
+			}
+			switch aggType {
+			case "top_hits":
+				e.checkSize(body, "size", e.policy.MaxSize, MaxSizeExceeded, "size")
+			case "filter":
+				agg[aggType] = e.query(body)
+			case "filters":
+				if filters, ok := body["filters"].(map[string]interface{}); ok {
+					for key, filter := range filters {
+						filters[key] = e.query(filter)
+					}
+				} else if filters, ok := body["filters"].([]interface{}); ok {
+					body["filters"] = e.queries(filters)
+				}
+			}
+		}
+	}
+}
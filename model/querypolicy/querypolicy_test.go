@@ -0,0 +1,123 @@
+package querypolicy
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func parse(body string) map[string]interface{} {
+	search := make(map[string]interface{})
+	So(json.Unmarshal([]byte(body), &search), ShouldBeNil)
+	return search
+}
+
+func violationType(err error) string {
+	if v, ok := err.(*Violation); ok {
+		return v.Type
+	}
+	return ""
+}
+
+func TestPolicyReject(t *testing.T) {
+	ten := 10
+	policy := &Policy{
+		DisableScripts:          true,
+		DisableRegexp:           true,
+		DisableLeadingWildcards: true,
+		DisableTermsLookup:      true,
+		MaxSize:                 &ten,
+		MaxBuckets:              &ten,
+	}
+	apply := func(body string, queryString string) string {
+		return violationType(policy.ApplyToSearch(parse(body), queryString))
+	}
+	Convey("should allow the queries complying with the policy", t, func() {
+		So(apply(`{"size":10,"query":{"bool":{"must":[{"match":{"title":"shoes"}},{"wildcard":{"sku":"AB*"}}]}},"aggs":{"brands":{"terms":{"field":"brand","size":10}}}}`, "title:shoes"), ShouldBeEmpty)
+	})
+	Convey("should reject the scripts", t, func() {
+		So(apply(`{"query":{"bool":{"filter":{"script":{"script":"doc['price'].value > 1"}}}}}`, ""), ShouldEqual, ScriptNotAllowed)
+		So(apply(`{"query":{"function_score":{"functions":[{"script_score":{"script":"1"}}]}}}`, ""), ShouldEqual, ScriptNotAllowed)
+		So(apply(`{"script_fields":{"total":{"script":"1"}}}`, ""), ShouldEqual, ScriptNotAllowed)
+		So(apply(`{"sort":[{"_script":{"type":"number","script":"1"}}]}`, ""), ShouldEqual, ScriptNotAllowed)
+		So(apply(`{"aggs":{"total":{"sum":{"script":"1"}}}}`, ""), ShouldEqual, ScriptNotAllowed)
+	})
+	Convey("should reject the expensive queries", t, func() {
+		So(apply(`{"query":{"regexp":{"title":"sh.*"}}}`, ""), ShouldEqual, RegexpNotAllowed)
+		So(apply(`{"query":{"wildcard":{"title":{"value":"*oes"}}}}`, ""), ShouldEqual, LeadingWildcardNotAllowed)
+		So(apply(`{"query":{"query_string":{"query":"title:*oes"}}}`, ""), ShouldEqual, LeadingWildcardNotAllowed)
+		So(apply(`{}`, "*oes"), ShouldEqual, LeadingWildcardNotAllowed)
+		So(apply(`{"query":{"terms":{"user":{"index":"users","id":"1","path":"followers"}}}}`, ""), ShouldEqual, TermsLookupNotAllowed)
+	})
+	Convey("should reject the regular expressions and the wildcards of the other queries", t, func() {
+		So(apply(`{"query":{"query_string":{"query":"title:/sh.*/"}}}`, ""), ShouldEqual, RegexpNotAllowed)
+		So(apply(`{}`, "title:/sh.*/"), ShouldEqual, RegexpNotAllowed)
+		So(apply(`{}`, "path:a/b"), ShouldBeEmpty)
+		So(apply(`{"query":{"simple_query_string":{"query":"*oes"}}}`, ""), ShouldEqual, LeadingWildcardNotAllowed)
+		So(apply(`{"query":{"span_multi":{"match":{"regexp":{"title":"sh.*"}}}}}`, ""), ShouldEqual, RegexpNotAllowed)
+		So(apply(`{"query":{"span_near":{"clauses":[{"span_term":{"title":"red"}},{"span_multi":{"match":{"wildcard":{"title":"*oes"}}}}]}}}`, ""), ShouldEqual, LeadingWildcardNotAllowed)
+	})
+	Convey("should reject the large sizes", t, func() {
+		So(apply(`{"size":100}`, ""), ShouldEqual, MaxSizeExceeded)
+		So(apply(`{"aggs":{"brands":{"terms":{"field":"brand"},"aggs":{"top":{"top_hits":{"size":100}}}}}}`, ""), ShouldEqual, MaxSizeExceeded)
+		So(apply(`{"aggs":{"brands":{"composite":{"size":1000,"sources":[]}}}}`, ""), ShouldEqual, MaxBucketsExceeded)
+	})
+	Convey("should reject the sizes given as strings", t, func() {
+		So(apply(`{"size":"10"}`, ""), ShouldBeEmpty)
+		So(apply(`{"size":"100000"}`, ""), ShouldEqual, MaxSizeExceeded)
+		So(apply(`{"size":"all"}`, ""), ShouldEqual, MaxSizeExceeded)
+		So(apply(`{"aggs":{"brands":{"terms":{"field":"brand","size":"1000"}}}}`, ""), ShouldEqual, MaxBucketsExceeded)
+	})
+	Convey("should apply the policy to each search of a msearch", t, func() {
+		_, err := policy.ApplyToMsearch([]byte("{}\n{\"size\":5}\n{\"index\":\"products\"}\n{\"size\":50}\n"))
+		So(violationType(err), ShouldEqual, MaxSizeExceeded)
+	})
+}
+
+func TestPolicyRewrite(t *testing.T) {
+	ten := 10
+	policy := &Policy{
+		Action:                  Rewrite,
+		DisableScripts:          true,
+		DisableRegexp:           true,
+		DisableLeadingWildcards: true,
+		MaxSize:                 &ten,
+		MaxBuckets:              &ten,
+	}
+	Convey("should rewrite the requests violating the policy", t, func() {
+		body := parse(`{
+			"size": 100,
+			"query": {"bool": {"must": [{"match": {"title": "shoes"}}, {"regexp": {"title": "sh.*"}}]}},
+			"script_fields": {"total": {"script": "1"}},
+			"aggs": {
+				"brands": {"terms": {"field": "brand", "size": 100}},
+				"total": {"sum": {"script": "1"}}
+			}
+		}`)
+		So(policy.ApplyToSearch(body, ""), ShouldBeNil)
+		raw, _ := json.Marshal(body)
+		So(string(raw), ShouldEqual, `{"aggs":{"brands":{"terms":{"field":"brand","size":10}}},`+
+			`"query":{"bool":{"must":[{"match":{"title":"shoes"}},{"match_none":{}}]}},"size":10}`)
+	})
+	Convey("should replace the span queries wrapping a disallowed query", t, func() {
+		body := parse(`{"query":{"span_near":{"clauses":[{"span_term":{"title":"red"}},{"span_multi":{"match":{"regexp":{"title":"sh.*"}}}}]}}}`)
+		So(policy.ApplyToSearch(body, ""), ShouldBeNil)
+		So(body["query"], ShouldResemble, map[string]interface{}{"match_none": map[string]interface{}{}})
+	})
+	Convey("should reject the regular expressions of the q param", t, func() {
+		So(violationType(policy.ApplyToSearch(parse(`{}`), "title:/sh.*/")), ShouldEqual, RegexpNotAllowed)
+	})
+	Convey("should disable the leading wildcards of the query string", t, func() {
+		body := parse(`{"query":{"query_string":{"query":"*oes"}}}`)
+		So(policy.ApplyToSearch(body, "*oes"), ShouldBeNil)
+		So(body["query"].(map[string]interface{})["query_string"], ShouldResemble, map[string]interface{}{
+			"query":                  "*oes",
+			"allow_leading_wildcard": false,
+		})
+	})
+	Convey("should validate the policy", t, func() {
+		So((&Policy{Action: "ignore"}).Validate(), ShouldNotBeNil)
+		So(policy.Validate(), ShouldBeNil)
+	})
+}
@@ -223,8 +223,11 @@ func Reindex(ctx context.Context, sourceIndex string, config *ReindexConfig, wai
 	// Configure reindex source
 	src := es7.NewReindexSource().
 		Index(sourceIndex).
-		Type(config.Types...).
 		FetchSourceIncludeExclude(config.Include, config.Exclude)
+	// the types can't be filtered once the mapping types are removed
+	if util.GetDocType() != "" {
+		src.Type(config.Types...)
+	}
 
 	// Configure reindex dest
 	dest := es7.NewReindexDestination().
@@ -39,6 +39,18 @@ type User struct {
 	Indices          []string            `json:"indices"`
 	CreatedAt        string              `json:"created_at"`
 	Sources          *[]string           `json:"sources"`
+	TOTP             *TOTP               `json:"totp,omitempty"`
+	Provider         string              `json:"provider,omitempty"`
+}
+
+// TOTP defines the time based one time password enrollment of a user. The
+// recovery codes are stored as bcrypt hashes, the time step of the last accepted
+// code is stored so that a code can't be replayed.
+type TOTP struct {
+	Secret        string   `json:"secret,omitempty"`
+	Enabled       bool     `json:"enabled"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	LastStep      int64    `json:"last_step,omitempty"`
 }
 
 // Options is a function type used to define a user's properties.
@@ -237,6 +249,9 @@ func (u *User) HasCategory(category category.Category) bool {
 }
 
 func (u *User) HasAction(action UserAction) bool {
+	if u.AllowedActions == nil {
+		return false
+	}
 	for _, c := range *u.AllowedActions {
 		if c == action {
 			return true
@@ -245,6 +260,19 @@ func (u *User) HasAction(action UserAction) bool {
 	return false
 }
 
+// IsPrivileged checks whether the user is an admin or can manage other users or the access control.
+func (u *User) IsPrivileged() bool {
+	if u.IsAdmin != nil && *u.IsAdmin {
+		return true
+	}
+	return u.HasAction(UserManagement) || u.HasAction(AccessControl)
+}
+
+// HasMFA checks whether the user has completed the TOTP enrollment.
+func (u *User) HasMFA() bool {
+	return u.TOTP != nil && u.TOTP.Enabled
+}
+
 func (u *User) hasCategoryForACL(acl acl.ACL) bool {
 	for _, c := range u.Categories {
 		if c.HasACL(acl) {
@@ -354,6 +382,9 @@ func (u *User) GetPatch() (map[string]interface{}, error) {
 	if u.CreatedAt != "" {
 		return nil, errors.NewUnsupportedPatchError("user", "created_at")
 	}
+	if u.TOTP != nil {
+		return nil, errors.NewUnsupportedPatchError("user", "totp")
+	}
 
 	return patch, nil
 }
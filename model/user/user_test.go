@@ -0,0 +1,22 @@
+package user
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIsPrivileged(t *testing.T) {
+	isAdmin := true
+	Convey("should treat the admins without actions as privileged", t, func() {
+		u := &User{Username: "foo", IsAdmin: &isAdmin}
+		So(u.IsPrivileged(), ShouldBeTrue)
+		So(u.HasAction(UserManagement), ShouldBeFalse)
+	})
+	Convey("should check the actions of the other users", t, func() {
+		u := &User{Username: "foo"}
+		So(u.IsPrivileged(), ShouldBeFalse)
+		u.AllowedActions = &[]UserAction{AccessControl}
+		So(u.IsPrivileged(), ShouldBeTrue)
+	})
+}
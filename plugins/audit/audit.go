@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/plugins"
+)
+
+const (
+	logTag              = "[audit]"
+	defaultAuditEsIndex = ".audit"
+	envAuditEsIndex     = "AUDIT_ES_INDEX"
+	settings            = `{ "settings" : { %s "index.number_of_shards" : 1, "index.number_of_replicas" : %d }, "mappings": %s }`
+	eventMappings       = `{
+		"properties": {
+			"actor": { "type": "keyword" },
+			"action": { "type": "keyword" },
+			"target_type": { "type": "keyword" },
+			"target": { "type": "keyword" },
+			"source_ip": { "type": "keyword" },
+			"timestamp": { "type": "date" },
+			"before": { "type": "object", "enabled": false },
+			"after": { "type": "object", "enabled": false },
+			"diff": { "type": "object", "enabled": false }
+		}
+	}`
+)
+
+var (
+	singleton *Audit
+	once      sync.Once
+)
+
+// Audit plugin records the changes made to the permissions, users and roles.
+type Audit struct {
+	es auditService
+}
+
+// Instance returns the singleton instance of Audit plugin.
+// Note: Only this function must be used (both within and outside the package) to
+// obtain the instance Audit in order to avoid stateless instances of the plugin.
+func Instance() *Audit {
+	once.Do(func() { singleton = &Audit{} })
+	return singleton
+}
+
+// Name returns the name of the plugin: "[audit]"
+func (a *Audit) Name() string {
+	return logTag
+}
+
+// InitFunc is a part of Plugin interface that gets executed only once, and initializes
+// the dao, i.e. elasticsearch before the plugin is operational.
+func (a *Audit) InitFunc() error {
+	log.Println(logTag, ": initializing plugin")
+
+	indexName := os.Getenv(envAuditEsIndex)
+	if indexName == "" {
+		indexName = defaultAuditEsIndex
+	}
+
+	es, err := initPlugin(indexName, settings)
+	if err != nil {
+		return err
+	}
+	a.es = es
+	return nil
+}
+
+// Routes returns the routes to query the audit events.
+func (a *Audit) Routes() []plugins.Route {
+	return a.routes()
+}
+
+// Default empty middleware array function
+func (a *Audit) ESMiddleware() []middleware.Middleware {
+	return make([]middleware.Middleware, 0)
+}
+
+// Default empty middleware array function
+func (a *Audit) RSMiddleware() []middleware.Middleware {
+	return make([]middleware.Middleware, 0)
+}
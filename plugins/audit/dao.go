@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/util"
+)
+
+type elasticsearch struct {
+	indexName string
+	cluster   *util.Cluster
+}
+
+// eventsFilter represents the filters applied to query the audit events.
+type eventsFilter struct {
+	Actor      string
+	Target     string
+	TargetType string
+	Action     string
+	StartDate  string
+	EndDate    string
+	Offset     int
+	Size       int
+}
+
+func initPlugin(indexName, config string) (*elasticsearch, error) {
+	ctx := context.Background()
+
+	es := &elasticsearch{indexName, util.ClusterForIndex(indexName)}
+
+	// Check if the meta index already exists
+	exists, err := es.cluster.Client7().IndexExists(indexName).
+		Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: error while checking if index already exists: %v", logTag, err)
+	}
+	if exists {
+		log.Println(logTag, ": index named", indexName, "already exists, skipping...")
+		return es, nil
+	}
+
+	replicas := util.GetReplicas()
+	mappings := eventMappings
+	if es.cluster.GetVersion() == 6 {
+		mappings = fmt.Sprintf(`{"_doc": %s}`, eventMappings)
+	}
+	settings := fmt.Sprintf(config, es.cluster.HiddenIndexSettings(), replicas, mappings)
+
+	// this works for ES6 client as well
+	_, err = es.cluster.Client7().CreateIndex(indexName).
+		Body(settings).
+		Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: error while creating index named %s: %v", logTag, indexName, err)
+	}
+
+	log.Println(logTag, ": successfully created index named", indexName)
+	return es, nil
+}
+
+func (es *elasticsearch) indexEvent(ctx context.Context, e Event) error {
+	_, err := es.cluster.Client7().Index().
+		Index(es.indexName).
+		Type("_doc").
+		BodyJson(e).
+		Do(ctx)
+	return err
+}
+
+func (es *elasticsearch) getEvents(ctx context.Context, filter eventsFilter) ([]Event, int64, error) {
+	switch es.cluster.GetVersion() {
+	case 6:
+		return es.getEventsEs6(ctx, filter)
+	default:
+		return es.getEventsEs7(ctx, filter)
+	}
+}
@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	es7 "github.com/olivere/elastic/v7"
+)
+
+func (es *elasticsearch) getEventsEs7(ctx context.Context, filter eventsFilter) ([]Event, int64, error) {
+	query := es7.NewBoolQuery().Filter(es7.NewRangeQuery("timestamp").
+		From(filter.StartDate).
+		To(filter.EndDate))
+	terms := map[string]string{
+		"actor":       filter.Actor,
+		"target":      filter.Target,
+		"target_type": filter.TargetType,
+		"action":      filter.Action,
+	}
+	for field, value := range terms {
+		if value != "" {
+			query.Filter(es7.NewTermQuery(field, value))
+		}
+	}
+
+	response, err := es.cluster.Client7().Search(es.indexName).
+		Query(query).
+		From(filter.Offset).
+		Size(filter.Size).
+		SortWithInfo(es7.SortInfo{Field: "timestamp", UnmappedType: "date", Ascending: false}).
+		Do(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := []Event{}
+	for _, hit := range response.Hits.Hits {
+		var e Event
+		if err := json.Unmarshal(hit.Source, &e); err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+	}
+	return events, response.Hits.TotalHits.Value, nil
+}
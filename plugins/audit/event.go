@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/appbaseio/reactivesearch-api/model/difference"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Actions recorded by the audit events.
+const (
+	Create = "create"
+	Update = "update"
+	Delete = "delete"
+)
+
+// Types of the targets recorded by the audit events.
+const (
+	TargetPermission = "permission"
+	TargetUser       = "user"
+	TargetRole       = "role"
+)
+
+const redacted = "[redacted]"
+
+// Event represents a change made to a permission, user or role.
+type Event struct {
+	Actor      string                 `json:"actor"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	Target     string                 `json:"target"`
+	SourceIP   string                 `json:"source_ip"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Before     json.RawMessage        `json:"before,omitempty"`
+	After      json.RawMessage        `json:"after,omitempty"`
+	Diff       *difference.Difference `json:"diff,omitempty"`
+}
+
+// setStates sets the redacted before and after states of the target along
+// with the diff between them.
+func (e *Event) setStates(before, after []byte) {
+	before, after = redactPasswords(before, after)
+	e.Before = before
+	e.After = after
+
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffMain(string(before), string(after), false)
+	e.Diff = &difference.Difference{Body: dmp.DiffToDelta(diffs)}
+}
+
+// redactPasswords returns the indented states with the passwords redacted,
+// a changed password is marked as such in the after state.
+func redactPasswords(before, after []byte) ([]byte, []byte) {
+	beforeState, beforePassword := redactPassword(before)
+	afterState, afterPassword := redactPassword(after)
+	if beforeState != nil && afterState != nil && afterPassword != nil &&
+		(beforePassword == nil || !bytes.Equal(beforePassword, afterPassword)) {
+		afterState["password"] = redacted + ", changed"
+	}
+	return indent(beforeState, before), indent(afterState, after)
+}
+
+func redactPassword(raw []byte) (map[string]interface{}, json.RawMessage) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, nil
+	}
+	redactedState := make(map[string]interface{}, len(state))
+	for key, value := range state {
+		redactedState[key] = value
+	}
+	password, ok := state["password"]
+	if ok {
+		redactedState["password"] = redacted
+	}
+	return redactedState, password
+}
+
+// indent marshals the state with sorted keys so that the diff only
+// contains the changed fields, the raw state is returned if it isn't an object.
+func indent(state map[string]interface{}, raw []byte) []byte {
+	if state == nil {
+		if len(raw) == 0 || !json.Valid(raw) {
+			return nil
+		}
+		return raw
+	}
+	indented, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil
+	}
+	return indented
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func state(raw []byte) map[string]interface{} {
+	var s map[string]interface{}
+	json.Unmarshal(raw, &s)
+	return s
+}
+
+func TestSetStates(t *testing.T) {
+	Convey("should redact the passwords", t, func() {
+		var e Event
+		e.setStates(nil, []byte(`{"username":"foo","password":"secret"}`))
+		So(e.Before, ShouldBeNil)
+		So(state(e.After)["password"], ShouldEqual, redacted)
+		So(string(e.After), ShouldNotContainSubstring, "secret")
+		So(e.Diff.Body, ShouldNotContainSubstring, "secret")
+	})
+	Convey("should mark the changed passwords", t, func() {
+		var e Event
+		e.setStates([]byte(`{"username":"foo","password":"old"}`), []byte(`{"username":"foo","password":"new"}`))
+		So(state(e.Before)["password"], ShouldEqual, redacted)
+		So(state(e.After)["password"], ShouldEqual, redacted+", changed")
+		So(e.Diff.Body, ShouldNotEqual, "")
+	})
+	Convey("should not mark the unchanged passwords", t, func() {
+		var e Event
+		e.setStates([]byte(`{"password":"same","acl":["search"]}`), []byte(`{"acl":["search","get"],"password":"same"}`))
+		So(state(e.After)["password"], ShouldEqual, redacted)
+		So(state(e.After)["acl"], ShouldResemble, []interface{}{"search", "get"})
+	})
+	Convey("should keep the state of the deleted target", t, func() {
+		var e Event
+		e.setStates([]byte(`{"username":"foo"}`), nil)
+		So(state(e.Before)["username"], ShouldEqual, "foo")
+		So(e.After, ShouldBeNil)
+	})
+}
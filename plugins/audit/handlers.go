@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/util"
+)
+
+const (
+	defaultResponseSize = 100
+	maxResponseSize     = 1000
+	defaultTimeFormat   = "2006/01/02"
+)
+
+// parseDate parses the date in either RFC3339 or the default time format.
+func parseDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(defaultTimeFormat, value)
+	return t, true, err
+}
+
+// newEventsFilter returns the filter from the query params, the events of
+// the previous 30 days are returned by default.
+func newEventsFilter(values url.Values) (eventsFilter, error) {
+	now := time.Now()
+	filter := eventsFilter{
+		Actor:      values.Get("actor"),
+		Target:     values.Get("target"),
+		TargetType: values.Get("target_type"),
+		Action:     values.Get("action"),
+		StartDate:  now.AddDate(0, 0, -30).Format(time.RFC3339),
+		EndDate:    now.Format(time.RFC3339),
+		Size:       defaultResponseSize,
+	}
+
+	if value := values.Get("start_date"); value != "" {
+		t, _, err := parseDate(value)
+		if err != nil {
+			return filter, fmt.Errorf(`invalid value "%s" for query param "start_date"`, value)
+		}
+		filter.StartDate = t.Format(time.RFC3339)
+	}
+	if value := values.Get("end_date"); value != "" {
+		t, isDay, err := parseDate(value)
+		if err != nil {
+			return filter, fmt.Errorf(`invalid value "%s" for query param "end_date"`, value)
+		}
+		if isDay {
+			// Use end of the day for to range
+			year, month, day := t.Date()
+			t = time.Date(year, month, day, 23, 59, 59, 0, t.Location())
+		}
+		filter.EndDate = t.Format(time.RFC3339)
+	}
+	if value := values.Get("from"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf(`invalid value "%s" for query param "from"`, value)
+		}
+		filter.Offset = offset
+	}
+	if value := values.Get("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 || size > maxResponseSize {
+			return filter, fmt.Errorf(`invalid value "%s" for query param "size", must be between 0 and %d`, value, maxResponseSize)
+		}
+		filter.Size = size
+	}
+	return filter, nil
+}
+
+func (a *Audit) getEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		filter, err := newEventsFilter(req.URL.Query())
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		events, total, err := a.es.getEvents(req.Context(), filter)
+		if err != nil {
+			log.Errorln(logTag, ": error fetching audit events :", err)
+			util.WriteBackError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		raw, err := json.Marshal(map[string]interface{}{
+			"events": events,
+			"total":  total,
+			"took":   time.Since(start).Milliseconds(),
+		})
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error while marshalling the audit events", http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, raw, http.StatusOK)
+	}
+}
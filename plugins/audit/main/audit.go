@@ -0,0 +1,8 @@
+package main
+
+import (
+	"github.com/appbaseio/reactivesearch-api/plugins"
+	"github.com/appbaseio/reactivesearch-api/plugins/audit"
+)
+
+var PluginInstance plugins.Plugin = audit.Instance()
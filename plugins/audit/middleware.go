@@ -0,0 +1,229 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/middleware/classify"
+	"github.com/appbaseio/reactivesearch-api/middleware/validate"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/model/user"
+	"github.com/appbaseio/reactivesearch-api/plugins/auth"
+	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
+	"github.com/appbaseio/reactivesearch-api/util/iplookup"
+	"github.com/gorilla/mux"
+)
+
+type chain struct {
+	middleware.Fifo
+}
+
+func (c *chain) Wrap(h http.HandlerFunc) http.HandlerFunc {
+	return c.Adapt(h, list()...)
+}
+
+func list() []middleware.Middleware {
+	return []middleware.Middleware{
+		classifyCategory,
+		classify.Op(),
+		classify.Indices(),
+		auth.BasicAuth(),
+		validate.Sources(),
+		validate.Operation(),
+		validate.Category(),
+		telemetry.Recorder(),
+	}
+}
+
+func classifyCategory(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		requestCategory := category.Logs
+
+		ctx := category.NewContext(req.Context(), &requestCategory)
+		req = req.WithContext(ctx)
+
+		h(w, req)
+	}
+}
+
+// isAdmin restricts the access to the admin users, the audit events record the
+// changes made to the credentials of all the users.
+func isAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		reqCredential, err := credential.FromContext(ctx)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			telemetry.WriteBackErrorWithTelemetry(req, w, "an error occurred while validating user admin", http.StatusInternalServerError)
+			return
+		}
+		if reqCredential == credential.User {
+			reqUser, err := user.FromContext(ctx)
+			if err != nil {
+				log.Errorln(logTag, ":", err)
+				telemetry.WriteBackErrorWithTelemetry(req, w, "an error occurred while validating user admin", http.StatusInternalServerError)
+				return
+			}
+			if reqUser.IsAdmin != nil && *reqUser.IsAdmin {
+				h(w, req)
+				return
+			}
+		}
+		w.Header().Set("www-authenticate", "Basic realm=\"Authentication Required\"")
+		telemetry.WriteBackErrorWithTelemetry(req, w, "only the admin users can access the audit events", http.StatusUnauthorized)
+	}
+}
+
+// Target describes how the audited routes identify and load the changed target.
+type Target struct {
+	// Type is the type of the target, e.g. "permission".
+	Type string
+	// ID returns the identifier of the target from the request, or from
+	// the response if it isn't known before the request is served.
+	ID func(req *http.Request, res []byte) string
+	// Load returns the stored state of the target.
+	Load func(ctx context.Context, id string) ([]byte, error)
+}
+
+// VarID returns the identifier of the target from the route variable.
+func VarID(name string) func(req *http.Request, res []byte) string {
+	return func(req *http.Request, res []byte) string {
+		return mux.Vars(req)[name]
+	}
+}
+
+// ResponseUsernameID returns the username from the response for the routes
+// that create the target.
+func ResponseUsernameID(req *http.Request, res []byte) string {
+	var body struct {
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(res, &body); err != nil {
+		return ""
+	}
+	return body.Username
+}
+
+// RequestUsernameID returns the username of the authenticated user for the
+// routes that change the requesting user. The user is read from the request
+// context, so that the sessions, certificates and tokens identify it as well.
+func RequestUsernameID(req *http.Request, res []byte) string {
+	reqUser, err := user.FromContext(req.Context())
+	if err != nil {
+		return ""
+	}
+	return reqUser.Username
+}
+
+// Recorder records an audit event for the successful requests that create,
+// update or delete the target.
+func Recorder(target Target) middleware.Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			action := actionOf(req.Method)
+			// the requests to sync the local state of the nodes aren't changes
+			if action == "" || req.URL.Query().Get("local") == "true" {
+				h(w, req)
+				return
+			}
+
+			id := target.ID(req, nil)
+			var before []byte
+			if id != "" && action != Create {
+				before = loadTarget(req.Context(), target, id)
+			}
+
+			resp := httptest.NewRecorder()
+			h(resp, req)
+
+			for key, values := range resp.Header() {
+				for _, value := range values {
+					w.Header().Add(key, value)
+				}
+			}
+			w.WriteHeader(resp.Code)
+			body := resp.Body.Bytes()
+			w.Write(body)
+
+			if resp.Code < http.StatusOK || resp.Code >= http.StatusMultipleChoices {
+				return
+			}
+			if id == "" {
+				id = target.ID(req, body)
+			}
+			var after []byte
+			if id != "" && action != Delete {
+				after = loadTarget(req.Context(), target, id)
+			}
+			Record(req, Event{
+				Action:     action,
+				TargetType: target.Type,
+				Target:     id,
+			}, before, after)
+		}
+	}
+}
+
+func actionOf(method string) string {
+	switch method {
+	case http.MethodPost:
+		return Create
+	case http.MethodPut, http.MethodPatch:
+		return Update
+	case http.MethodDelete:
+		return Delete
+	}
+	return ""
+}
+
+func loadTarget(ctx context.Context, target Target, id string) []byte {
+	raw, err := target.Load(ctx, id)
+	if err != nil {
+		log.Warnln(logTag, ": unable to load", target.Type, id, ":", err)
+		return nil
+	}
+	return raw
+}
+
+// Record indexes the audit event with the before and after states of the
+// target, the actor, source ip and timestamp are derived from the request.
+func Record(req *http.Request, e Event, before, after []byte) {
+	es := Instance().es
+	if es == nil {
+		return
+	}
+	if e.Actor == "" {
+		e.Actor = actorOf(req)
+	}
+	e.SourceIP = iplookup.FromRequest(req)
+	e.Timestamp = time.Now()
+	e.setStates(before, after)
+
+	// the request context is cancelled once the response is written
+	go func() {
+		if err := es.indexEvent(context.Background(), e); err != nil {
+			log.Errorln(logTag, ": error indexing audit event :", err)
+		}
+	}()
+}
+
+// actorOf returns the username of the credentials authenticated by the auth
+// middleware, whatever the authentication method.
+func actorOf(req *http.Request) string {
+	if reqUser, err := user.FromContext(req.Context()); err == nil && reqUser.Username != "" {
+		return reqUser.Username
+	}
+	if reqPermission, err := permission.FromContext(req.Context()); err == nil {
+		return reqPermission.Username
+	}
+	return ""
+}
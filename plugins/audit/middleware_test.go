@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/model/user"
+)
+
+func TestIsAdmin(t *testing.T) {
+	serve := func(req *http.Request) int {
+		req.Header.Set("X-Enable-Telemetry", "false")
+		w := httptest.NewRecorder()
+		isAdmin(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})(w, req)
+		return w.Code
+	}
+	newRequest := func() *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/_audit", nil)
+	}
+	Convey("should allow the admin users", t, func() {
+		admin, _ := user.NewAdmin("admin", "secret")
+		req := newRequest()
+		ctx := credential.NewContext(req.Context(), credential.User)
+		ctx = user.NewContext(ctx, admin)
+		So(serve(req.WithContext(ctx)), ShouldEqual, http.StatusOK)
+	})
+	Convey("should reject the other users and the permissions", t, func() {
+		u, _ := user.New("analyst", "secret", user.SetAllowedActions([]user.UserAction{user.Analytics}))
+		req := newRequest()
+		ctx := credential.NewContext(req.Context(), credential.User)
+		ctx = user.NewContext(ctx, u)
+		So(serve(req.WithContext(ctx)), ShouldEqual, http.StatusUnauthorized)
+
+		p, _ := permission.New("admin", permission.SetCategories([]category.Category{category.Logs}))
+		req = newRequest()
+		ctx = credential.NewContext(req.Context(), credential.Permission)
+		ctx = permission.NewContext(ctx, p)
+		So(serve(req.WithContext(ctx)), ShouldEqual, http.StatusUnauthorized)
+	})
+}
+
+func TestActor(t *testing.T) {
+	Convey("should read the user from the request context", t, func() {
+		u, _ := user.New("analyst", "secret")
+		req := httptest.NewRequest(http.MethodPatch, "/_user", nil)
+		So(RequestUsernameID(req, nil), ShouldBeEmpty)
+		So(actorOf(req), ShouldBeEmpty)
+
+		req = req.WithContext(user.NewContext(req.Context(), u))
+		So(RequestUsernameID(req, nil), ShouldEqual, "analyst")
+		So(actorOf(req), ShouldEqual, "analyst")
+	})
+	Convey("should read the permission from the request context", t, func() {
+		p, _ := permission.New("admin", permission.SetCategories([]category.Category{category.Logs}))
+		req := httptest.NewRequest(http.MethodPost, "/_permission", nil)
+		req = req.WithContext(permission.NewContext(req.Context(), p))
+		So(actorOf(req), ShouldEqual, p.Username)
+		So(RequestUsernameID(req, nil), ShouldBeEmpty)
+	})
+}
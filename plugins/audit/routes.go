@@ -0,0 +1,20 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/appbaseio/reactivesearch-api/plugins"
+)
+
+func (a *Audit) routes() []plugins.Route {
+	middleware := (&chain{}).Wrap
+	return []plugins.Route{
+		{
+			Name:        "Get audit events",
+			Methods:     []string{http.MethodGet},
+			Path:        "/_audit",
+			HandlerFunc: middleware(isAdmin(a.getEvents())),
+			Description: "Returns the audit events of the changes made to the permissions, users and roles, only accessible to the admin users",
+		},
+	}
+}
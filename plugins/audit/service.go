@@ -0,0 +1,8 @@
+package audit
+
+import "context"
+
+type auditService interface {
+	indexEvent(ctx context.Context, e Event) error
+	getEvents(ctx context.Context, filter eventsFilter) ([]Event, int64, error)
+}
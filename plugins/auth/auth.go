@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/appbaseio/reactivesearch-api/plugins"
 	"github.com/appbaseio/reactivesearch-api/util"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/robfig/cron"
 )
 
 const (
@@ -52,6 +54,8 @@ type Auth struct {
 	mu              sync.Mutex
 	jwtRsaPublicKey *rsa.PublicKey
 	jwtRoleKey      string
+	sessionTTL      time.Duration
+	authenticators  []Authenticator
 	es              authService
 }
 
@@ -86,10 +90,18 @@ func (a *Auth) InitFunc() error {
 	if publicKeyIndex == "" {
 		publicKeyIndex = defaultPublicKeyEsIndex
 	}
+	sessionIndex := getSessionIndex()
+	a.sessionTTL = getSessionTTL()
 	var err error
 
 	// initialize the dao
-	a.es, err = initPlugin(userIndex, permissionIndex)
+	a.es, err = initPlugin(userIndex, permissionIndex, sessionIndex)
+	if err != nil {
+		return err
+	}
+
+	// Create sessions index
+	_, err = a.es.createIndex(sessionIndex, settings)
 	if err != nil {
 		return err
 	}
@@ -139,12 +151,29 @@ func (a *Auth) InitFunc() error {
 		a.jwtRoleKey = record.RoleKey
 	}
 
+	if delegate, ok := delegateAuthenticatorFromEnv(); ok {
+		a.RegisterAuthenticator(delegate)
+	}
+	ldapAuthenticator, err := ldapAuthenticatorFromEnv(a.es)
+	if err != nil {
+		return err
+	}
+	if ldapAuthenticator != nil {
+		a.RegisterAuthenticator(ldapAuthenticator)
+	}
+
 	// Set plugin cache sync script
 	s := CacheSyncScript{
 		index: publicKeyIndex,
 		a:     a,
 	}
 	util.AddSyncScript(s)
+	util.AddSyncScript(SessionSyncScript{index: sessionIndex})
+
+	// Delete the expired sessions periodically
+	cronjob := cron.New()
+	cronjob.AddFunc("@every 1h", a.purgeExpiredSessions)
+	cronjob.Start()
 
 	return nil
 }
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+)
+
+// Authenticator resolves the basic auth credentials of a request from an identity
+// provider other than the users and permissions indices.
+type Authenticator interface {
+	// Name returns the name of the authenticator, used for logging.
+	Name() string
+
+	// Authenticate verifies the credentials and returns the user or the permission
+	// they map to. It must return a nil credential without an error if the credentials
+	// aren't recognized, so that the next authenticator can be tried.
+	Authenticate(ctx context.Context, username, password string) (credential.AuthCredential, error)
+}
+
+// RegisterAuthenticator adds an authenticator to be tried when the credentials aren't
+// found in elasticsearch. Authenticators are tried in the order of registration.
+func (a *Auth) RegisterAuthenticator(authenticator Authenticator) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.authenticators = append(a.authenticators, authenticator)
+}
+
+// authenticate returns the credential resolved by the first authenticator that
+// recognizes the username and password.
+func (a *Auth) authenticate(ctx context.Context, username, password string) (credential.AuthCredential, bool) {
+	a.mu.Lock()
+	authenticators := a.authenticators
+	a.mu.Unlock()
+
+	for _, authenticator := range authenticators {
+		c, err := authenticator.Authenticate(ctx, username, password)
+		if err != nil {
+			log.Errorln(logTag, ":", authenticator.Name(), "authenticator failed:", err)
+			continue
+		}
+		if c != nil {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+type verifiedCacheEntry struct {
+	credential credential.AuthCredential
+	expiresAt  time.Time
+}
+
+// verifiedCache caches the credentials resolved by an authenticator for a ttl. Entries
+// are keyed by both the username and the password so that a cached credential is never
+// returned for a wrong password.
+type verifiedCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	cache map[string]verifiedCacheEntry
+}
+
+func newVerifiedCache(ttl time.Duration) *verifiedCache {
+	return &verifiedCache{
+		ttl:   ttl,
+		cache: make(map[string]verifiedCacheEntry),
+	}
+}
+
+func verifiedCacheKey(username, password string) string {
+	hash := sha256.Sum256([]byte(username + ":" + password))
+	return hex.EncodeToString(hash[:])
+}
+
+func (v *verifiedCache) get(username, password string) (credential.AuthCredential, bool) {
+	key := verifiedCacheKey(username, password)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	entry, ok := v.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(v.cache, key)
+		return nil, false
+	}
+	return entry.credential, true
+}
+
+func (v *verifiedCache) set(username, password string, c credential.AuthCredential) {
+	key := verifiedCacheKey(username, password)
+	v.mu.Lock()
+	v.cache[key] = verifiedCacheEntry{credential: c, expiresAt: time.Now().Add(v.ttl)}
+	v.mu.Unlock()
+}
+
+// getCacheTTL reads the cache ttl of an authenticator from the env.
+func getCacheTTL(env string, defaultTTL time.Duration) time.Duration {
+	value := os.Getenv(env)
+	if value == "" {
+		return defaultTTL
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		log.Errorln(logTag, ":", env, "must be a valid duration, using the default value")
+		return defaultTTL
+	}
+	return ttl
+}
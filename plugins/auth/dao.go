@@ -17,6 +17,7 @@ import (
 type elasticsearch struct {
 	userIndex, userType             string
 	permissionIndex, permissionType string
+	sessionIndex, sessionType       string
 }
 
 type publicKey struct {
@@ -24,12 +25,13 @@ type publicKey struct {
 	RoleKey   string `json:"role_key"`
 }
 
-func initPlugin(userIndex, permissionIndex string) (*elasticsearch, error) {
+func initPlugin(userIndex, permissionIndex, sessionIndex string) (*elasticsearch, error) {
 	// auth only has to establish a connection to es, users, permissions
 	// plugin handles the creation of their respective meta indices
 	es := &elasticsearch{
 		userIndex, "_doc",
 		permissionIndex, "_doc",
+		sessionIndex, "_doc",
 	}
 
 	return es, nil
@@ -119,6 +121,17 @@ func (es *elasticsearch) putUser(ctx context.Context, u user.User) (bool, error)
 	return true, nil
 }
 
+func (es *elasticsearch) patchUser(ctx context.Context, username string, patch map[string]interface{}) error {
+	_, err := util.GetClient7().Update().
+		Refresh("wait_for").
+		Index(es.userIndex).
+		Type(es.userType).
+		Id(username).
+		Doc(patch).
+		Do(ctx)
+	return err
+}
+
 func (es *elasticsearch) getUser(ctx context.Context, username string) (*user.User, error) {
 	data, err := es.getRawUser(ctx, username)
 	if err != nil {
@@ -221,3 +234,48 @@ func (es *elasticsearch) getRawRolePermission(ctx context.Context, role string)
 		return es.getRawRolePermissionEs7(ctx, role)
 	}
 }
+
+func (es *elasticsearch) putSession(ctx context.Context, id string, s session) (bool, error) {
+	_, err := util.GetClient7().Index().
+		Refresh("wait_for").
+		Index(es.sessionIndex).
+		Id(id).
+		BodyJson(s).
+		Do(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (es *elasticsearch) getSession(ctx context.Context, id string) (*session, error) {
+	switch util.GetVersion() {
+	case 6:
+		return es.getSessionEs6(ctx, id)
+	default:
+		return es.getSessionEs7(ctx, id)
+	}
+}
+
+func (es *elasticsearch) deleteSession(ctx context.Context, id string) (bool, error) {
+	_, err := util.GetClient7().Delete().
+		Refresh("wait_for").
+		Index(es.sessionIndex).
+		Id(id).
+		Do(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (es *elasticsearch) deleteExpiredSessions(ctx context.Context) error {
+	switch util.GetVersion() {
+	case 6:
+		return es.deleteExpiredSessionsEs6(ctx)
+	default:
+		return es.deleteExpiredSessionsEs7(ctx)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -99,3 +100,30 @@ func (es *elasticsearch) getRawRolePermissionEs6(ctx context.Context, role strin
 	}
 	return nil, nil
 }
+
+func (es *elasticsearch) getSessionEs6(ctx context.Context, id string) (*session, error) {
+	response, err := util.GetClient6().Get().
+		Index(es.sessionIndex).
+		Type(es.sessionType).
+		Id(id).
+		FetchSource(true).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var s session
+	err = json.Unmarshal(*response.Source, &s)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (es *elasticsearch) deleteExpiredSessionsEs6(ctx context.Context) error {
+	_, err := util.GetClient6().DeleteByQuery(es.sessionIndex).
+		Type(es.sessionType).
+		Query(es6.NewRangeQuery("expires_at").Lt(time.Now().Format(time.RFC3339))).
+		Do(ctx)
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -83,7 +84,6 @@ func (es *elasticsearch) getCredentialEs7(ctx context.Context, username string)
 func (es *elasticsearch) getRawRolePermissionEs7(ctx context.Context, role string) ([]byte, error) {
 	resp, err := util.GetClient7().Search().
 		Index(es.permissionIndex).
-		Type(es.permissionType).
 		Query(es7.NewTermQuery("role.keyword", role)).
 		Size(1).
 		FetchSource(true).
@@ -99,3 +99,28 @@ func (es *elasticsearch) getRawRolePermissionEs7(ctx context.Context, role strin
 	}
 	return nil, nil
 }
+
+func (es *elasticsearch) getSessionEs7(ctx context.Context, id string) (*session, error) {
+	response, err := util.GetClient7().Get().
+		Index(es.sessionIndex).
+		Id(id).
+		FetchSource(true).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var s session
+	err = json.Unmarshal(response.Source, &s)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (es *elasticsearch) deleteExpiredSessionsEs7(ctx context.Context) error {
+	_, err := util.GetClient7().DeleteByQuery(es.sessionIndex).
+		Query(es7.NewRangeQuery("expires_at").Lt(time.Now().Format(time.RFC3339))).
+		Do(ctx)
+	return err
+}
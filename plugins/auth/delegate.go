@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/fieldmask"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/model/querypolicy"
+)
+
+const (
+	envAuthDelegateURL      = "AUTH_DELEGATE_URL"
+	envAuthDelegateCacheTTL = "AUTH_DELEGATE_CACHE_TTL"
+	defaultDelegateCacheTTL = 5 * time.Minute
+	delegateTimeout         = 10 * time.Second
+)
+
+// delegatePermission is the response expected from the auth delegate endpoint.
+type delegatePermission struct {
+	Categories  []category.Category `json:"categories"`
+	ACLs        []acl.ACL           `json:"acls"`
+	Ops         []op.Operation      `json:"ops"`
+	Indices     []string            `json:"indices"`
+	Limits      *permission.Limits  `json:"limits"`
+	Includes    []string            `json:"include_fields"`
+	Excludes    []string            `json:"exclude_fields"`
+	Filter      *permission.Filter  `json:"filter"`
+	FieldMasks  []fieldmask.Rule    `json:"field_masks"`
+	QueryPolicy *querypolicy.Policy `json:"query_policy"`
+}
+
+// httpAuthenticator forwards the basic auth credentials to an external endpoint that
+// responds with the permission of the credentials. The resolved permissions are cached
+// against the credentials for a ttl.
+type httpAuthenticator struct {
+	url    string
+	client *http.Client
+	cache  *verifiedCache
+}
+
+func newHTTPAuthenticator(url string, ttl time.Duration) *httpAuthenticator {
+	return &httpAuthenticator{
+		url:    url,
+		client: &http.Client{Timeout: delegateTimeout},
+		cache:  newVerifiedCache(ttl),
+	}
+}
+
+// delegateAuthenticatorFromEnv returns the auth delegate configured by the env, if any.
+func delegateAuthenticatorFromEnv() (*httpAuthenticator, bool) {
+	url := os.Getenv(envAuthDelegateURL)
+	if url == "" {
+		return nil, false
+	}
+	ttl := getCacheTTL(envAuthDelegateCacheTTL, defaultDelegateCacheTTL)
+	return newHTTPAuthenticator(url, ttl), true
+}
+
+// Name is the implementation of Authenticator interface.
+func (h *httpAuthenticator) Name() string {
+	return "auth delegate"
+}
+
+// Authenticate is the implementation of Authenticator interface.
+func (h *httpAuthenticator) Authenticate(ctx context.Context, username, password string) (credential.AuthCredential, error) {
+	if c, ok := h.cache.get(username, password); ok {
+		return c, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(username, password)
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("auth delegate responded with status code %d", res.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	var dp delegatePermission
+	err = json.Unmarshal(body, &dp)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse the auth delegate response: %v", err)
+	}
+	p, err := dp.toPermission(username)
+	if err != nil {
+		return nil, err
+	}
+
+	h.cache.set(username, password, p)
+	return p, nil
+}
+
+// toPermission builds the permission with the defaults for the fields that
+// are missing in the delegate response.
+func (dp delegatePermission) toPermission(username string) (*permission.Permission, error) {
+	var opts []permission.Options
+	if dp.Categories != nil {
+		opts = append(opts, permission.SetCategories(dp.Categories))
+	}
+	if dp.ACLs != nil {
+		opts = append(opts, permission.SetACLs(dp.ACLs))
+	}
+	if dp.Ops != nil {
+		opts = append(opts, permission.SetOps(dp.Ops))
+	}
+	if dp.Indices != nil {
+		opts = append(opts, permission.SetIndices(dp.Indices))
+	}
+	if dp.Limits != nil {
+		opts = append(opts, permission.SetLimits(dp.Limits, false))
+	}
+	if dp.Filter != nil {
+		opts = append(opts, permission.SetFilter(dp.Filter))
+	}
+	if dp.FieldMasks != nil {
+		opts = append(opts, permission.SetFieldMasks(dp.FieldMasks))
+	}
+	if dp.QueryPolicy != nil {
+		opts = append(opts, permission.SetQueryPolicy(dp.QueryPolicy))
+	}
+	opts = append(opts, permission.SetIncludes(dp.Includes), permission.SetExcludes(dp.Excludes))
+
+	p, err := permission.New(username, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid permission returned by the auth delegate: %v", err)
+	}
+	// the generated password is never shared, the delegate is the only
+	// one who can verify the credentials
+	p.Username = username
+	return p, nil
+}
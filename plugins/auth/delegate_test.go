@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+)
+
+func newDelegateServer(hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(hits, 1)
+		username, password, ok := req.BasicAuth()
+		if !ok || username != "foo" || password != "bar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"categories":["docs","search"],"indices":["books"],"limits":{"ip_limit":10}}`))
+	}))
+}
+
+func TestHTTPAuthenticator(t *testing.T) {
+	Convey("should resolve the permission from the delegate", t, func() {
+		var hits int32
+		server := newDelegateServer(&hits)
+		defer server.Close()
+
+		authenticator := newHTTPAuthenticator(server.URL, time.Minute)
+		c, err := authenticator.Authenticate(context.Background(), "foo", "bar")
+		So(err, ShouldBeNil)
+		p, ok := c.(*permission.Permission)
+		So(ok, ShouldBeTrue)
+		So(p.Username, ShouldEqual, "foo")
+		So(p.Indices, ShouldResemble, []string{"books"})
+		So(p.HasCategory(category.Docs), ShouldBeTrue)
+		So(p.HasCategory(category.Analytics), ShouldBeFalse)
+		So(p.Limits.IPLimit, ShouldEqual, 10)
+
+		Convey("should cache the permission for the same credentials", func() {
+			_, err := authenticator.Authenticate(context.Background(), "foo", "bar")
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&hits), ShouldEqual, 1)
+		})
+	})
+	Convey("should not recognize the rejected credentials", t, func() {
+		var hits int32
+		server := newDelegateServer(&hits)
+		defer server.Close()
+
+		authenticator := newHTTPAuthenticator(server.URL, time.Minute)
+		_, err := authenticator.Authenticate(context.Background(), "foo", "bar")
+		So(err, ShouldBeNil)
+		c, err := authenticator.Authenticate(context.Background(), "foo", "baz")
+		So(err, ShouldBeNil)
+		So(c, ShouldBeNil)
+		So(atomic.LoadInt32(&hits), ShouldEqual, 2)
+	})
+	Convey("should expire the cached permission", t, func() {
+		var hits int32
+		server := newDelegateServer(&hits)
+		defer server.Close()
+
+		authenticator := newHTTPAuthenticator(server.URL, 0)
+		authenticator.Authenticate(context.Background(), "foo", "bar")
+		authenticator.Authenticate(context.Background(), "foo", "bar")
+		So(atomic.LoadInt32(&hits), ShouldEqual, 2)
+	})
+	Convey("should fail when the delegate errors", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		authenticator := newHTTPAuthenticator(server.URL, time.Minute)
+		c, err := authenticator.Authenticate(context.Background(), "foo", "bar")
+		So(err, ShouldNotBeNil)
+		So(c, ShouldBeNil)
+	})
+}
@@ -5,16 +5,21 @@ import (
 	"crypto/rsa"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/appbaseio/reactivesearch-api/model/user"
 	"github.com/appbaseio/reactivesearch-api/util"
+	"github.com/gorilla/mux"
 )
 
 func (a *Auth) savePublicKey(ctx context.Context, indexName string, record publicKey) (interface{}, error) {
@@ -160,3 +165,313 @@ func getJWTPublickKey(record publicKey) (*rsa.PublicKey, error) {
 	}
 	return nil, errors.New("public key is missing in the request body")
 }
+
+type loginBody struct {
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	TOTPCode     string `json:"totp_code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (a *Auth) login() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		reqBody, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "Can't read request body", http.StatusBadRequest)
+			return
+		}
+		defer req.Body.Close()
+
+		var body loginBody
+		err = json.Unmarshal(reqBody, &body)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "Can't parse request body", http.StatusBadRequest)
+			return
+		}
+		if body.Username == "" || body.Password == "" {
+			util.WriteBackError(w, `"username" and "password" are required`, http.StatusBadRequest)
+			return
+		}
+
+		reqUser, err := a.es.getUser(req.Context(), body.Username)
+		if err != nil || bcrypt.CompareHashAndPassword([]byte(reqUser.Password), []byte(body.Password)) != nil {
+			// the users provisioned by the authenticators can't be verified locally
+			c, ok := a.authenticate(req.Context(), body.Username, body.Password)
+			if !ok {
+				util.WriteBackError(w, "invalid username or password", http.StatusUnauthorized)
+				return
+			}
+			if reqUser, ok = c.(*user.User); !ok {
+				util.WriteBackError(w, "only the users can login", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if reqUser.HasMFA() {
+			ok, err := a.verifyMFA(req.Context(), reqUser, body.TOTPCode, body.RecoveryCode)
+			if err != nil {
+				log.Errorln(logTag, ": error while verifying the mfa code", err)
+			}
+			if !ok {
+				util.WriteBackError(w, `a valid "totp_code" or "recovery_code" is required`, http.StatusUnauthorized)
+				return
+			}
+		} else if isMFARequiredForPrivileged() && reqUser.IsPrivileged() {
+			util.WriteBackError(w, "mfa enrollment is required to login", http.StatusForbidden)
+			return
+		}
+
+		token, s, err := a.newSession(req.Context(), reqUser.Username)
+		if err != nil {
+			log.Errorln(logTag, ": error while creating session", err)
+			util.WriteBackError(w, "error occurred while creating the session", http.StatusInternalServerError)
+			return
+		}
+		a.setSessionCookie(w, token, s.ExpiresAt)
+
+		response, err := json.Marshal(map[string]interface{}{
+			"username":   s.Username,
+			"csrf_token": s.CSRFToken,
+			"expires_at": s.ExpiresAt,
+		})
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, response, http.StatusOK)
+	}
+}
+
+func (a *Auth) logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		cookie, err := req.Cookie(sessionCookieName)
+		if err != nil || cookie.Value == "" {
+			util.WriteBackError(w, "session not found", http.StatusUnauthorized)
+			return
+		}
+		s, ok := a.getSession(req.Context(), cookie.Value)
+		if !ok {
+			clearSessionCookie(w)
+			util.WriteBackError(w, "session not found", http.StatusUnauthorized)
+			return
+		}
+		if !validCSRFToken(req, s) {
+			util.WriteBackError(w, "invalid csrf token", http.StatusForbidden)
+			return
+		}
+		err = a.deleteSession(req.Context(), cookie.Value)
+		if err != nil {
+			log.Errorln(logTag, ": error while deleting session", err)
+			util.WriteBackError(w, "error occurred while deleting the session", http.StatusInternalServerError)
+			return
+		}
+		clearSessionCookie(w)
+		util.WriteBackMessage(w, "Logged out successfully.", http.StatusOK)
+	}
+}
+
+type totpBody struct {
+	TOTPCode     string `json:"totp_code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// requestUser returns the latest state of the user making the request, mfa routes
+// can't rely on the cached user as it is modified by these routes.
+func (a *Auth) requestUser(w http.ResponseWriter, req *http.Request) (*user.User, bool) {
+	reqUser, err := user.FromContext(req.Context())
+	if err != nil {
+		util.WriteBackError(w, "mfa is only available for the users", http.StatusBadRequest)
+		return nil, false
+	}
+	u, err := a.es.getUser(req.Context(), reqUser.Username)
+	if err != nil {
+		log.Errorln(logTag, ":", err)
+		util.WriteBackError(w, "error occurred while fetching the user", http.StatusInternalServerError)
+		return nil, false
+	}
+	return u, true
+}
+
+func readTOTPBody(w http.ResponseWriter, req *http.Request) (*totpBody, bool) {
+	reqBody, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.Errorln(logTag, ":", err)
+		util.WriteBackError(w, "Can't read request body", http.StatusBadRequest)
+		return nil, false
+	}
+	defer req.Body.Close()
+
+	var body totpBody
+	err = json.Unmarshal(reqBody, &body)
+	if err != nil {
+		log.Errorln(logTag, ":", err)
+		util.WriteBackError(w, "Can't parse request body", http.StatusBadRequest)
+		return nil, false
+	}
+	return &body, true
+}
+
+func (a *Auth) enrollTOTP() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		reqUser, ok := a.requestUser(w, req)
+		if !ok {
+			return
+		}
+		if reqUser.HasMFA() {
+			util.WriteBackError(w, "totp is already enabled for the user", http.StatusConflict)
+			return
+		}
+
+		secret, err := newTOTPSecret()
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error occurred while generating the totp secret", http.StatusInternalServerError)
+			return
+		}
+		// the enrollment remains disabled until a code generated with the secret is verified
+		err = a.es.patchUser(req.Context(), reqUser.Username, map[string]interface{}{
+			"totp": user.TOTP{Secret: secret},
+		})
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error occurred while saving the totp secret", http.StatusInternalServerError)
+			return
+		}
+		ClearLocalUser(reqUser.Username)
+
+		response, err := json.Marshal(map[string]interface{}{
+			"secret":           secret,
+			"provisioning_uri": totpProvisioningURI(getTOTPIssuer(), reqUser.Username, secret),
+		})
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, response, http.StatusOK)
+	}
+}
+
+func (a *Auth) verifyTOTP() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		reqUser, ok := a.requestUser(w, req)
+		if !ok {
+			return
+		}
+		body, ok := readTOTPBody(w, req)
+		if !ok {
+			return
+		}
+		if reqUser.TOTP == nil || reqUser.TOTP.Secret == "" {
+			util.WriteBackError(w, "totp enrollment not found, enroll the user first", http.StatusBadRequest)
+			return
+		}
+		if reqUser.TOTP.Enabled {
+			util.WriteBackError(w, "totp is already enabled for the user", http.StatusConflict)
+			return
+		}
+		step, ok := validateTOTP(reqUser.TOTP.Secret, body.TOTPCode, time.Now(), reqUser.TOTP.LastStep)
+		if !ok {
+			util.WriteBackError(w, `invalid "totp_code"`, http.StatusUnauthorized)
+			return
+		}
+
+		codes, err := newRecoveryCodes()
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error occurred while generating the recovery codes", http.StatusInternalServerError)
+			return
+		}
+		hashedCodes := make([]string, len(codes))
+		for i, code := range codes {
+			hashedCode, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+			if err != nil {
+				log.Errorln(logTag, ":", err)
+				util.WriteBackError(w, "error occurred while generating the recovery codes", http.StatusInternalServerError)
+				return
+			}
+			hashedCodes[i] = string(hashedCode)
+		}
+		err = a.es.patchUser(req.Context(), reqUser.Username, map[string]interface{}{
+			"totp": user.TOTP{
+				Secret:        reqUser.TOTP.Secret,
+				Enabled:       true,
+				RecoveryCodes: hashedCodes,
+				LastStep:      step,
+			},
+		})
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error occurred while enabling totp", http.StatusInternalServerError)
+			return
+		}
+		ClearLocalUser(reqUser.Username)
+
+		// recovery codes are only returned once, only their hashes are stored
+		response, err := json.Marshal(map[string]interface{}{
+			"recovery_codes": codes,
+		})
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, response, http.StatusOK)
+	}
+}
+
+func (a *Auth) disableTOTP() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		reqUser, ok := a.requestUser(w, req)
+		if !ok {
+			return
+		}
+		body, ok := readTOTPBody(w, req)
+		if !ok {
+			return
+		}
+		if reqUser.HasMFA() {
+			ok, err := a.verifyMFA(req.Context(), reqUser, body.TOTPCode, body.RecoveryCode)
+			if err != nil {
+				log.Errorln(logTag, ": error while verifying the mfa code", err)
+			}
+			if !ok {
+				util.WriteBackError(w, `a valid "totp_code" or "recovery_code" is required`, http.StatusUnauthorized)
+				return
+			}
+		}
+		a.resetTOTP(w, req, reqUser.Username)
+	}
+}
+
+func (a *Auth) disableTOTPWithUsername() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		reqUser, err := user.FromContext(req.Context())
+		if err != nil || !reqUser.HasAction(user.UserManagement) {
+			util.WriteBackError(w, "only the users with user-management action can reset the mfa of other users", http.StatusForbidden)
+			return
+		}
+		username := mux.Vars(req)["username"]
+		if _, err := a.es.getUser(req.Context(), username); err != nil {
+			util.WriteBackError(w, fmt.Sprintf(`user with "username"="%s" not found`, username), http.StatusNotFound)
+			return
+		}
+		a.resetTOTP(w, req, username)
+	}
+}
+
+func (a *Auth) resetTOTP(w http.ResponseWriter, req *http.Request, username string) {
+	err := a.es.patchUser(req.Context(), username, map[string]interface{}{
+		"totp": nil,
+	})
+	if err != nil {
+		log.Errorln(logTag, ":", err)
+		util.WriteBackError(w, "error occurred while disabling totp", http.StatusInternalServerError)
+		return
+	}
+	ClearLocalUser(username)
+	util.WriteBackMessage(w, "TOTP disabled successfully.", http.StatusOK)
+}
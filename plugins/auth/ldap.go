@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/user"
+)
+
+const (
+	envLDAPURL             = "LDAP_URL"
+	envLDAPBindDN          = "LDAP_BIND_DN"
+	envLDAPBindPassword    = "LDAP_BIND_PASSWORD"
+	envLDAPUserBaseDN      = "LDAP_USER_BASE_DN"
+	envLDAPUserFilter      = "LDAP_USER_FILTER"
+	defaultLDAPUserFilter  = "(uid=%s)"
+	envLDAPGroupBaseDN     = "LDAP_GROUP_BASE_DN"
+	envLDAPGroupFilter     = "LDAP_GROUP_FILTER"
+	defaultLDAPGroupFilter = "(member=%s)"
+	envLDAPGroupMapping    = "LDAP_GROUP_MAPPING"
+	envLDAPCacheTTL        = "LDAP_CACHE_TTL"
+	defaultLDAPCacheTTL    = 5 * time.Minute
+	// ldapProvider identifies the shadow users provisioned by the ldap authenticator
+	ldapProvider = "ldap"
+)
+
+// ldapConn defines the ldap operations used by the authenticator, it is
+// implemented by *ldap.Conn.
+type ldapConn interface {
+	Bind(username, password string) error
+	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close()
+}
+
+// shadowUserStore persists the users provisioned for the directory accounts.
+type shadowUserStore interface {
+	getUser(ctx context.Context, username string) (*user.User, error)
+	putUser(ctx context.Context, u user.User) (bool, error)
+}
+
+// ldapGroup defines the access granted to the members of a directory group.
+type ldapGroup struct {
+	IsAdmin bool              `json:"is_admin"`
+	Actions []user.UserAction `json:"actions"`
+	Indices []string          `json:"indices"`
+}
+
+type ldapConfig struct {
+	bindDN       string
+	bindPassword string
+	userBaseDN   string
+	userFilter   string
+	groupBaseDN  string
+	groupFilter  string
+	// groups maps the common names of the directory groups to their access
+	groups map[string]ldapGroup
+}
+
+// ldapAuthenticator binds with the basic auth credentials against a directory and
+// provisions a shadow user with the access mapped from the groups of the account.
+type ldapAuthenticator struct {
+	config ldapConfig
+	dial   func() (ldapConn, error)
+	store  shadowUserStore
+	cache  *verifiedCache
+}
+
+// ldapAuthenticatorFromEnv returns the ldap authenticator configured by the env, if any.
+func ldapAuthenticatorFromEnv(store shadowUserStore) (*ldapAuthenticator, error) {
+	url := os.Getenv(envLDAPURL)
+	if url == "" {
+		return nil, nil
+	}
+	config := ldapConfig{
+		bindDN:       os.Getenv(envLDAPBindDN),
+		bindPassword: os.Getenv(envLDAPBindPassword),
+		userBaseDN:   os.Getenv(envLDAPUserBaseDN),
+		userFilter:   os.Getenv(envLDAPUserFilter),
+		groupBaseDN:  os.Getenv(envLDAPGroupBaseDN),
+		groupFilter:  os.Getenv(envLDAPGroupFilter),
+	}
+	if config.userBaseDN == "" {
+		return nil, fmt.Errorf("%s is required to use the ldap authentication", envLDAPUserBaseDN)
+	}
+	if config.userFilter == "" {
+		config.userFilter = defaultLDAPUserFilter
+	}
+	if config.groupBaseDN == "" {
+		config.groupBaseDN = config.userBaseDN
+	}
+	if config.groupFilter == "" {
+		config.groupFilter = defaultLDAPGroupFilter
+	}
+	err := json.Unmarshal([]byte(os.Getenv(envLDAPGroupMapping)), &config.groups)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a valid JSON: %v", envLDAPGroupMapping, err)
+	}
+
+	dial := func() (ldapConn, error) {
+		return ldap.DialURL(url)
+	}
+	return newLDAPAuthenticator(config, dial, store, getCacheTTL(envLDAPCacheTTL, defaultLDAPCacheTTL)), nil
+}
+
+func newLDAPAuthenticator(config ldapConfig, dial func() (ldapConn, error), store shadowUserStore, ttl time.Duration) *ldapAuthenticator {
+	return &ldapAuthenticator{
+		config: config,
+		dial:   dial,
+		store:  store,
+		cache:  newVerifiedCache(ttl),
+	}
+}
+
+// Name is the implementation of Authenticator interface.
+func (l *ldapAuthenticator) Name() string {
+	return ldapProvider
+}
+
+// Authenticate is the implementation of Authenticator interface.
+func (l *ldapAuthenticator) Authenticate(ctx context.Context, username, password string) (credential.AuthCredential, error) {
+	// an empty password results in an unauthenticated bind which always succeeds
+	if username == "" || password == "" {
+		return nil, nil
+	}
+	if c, ok := l.cache.get(username, password); ok {
+		return c, nil
+	}
+
+	groups, ok, err := l.verify(username, password)
+	if err != nil || !ok {
+		return nil, err
+	}
+	access, ok := l.config.access(groups)
+	if !ok {
+		log.Warnln(logTag, ": ldap account", username, "is not a member of any mapped group")
+		return nil, nil
+	}
+	u, ok, err := l.provision(ctx, username, access)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	l.cache.set(username, password, u)
+	return u, nil
+}
+
+// verify binds with the credentials and returns the common names of the groups of the account.
+func (l *ldapAuthenticator) verify(username, password string) ([]string, bool, error) {
+	conn, err := l.dial()
+	if err != nil {
+		return nil, false, err
+	}
+	defer conn.Close()
+
+	// lookup the dn of the account, anonymously if the bind dn isn't configured
+	if l.config.bindDN != "" {
+		if err := conn.Bind(l.config.bindDN, l.config.bindPassword); err != nil {
+			return nil, false, fmt.Errorf("error while binding with %s: %v", envLDAPBindDN, err)
+		}
+	}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.config.userBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(l.config.userFilter, ldap.EscapeFilter(username)),
+		[]string{"dn"},
+		nil,
+	))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(result.Entries) != 1 {
+		return nil, false, nil
+	}
+	userDN := result.Entries[0].DN
+
+	err = conn.Bind(userDN, password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	result, err = conn.Search(ldap.NewSearchRequest(
+		l.config.groupBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(l.config.groupFilter, ldap.EscapeFilter(userDN)),
+		[]string{"cn"},
+		nil,
+	))
+	if err != nil {
+		return nil, false, err
+	}
+	var groups []string
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.GetAttributeValue("cn"))
+	}
+	return groups, true, nil
+}
+
+// access merges the access of the mapped groups of an account.
+func (c ldapConfig) access(groups []string) (ldapGroup, bool) {
+	var access ldapGroup
+	found := false
+	actions := make(map[user.UserAction]bool)
+	indices := make(map[string]bool)
+	for _, name := range groups {
+		group, ok := c.groups[name]
+		if !ok {
+			continue
+		}
+		found = true
+		access.IsAdmin = access.IsAdmin || group.IsAdmin
+		for _, action := range group.Actions {
+			if !actions[action] {
+				actions[action] = true
+				access.Actions = append(access.Actions, action)
+			}
+		}
+		for _, index := range group.Indices {
+			if !indices[index] {
+				indices[index] = true
+				access.Indices = append(access.Indices, index)
+			}
+		}
+	}
+	sort.Slice(access.Actions, func(i, j int) bool { return access.Actions[i] < access.Actions[j] })
+	sort.Strings(access.Indices)
+	return access, found
+}
+
+// provision creates or updates the shadow user of the account. Local users with the
+// same username are never overwritten, in which case it returns false.
+func (l *ldapAuthenticator) provision(ctx context.Context, username string, access ldapGroup) (*user.User, bool, error) {
+	var u *user.User
+	var err error
+	if access.IsAdmin {
+		u, err = user.NewAdmin(username, "")
+	} else {
+		if access.Actions == nil {
+			access.Actions = []user.UserAction{}
+		}
+		if access.Indices == nil {
+			access.Indices = []string{}
+		}
+		u, err = user.New(username, "",
+			user.SetAllowedActions(access.Actions),
+			user.SetIndices(access.Indices),
+		)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	// the empty password hash can never be matched, shadow users
+	// are only verified by the directory
+	u.PasswordHashType = "bcrypt"
+	u.Provider = ldapProvider
+
+	existing, err := l.store.getUser(ctx, username)
+	if err == nil && existing != nil {
+		if existing.Provider != ldapProvider {
+			log.Warnln(logTag, ": ldap account", username, "conflicts with a local user, skipping...")
+			return nil, false, nil
+		}
+		// preserve the state owned by the shadow user
+		u.CreatedAt = existing.CreatedAt
+		u.Email = existing.Email
+		u.TOTP = existing.TOTP
+		if existing.Sources != nil {
+			u.Sources = existing.Sources
+		}
+		if shadowUserEqual(existing, u) {
+			return existing, true, nil
+		}
+	}
+
+	if _, err := l.store.putUser(ctx, *u); err != nil {
+		return nil, false, err
+	}
+	ClearLocalUser(username)
+	return u, true, nil
+}
+
+func shadowUserEqual(a, b *user.User) bool {
+	if a.IsAdmin == nil || b.IsAdmin == nil {
+		return false
+	}
+	return *a.IsAdmin == *b.IsAdmin &&
+		reflect.DeepEqual(a.AllowedActions, b.AllowedActions) &&
+		reflect.DeepEqual(a.Indices, b.Indices)
+}
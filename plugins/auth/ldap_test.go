@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/appbaseio/reactivesearch-api/model/user"
+)
+
+// fakeDirectory is an in-process stand-in of an ldap server, it supports
+// the equality filters used by the authenticator.
+type fakeDirectory struct {
+	passwords map[string]string
+	entries   []*ldap.Entry
+}
+
+type fakeLDAPConn struct {
+	directory *fakeDirectory
+}
+
+var equalityFilter = regexp.MustCompile(`^\((\w+)=(.*)\)$`)
+
+func (c *fakeLDAPConn) Bind(dn, password string) error {
+	if expected, ok := c.directory.passwords[dn]; ok && expected == password {
+		return nil
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func (c *fakeLDAPConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	matches := equalityFilter.FindStringSubmatch(req.Filter)
+	if matches == nil {
+		return nil, ldap.NewError(ldap.LDAPResultFilterError, errors.New("unsupported filter"))
+	}
+	result := &ldap.SearchResult{}
+	for _, entry := range c.directory.entries {
+		if regexp.MustCompile(regexp.QuoteMeta(req.BaseDN)+"$").MatchString(entry.DN) &&
+			contains(entry.GetAttributeValues(matches[1]), matches[2]) {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeLDAPConn) Close() {}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeUserStore struct {
+	users map[string]user.User
+	puts  int
+}
+
+func (s *fakeUserStore) getUser(ctx context.Context, username string) (*user.User, error) {
+	u, ok := s.users[username]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &u, nil
+}
+
+func (s *fakeUserStore) putUser(ctx context.Context, u user.User) (bool, error) {
+	s.puts++
+	s.users[u.Username] = u
+	return true, nil
+}
+
+func newTestLDAPAuthenticator(store shadowUserStore) *ldapAuthenticator {
+	directory := &fakeDirectory{
+		passwords: map[string]string{
+			"uid=alice,ou=people,dc=example,dc=com": "secret",
+			"uid=bob,ou=people,dc=example,dc=com":   "secret",
+			"uid=carol,ou=people,dc=example,dc=com": "secret",
+		},
+		entries: []*ldap.Entry{
+			ldap.NewEntry("uid=alice,ou=people,dc=example,dc=com", map[string][]string{"uid": {"alice"}}),
+			ldap.NewEntry("uid=bob,ou=people,dc=example,dc=com", map[string][]string{"uid": {"bob"}}),
+			ldap.NewEntry("uid=carol,ou=people,dc=example,dc=com", map[string][]string{"uid": {"carol"}}),
+			ldap.NewEntry("cn=ops,ou=groups,dc=example,dc=com", map[string][]string{
+				"cn":     {"ops"},
+				"member": {"uid=alice,ou=people,dc=example,dc=com"},
+			}),
+			ldap.NewEntry("cn=devs,ou=groups,dc=example,dc=com", map[string][]string{
+				"cn":     {"devs"},
+				"member": {"uid=alice,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"},
+			}),
+		},
+	}
+	config := ldapConfig{
+		userBaseDN:  "ou=people,dc=example,dc=com",
+		userFilter:  defaultLDAPUserFilter,
+		groupBaseDN: "ou=groups,dc=example,dc=com",
+		groupFilter: defaultLDAPGroupFilter,
+		groups: map[string]ldapGroup{
+			"ops":  {Actions: []user.UserAction{user.Analytics}, Indices: []string{"logs-*"}},
+			"devs": {Actions: []user.UserAction{user.Develop}, Indices: []string{"books"}},
+		},
+	}
+	dial := func() (ldapConn, error) {
+		return &fakeLDAPConn{directory: directory}, nil
+	}
+	return newLDAPAuthenticator(config, dial, store, time.Minute)
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	Convey("should provision the shadow user from the groups", t, func() {
+		store := &fakeUserStore{users: map[string]user.User{}}
+		authenticator := newTestLDAPAuthenticator(store)
+
+		c, err := authenticator.Authenticate(context.Background(), "alice", "secret")
+		So(err, ShouldBeNil)
+		u, ok := c.(*user.User)
+		So(ok, ShouldBeTrue)
+		So(*u.IsAdmin, ShouldBeFalse)
+		So(*u.AllowedActions, ShouldResemble, []user.UserAction{user.Develop, user.Analytics})
+		So(u.Indices, ShouldResemble, []string{"books", "logs-*"})
+		So(u.Provider, ShouldEqual, ldapProvider)
+		So(store.users["alice"].Provider, ShouldEqual, ldapProvider)
+		So(store.puts, ShouldEqual, 1)
+
+		Convey("should not update the unchanged shadow user", func() {
+			authenticator.cache = newVerifiedCache(0)
+			_, err := authenticator.Authenticate(context.Background(), "alice", "secret")
+			So(err, ShouldBeNil)
+			So(store.puts, ShouldEqual, 1)
+		})
+	})
+	Convey("should reject the invalid credentials", t, func() {
+		store := &fakeUserStore{users: map[string]user.User{}}
+		authenticator := newTestLDAPAuthenticator(store)
+
+		for _, password := range []string{"wrong", ""} {
+			c, err := authenticator.Authenticate(context.Background(), "alice", password)
+			So(err, ShouldBeNil)
+			So(c, ShouldBeNil)
+		}
+		c, err := authenticator.Authenticate(context.Background(), "mallory", "secret")
+		So(err, ShouldBeNil)
+		So(c, ShouldBeNil)
+		So(store.puts, ShouldEqual, 0)
+	})
+	Convey("should reject the accounts without mapped groups", t, func() {
+		store := &fakeUserStore{users: map[string]user.User{}}
+		authenticator := newTestLDAPAuthenticator(store)
+
+		c, err := authenticator.Authenticate(context.Background(), "carol", "secret")
+		So(err, ShouldBeNil)
+		So(c, ShouldBeNil)
+	})
+	Convey("should not overwrite the local users", t, func() {
+		local, _ := user.New("bob", "hash")
+		store := &fakeUserStore{users: map[string]user.User{"bob": *local}}
+		authenticator := newTestLDAPAuthenticator(store)
+
+		c, err := authenticator.Authenticate(context.Background(), "bob", "secret")
+		So(err, ShouldBeNil)
+		So(c, ShouldBeNil)
+		So(store.users["bob"].Password, ShouldEqual, "hash")
+	})
+}
@@ -101,10 +101,24 @@ func (a *Auth) basicAuth(h http.HandlerFunc) http.HandlerFunc {
 			}
 			return a.jwtRsaPublicKey, nil
 		})
-		if !hasBasicAuth && err != nil {
+		// fallback to the session cookie or the verified client certificate
+		// when neither basic auth nor jwt is present
+		hasIdentity := false
+		if !hasBasicAuth && err == request.ErrNoTokenInRequest {
+			if s, ok := a.sessionFromRequest(req); ok {
+				if !validCSRFToken(req, s) {
+					telemetry.WriteBackErrorWithTelemetry(req, w, "invalid csrf token", http.StatusForbidden)
+					return
+				}
+				username, hasIdentity = s.Username, true
+			} else {
+				username, hasIdentity = clientCertUsername(req)
+			}
+		}
+		if !hasBasicAuth && !hasIdentity && err != nil {
 			var msg string
 			if err == request.ErrNoTokenInRequest {
-				msg = "Basic Auth or JWT is required"
+				msg = "Basic Auth, JWT or session is required"
 			} else {
 				msg = fmt.Sprintf("Unable to parse JWT: %v", err)
 			}
@@ -114,7 +128,7 @@ func (a *Auth) basicAuth(h http.HandlerFunc) http.HandlerFunc {
 		}
 
 		role := ""
-		if !hasBasicAuth {
+		if !hasBasicAuth && !hasIdentity {
 			if claims, ok := jwtToken.Claims.(jwt.MapClaims); ok && jwtToken.Valid {
 				if a.jwtRoleKey != "" && claims[a.jwtRoleKey] != nil {
 					role = claims[a.jwtRoleKey].(string)
@@ -133,6 +147,8 @@ func (a *Auth) basicAuth(h http.HandlerFunc) http.HandlerFunc {
 		}
 		// we don't know if the credentials provided here are of a 'user' or a 'permission'
 		var obj credential.AuthCredential
+		// credentials resolved by the authenticators are verified by them
+		var isVerified bool
 		if role != "" {
 			obj, err = a.es.getRolePermission(ctx, role)
 			if err != nil || obj == nil {
@@ -144,6 +160,14 @@ func (a *Auth) basicAuth(h http.HandlerFunc) http.HandlerFunc {
 			}
 		} else {
 			obj, err = a.getCredential(ctx, username)
+			// the authenticators are only tried for the credentials unknown locally,
+			// to avoid calling the identity providers on every request, and for the
+			// users provisioned by them which can't be verified locally
+			if hasBasicAuth && (err != nil || obj == nil || isProvisioned(obj)) {
+				if c, ok := a.authenticate(ctx, username, password); ok {
+					obj, err, isVerified = c, nil, true
+				}
+			}
 			if err != nil || obj == nil {
 				msg := fmt.Sprintf("No API credentials match with provided username: %s", username)
 				log.Warnln(logTag, ":", err)
@@ -169,13 +193,27 @@ func (a *Auth) basicAuth(h http.HandlerFunc) http.HandlerFunc {
 				req = req.WithContext(ctx)
 
 				// No need to validate if already validated before
-				if hasBasicAuth && !IsPasswordExist(reqUser.Username, password) && bcrypt.CompareHashAndPassword([]byte(reqUser.Password), []byte(password)) != nil {
+				if hasBasicAuth && !isVerified && !IsPasswordExist(reqUser.Username, password) && bcrypt.CompareHashAndPassword([]byte(reqUser.Password), []byte(password)) != nil {
 					w.Header().Set("www-authenticate", "Basic realm=\"Authentication Required\"")
 					telemetry.WriteBackErrorWithTelemetry(req, w, "invalid password", http.StatusUnauthorized)
 					return
 				}
-				// Save validated username to avoid the bcrypt comparison
-				SavePassword(reqUser.Username, password)
+				// the password alone isn't enough for the users enrolled for mfa, they must
+				// login with the totp code, and the privileged users that must enroll can
+				// only use basic auth to enroll
+				if hasBasicAuth && reqUser.HasMFA() {
+					telemetry.WriteBackErrorWithTelemetry(req, w, "mfa is enabled for the user, login with the totp code to create a session", http.StatusUnauthorized)
+					return
+				}
+				if hasBasicAuth && isMFARequiredForPrivileged() && reqUser.IsPrivileged() && !isMFAEnrollment(req) {
+					telemetry.WriteBackErrorWithTelemetry(req, w, "mfa enrollment is required for the privileged users", http.StatusForbidden)
+					return
+				}
+				// Save validated username to avoid the bcrypt comparison, the requests
+				// authenticated by a session or a client certificate carry no password
+				if hasBasicAuth && !isVerified && password != "" {
+					SavePassword(reqUser.Username, password)
+				}
 
 				// ignore es auth for root route to fetch the cluster details
 				if (req.Method == http.MethodGet || req.Method == http.MethodHead) && req.RequestURI == "/" {
@@ -203,8 +241,8 @@ func (a *Auth) basicAuth(h http.HandlerFunc) http.HandlerFunc {
 					authenticated = true
 				}
 
-				// cache the user
-				if _, ok := GetCachedCredential(username); !ok {
+				// cache the user, authenticators maintain their own cache
+				if _, ok := GetCachedCredential(username); !ok && !isVerified {
 					SaveCredentialToCache(username, reqUser)
 				}
 
@@ -220,7 +258,7 @@ func (a *Auth) basicAuth(h http.HandlerFunc) http.HandlerFunc {
 				req = req.WithContext(ctx)
 
 				reqPermission := obj.(*permission.Permission)
-				if hasBasicAuth && reqPermission.Password != password {
+				if hasBasicAuth && !isVerified && reqPermission.Password != password {
 					w.Header().Set("www-authenticate", "Basic realm=\"Authentication Required\"")
 					telemetry.WriteBackErrorWithTelemetry(req, w, "invalid password", http.StatusUnauthorized)
 					return
@@ -235,8 +273,8 @@ func (a *Auth) basicAuth(h http.HandlerFunc) http.HandlerFunc {
 					errorMsg = "credential is not allowed to access" + " " + str
 				}
 
-				// cache the permission
-				if _, ok := GetCachedCredential(username); !ok {
+				// cache the permission, authenticators maintain their own cache
+				if _, ok := GetCachedCredential(username); !ok && !isVerified {
 					SaveCredentialToCache(username, reqPermission)
 				}
 
@@ -265,6 +303,12 @@ func (a *Auth) basicAuth(h http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// isProvisioned returns true for the shadow users of an external identity provider.
+func isProvisioned(c credential.AuthCredential) bool {
+	u, ok := c.(*user.User)
+	return ok && u.Provider != ""
+}
+
 func (a *Auth) getCredential(ctx context.Context, username string) (credential.AuthCredential, error) {
 	c, ok := GetCachedCredential(username)
 	if ok {
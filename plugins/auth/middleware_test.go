@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/index"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+	"github.com/appbaseio/reactivesearch-api/model/user"
+)
+
+// fakeAuthService serves the credentials and the sessions from memory, the
+// methods that aren't used by the middleware aren't implemented.
+type fakeAuthService struct {
+	authService
+	users    map[string]*user.User
+	sessions map[string]*session
+}
+
+func (s *fakeAuthService) getCredential(ctx context.Context, username string) (credential.AuthCredential, error) {
+	if u, ok := s.users[username]; ok {
+		return u, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *fakeAuthService) getUser(ctx context.Context, username string) (*user.User, error) {
+	if u, ok := s.users[username]; ok {
+		return u, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *fakeAuthService) putUser(ctx context.Context, u user.User) (bool, error) {
+	s.users[u.Username] = &u
+	return true, nil
+}
+
+func (s *fakeAuthService) getSession(ctx context.Context, id string) (*session, error) {
+	if s, ok := s.sessions[id]; ok {
+		return s, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func newTestUser(t *testing.T, username, password string) *user.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := user.NewAdmin(username, string(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// serveBasicAuth passes the request through the basic auth middleware of a and
+// returns the status code of the response.
+func serveBasicAuth(a *Auth, req *http.Request) int {
+	reqCategory := category.Docs
+	reqOp := op.Read
+	ctx := category.NewContext(req.Context(), &reqCategory)
+	ctx = op.NewContext(ctx, &reqOp)
+	ctx = index.NewContext(ctx, []string{"books"})
+	req = req.WithContext(ctx)
+	req.Header.Set("X-Enable-Telemetry", "false")
+
+	w := httptest.NewRecorder()
+	a.basicAuth(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})(w, req)
+	return w.Code
+}
+
+func withClientCert(req *http.Request, commonName string) *http.Request {
+	req.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{
+			{Subject: pkix.Name{CommonName: commonName}},
+		}},
+	}
+	return req
+}
+
+func TestBasicAuth(t *testing.T) {
+	Convey("should not accept an empty password after a client certificate request", t, func() {
+		defer ClearLocalUser("cert-user")
+		a := &Auth{es: &fakeAuthService{users: map[string]*user.User{
+			"cert-user": newTestUser(t, "cert-user", "secret"),
+		}}}
+
+		req := withClientCert(httptest.NewRequest(http.MethodGet, "/books/_search", nil), "cert-user")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+
+		req = httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.SetBasicAuth("cert-user", "")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusUnauthorized)
+
+		req = httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.SetBasicAuth("cert-user", "secret")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+	})
+	Convey("should not accept an empty password after a session request", t, func() {
+		defer ClearLocalUser("session-user")
+		token := "session-token"
+		defer removeSessionFromCache(sessionID(token))
+		a := &Auth{es: &fakeAuthService{
+			users: map[string]*user.User{
+				"session-user": newTestUser(t, "session-user", "secret"),
+			},
+			sessions: map[string]*session{
+				sessionID(token): {Username: "session-user", ExpiresAt: time.Now().Add(time.Minute)},
+			},
+		}}
+
+		req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+
+		req = httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.SetBasicAuth("session-user", "")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusUnauthorized)
+	})
+	Convey("should reject the basic auth of the users enrolled for mfa", t, func() {
+		defer ClearLocalUser("mfa-user")
+		token := "mfa-session-token"
+		defer removeSessionFromCache(sessionID(token))
+		mfaUser := newTestUser(t, "mfa-user", "secret")
+		mfaUser.TOTP = &user.TOTP{Secret: rfcSecret, Enabled: true}
+		a := &Auth{es: &fakeAuthService{
+			users: map[string]*user.User{"mfa-user": mfaUser},
+			sessions: map[string]*session{
+				sessionID(token): {Username: "mfa-user", ExpiresAt: time.Now().Add(time.Minute)},
+			},
+		}}
+
+		req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.SetBasicAuth("mfa-user", "secret")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusUnauthorized)
+
+		req = httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+	})
+	Convey("should only let the privileged users enroll when mfa is required", t, func() {
+		os.Setenv(envMFARequiredForPrivileged, "true")
+		defer os.Unsetenv(envMFARequiredForPrivileged)
+		defer ClearLocalUser("admin-user")
+		a := &Auth{es: &fakeAuthService{users: map[string]*user.User{
+			"admin-user": newTestUser(t, "admin-user", "secret"),
+		}}}
+
+		req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.SetBasicAuth("admin-user", "secret")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusForbidden)
+
+		req = httptest.NewRequest(http.MethodPost, "/_mfa/totp", nil)
+		req.SetBasicAuth("admin-user", "secret")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+	})
+	Convey("should accept the credentials verified by an authenticator", t, func() {
+		var hits int32
+		server := newDelegateServer(&hits)
+		defer server.Close()
+		a := &Auth{es: &fakeAuthService{users: map[string]*user.User{
+			"local-user": newTestUser(t, "local-user", "secret"),
+		}}}
+		defer ClearLocalUser("local-user")
+		a.RegisterAuthenticator(newHTTPAuthenticator(server.URL, time.Minute))
+
+		req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.SetBasicAuth("foo", "bar")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+
+		req = httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.SetBasicAuth("foo", "baz")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusUnauthorized)
+
+		Convey("should not call the authenticators for the local credentials", func() {
+			atomic.StoreInt32(&hits, 0)
+			req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+			req.SetBasicAuth("local-user", "secret")
+			So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+			So(atomic.LoadInt32(&hits), ShouldEqual, 0)
+		})
+	})
+	Convey("should authenticate the ldap users against the directory", t, func() {
+		defer ClearLocalUser("bob")
+		token := "ldap-session-token"
+		defer removeSessionFromCache(sessionID(token))
+		service := &fakeAuthService{
+			users: map[string]*user.User{},
+			sessions: map[string]*session{
+				sessionID(token): {Username: "bob", ExpiresAt: time.Now().Add(time.Minute)},
+			},
+		}
+		a := &Auth{es: service}
+		a.RegisterAuthenticator(newTestLDAPAuthenticator(service))
+
+		req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		req.SetBasicAuth("bob", "secret")
+		So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+		So(service.users["bob"].Provider, ShouldEqual, ldapProvider)
+
+		Convey("should verify the provisioned shadow users with the directory", func() {
+			req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+			req.SetBasicAuth("bob", "secret")
+			So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+
+			req = httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+			req.SetBasicAuth("bob", "wrong")
+			So(serveBasicAuth(a, req), ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("should not accept an empty password after a session request", func() {
+			req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+			req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+			So(serveBasicAuth(a, req), ShouldEqual, http.StatusOK)
+
+			req = httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+			req.SetBasicAuth("bob", "")
+			So(serveBasicAuth(a, req), ShouldEqual, http.StatusUnauthorized)
+		})
+	})
+}
@@ -23,6 +23,48 @@ func (a *Auth) routes() []plugins.Route {
 			HandlerFunc: middleware(a.setPublicKey()),
 			Description: "Create or Update the public key",
 		},
+		{
+			Name:        "Login",
+			Methods:     []string{http.MethodPost},
+			Path:        "/_login",
+			HandlerFunc: a.login(),
+			Description: "Verifies the user credentials and issues a session cookie",
+		},
+		{
+			Name:        "Logout",
+			Methods:     []string{http.MethodPost},
+			Path:        "/_logout",
+			HandlerFunc: a.logout(),
+			Description: "Deletes the session of the user",
+		},
+		{
+			Name:        "Enroll TOTP",
+			Methods:     []string{http.MethodPost},
+			Path:        "/_mfa/totp",
+			HandlerFunc: middleware(a.enrollTOTP()),
+			Description: "Generates the TOTP secret for the user",
+		},
+		{
+			Name:        "Verify TOTP",
+			Methods:     []string{http.MethodPost},
+			Path:        "/_mfa/totp/verify",
+			HandlerFunc: middleware(a.verifyTOTP()),
+			Description: "Enables TOTP for the user and returns the recovery codes",
+		},
+		{
+			Name:        "Disable TOTP",
+			Methods:     []string{http.MethodDelete},
+			Path:        "/_mfa/totp",
+			HandlerFunc: middleware(a.disableTOTP()),
+			Description: "Disables TOTP for the user",
+		},
+		{
+			Name:        "Disable TOTP with {username}",
+			Methods:     []string{http.MethodDelete},
+			Path:        "/_mfa/totp/{username}",
+			HandlerFunc: middleware(a.disableTOTPWithUsername()),
+			Description: "Disables TOTP for the user with {username}",
+		},
 	}
 	return routes
 }
@@ -12,6 +12,7 @@ type authService interface {
 	getCredential(ctx context.Context, username string) (credential.AuthCredential, error)
 	putUser(ctx context.Context, u user.User) (bool, error)
 	getUser(ctx context.Context, username string) (*user.User, error)
+	patchUser(ctx context.Context, username string, patch map[string]interface{}) error
 	getRawUser(ctx context.Context, username string) ([]byte, error)
 	putPermission(ctx context.Context, p permission.Permission) (bool, error)
 	getPermission(ctx context.Context, username string) (*permission.Permission, error)
@@ -20,4 +21,8 @@ type authService interface {
 	createIndex(indexName, mapping string) (bool, error)
 	savePublicKey(ctx context.Context, indexName string, record publicKey) (interface{}, error)
 	getPublicKey(ctx context.Context) (publicKey, error)
+	putSession(ctx context.Context, id string, s session) (bool, error)
+	getSession(ctx context.Context, id string) (*session, error)
+	deleteSession(ctx context.Context, id string) (bool, error)
+	deleteExpiredSessions(ctx context.Context) error
 }
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	envSessionsEsIndex     = "SESSIONS_ES_INDEX"
+	defaultSessionsEsIndex = ".sessions"
+	envSessionTTL          = "SESSION_TTL"
+	defaultSessionTTL      = 24 * time.Hour
+	envSessionCookieSecure = "SESSION_COOKIE_SECURE"
+	sessionCookieName      = "reactivesearch_session"
+	csrfHeader             = "X-CSRF-Token"
+)
+
+// session represents a server side session issued by the login route. The
+// session token itself is never stored, the session is indexed by its hash.
+type session struct {
+	Username  string    `json:"username"`
+	CSRFToken string    `json:"csrf_token"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (s *session) isExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// SessionCache represents the cached sessions where key is the hashed session token
+var SessionCache = struct {
+	mu    sync.RWMutex
+	cache map[string]*session
+}{
+	cache: make(map[string]*session),
+}
+
+func getCachedSession(id string) (*session, bool) {
+	SessionCache.mu.RLock()
+	defer SessionCache.mu.RUnlock()
+	s, ok := SessionCache.cache[id]
+	return s, ok
+}
+
+func saveSessionToCache(id string, s *session) {
+	SessionCache.mu.Lock()
+	SessionCache.cache[id] = s
+	SessionCache.mu.Unlock()
+}
+
+func removeSessionFromCache(id string) {
+	SessionCache.mu.Lock()
+	delete(SessionCache.cache, id)
+	SessionCache.mu.Unlock()
+}
+
+func getSessionIndex() string {
+	sessionIndex := os.Getenv(envSessionsEsIndex)
+	if sessionIndex == "" {
+		sessionIndex = defaultSessionsEsIndex
+	}
+	return sessionIndex
+}
+
+func getSessionTTL() time.Duration {
+	ttl := os.Getenv(envSessionTTL)
+	if ttl == "" {
+		return defaultSessionTTL
+	}
+	duration, err := time.ParseDuration(ttl)
+	if err != nil || duration <= 0 {
+		log.Errorln(logTag, ":", envSessionTTL, "must be a valid duration, using the default value")
+		return defaultSessionTTL
+	}
+	return duration
+}
+
+func isSessionCookieSecure() bool {
+	secure, err := strconv.ParseBool(os.Getenv(envSessionCookieSecure))
+	if err != nil {
+		return true
+	}
+	return secure
+}
+
+// randomToken returns a hex encoded cryptographically secure random token.
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// sessionID returns the id against which a session token is stored.
+func sessionID(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// newSession creates and stores a session for the given username and returns the
+// session token to be set in the cookie.
+func (a *Auth) newSession(ctx context.Context, username string) (string, *session, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	s := &session{
+		Username:  username,
+		CSRFToken: csrfToken,
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.sessionTTL),
+	}
+	id := sessionID(token)
+	if _, err := a.es.putSession(ctx, id, *s); err != nil {
+		return "", nil, err
+	}
+	saveSessionToCache(id, s)
+	return token, s, nil
+}
+
+// getSession returns the valid session for the given session token.
+func (a *Auth) getSession(ctx context.Context, token string) (*session, bool) {
+	id := sessionID(token)
+	s, ok := getCachedSession(id)
+	if !ok {
+		var err error
+		s, err = a.es.getSession(ctx, id)
+		if err != nil || s == nil {
+			return nil, false
+		}
+		saveSessionToCache(id, s)
+	}
+	if s.isExpired() {
+		removeSessionFromCache(id)
+		return nil, false
+	}
+	return s, true
+}
+
+func (a *Auth) deleteSession(ctx context.Context, token string) error {
+	id := sessionID(token)
+	removeSessionFromCache(id)
+	_, err := a.es.deleteSession(ctx, id)
+	return err
+}
+
+// sessionFromRequest returns the session carried by the request cookie, if any.
+func (a *Auth) sessionFromRequest(req *http.Request) (*session, bool) {
+	cookie, err := req.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, false
+	}
+	return a.getSession(req.Context(), cookie.Value)
+}
+
+// validCSRFToken checks the csrf token header for the requests that can modify the state.
+func validCSRFToken(req *http.Request, s *session) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	token := req.Header.Get(csrfHeader)
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRFToken)) == 1
+}
+
+func (a *Auth) setSessionCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   isSessionCookieSecure(),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   isSessionCookieSecure(),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// purgeExpiredSessions deletes the expired sessions from elasticsearch.
+func (a *Auth) purgeExpiredSessions() {
+	err := a.es.deleteExpiredSessions(context.Background())
+	if err != nil {
+		log.Errorln(logTag, ": error while deleting expired sessions", err)
+	}
+}
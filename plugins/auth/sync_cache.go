@@ -55,3 +55,34 @@ func (s CacheSyncScript) SetCache(response *elastic.SearchResult) error {
 
 	return nil
 }
+
+// SessionSyncScript keeps the cached sessions in sync with the sessions index
+type SessionSyncScript struct {
+	index string
+}
+
+func (s SessionSyncScript) Index() string {
+	return s.index
+}
+
+func (s SessionSyncScript) PluginName() string {
+	return singleton.Name()
+}
+
+func (s SessionSyncScript) SetCache(response *elastic.SearchResult) error {
+	sessionHits := util.GetHitsForIndex(response, s.index)
+
+	sessions := make(map[string]bool)
+	for _, hit := range sessionHits {
+		sessions[hit.Id] = true
+	}
+	// delete the sessions from cache which are no longer present in ES
+	SessionCache.mu.Lock()
+	for id := range SessionCache.cache {
+		if !sessions[id] {
+			delete(SessionCache.cache, id)
+		}
+	}
+	SessionCache.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/x509"
+	"net/http"
+	"os"
+)
+
+const (
+	envClientCertIdentity = "CLIENT_CERT_IDENTITY"
+	// identity sources supported by the CLIENT_CERT_IDENTITY env,
+	// subject common name is used for any other value
+	certIdentityDNS   = "dns"
+	certIdentityEmail = "email"
+	certIdentityURI   = "uri"
+)
+
+// clientCertUsername returns the username mapped to the verified client certificate
+// of the request. The field of the certificate used as the username is defined by the
+// `CLIENT_CERT_IDENTITY` env, it defaults to the subject common name.
+func clientCertUsername(req *http.Request) (string, bool) {
+	// certificates are only present in verified chains if they are signed by the
+	// CA bundle configured on the server
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	username := certIdentity(req.TLS.VerifiedChains[0][0], os.Getenv(envClientCertIdentity))
+	return username, username != ""
+}
+
+func certIdentity(cert *x509.Certificate, identity string) string {
+	switch identity {
+	case certIdentityDNS:
+		if len(cert.DNSNames) > 0 {
+			return cert.DNSNames[0]
+		}
+	case certIdentityEmail:
+		if len(cert.EmailAddresses) > 0 {
+			return cert.EmailAddresses[0]
+		}
+	case certIdentityURI:
+		if len(cert.URIs) > 0 {
+			return cert.URIs[0].String()
+		}
+	default:
+		return cert.Subject.CommonName
+	}
+	return ""
+}
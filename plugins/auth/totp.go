@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/appbaseio/reactivesearch-api/model/user"
+)
+
+const (
+	envTOTPIssuer               = "TOTP_ISSUER"
+	defaultTOTPIssuer           = "ReactiveSearch"
+	envMFARequiredForPrivileged = "MFA_REQUIRED_FOR_PRIVILEGED_USERS"
+	// parameters defined by RFC 6238, these are the only values supported
+	// by the common authenticator apps
+	totpDigits = 6
+	totpPeriod = 30
+	// number of periods accepted before and after the current one to allow clock drift
+	totpSkew           = 1
+	totpSecretSize     = 20
+	recoveryCodesCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func getTOTPIssuer() string {
+	issuer := os.Getenv(envTOTPIssuer)
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+	return issuer
+}
+
+// isMFARequiredForPrivileged returns whether the users with `user-management` or
+// `access-control` actions must enroll for TOTP in order to login.
+func isMFARequiredForPrivileged() bool {
+	required, _ := strconv.ParseBool(os.Getenv(envMFARequiredForPrivileged))
+	return required
+}
+
+// isMFAEnrollment returns whether the request enrolls the user for TOTP.
+func isMFAEnrollment(req *http.Request) bool {
+	return req.Method == http.MethodPost && (req.URL.Path == "/_mfa/totp" || req.URL.Path == "/_mfa/totp/verify")
+}
+
+// newTOTPSecret returns a base32 encoded random secret.
+func newTOTPSecret() (string, error) {
+	buf := make([]byte, totpSecretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// totpProvisioningURI returns the `otpauth://` URI to be encoded in the QR code
+// scanned by the authenticator apps.
+func totpProvisioningURI(issuer, username, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", strconv.Itoa(totpDigits))
+	params.Set("period", strconv.Itoa(totpPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(username)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// totpCode computes the code of the given secret for the time step containing t.
+func totpCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %v", err)
+	}
+	return hotpCode(key, uint64(t.Unix()/totpPeriod)), nil
+}
+
+func hotpCode(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation as described in RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// validateTOTP checks the code against the secret, accepting the adjacent time steps
+// later than the last accepted one. The time step of the matched code is returned.
+func validateTOTP(secret, code string, t time.Time, lastStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	var step int64
+	valid := false
+	for skew := -totpSkew; skew <= totpSkew; skew++ {
+		at := t.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totpCode(secret, at)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 && at.Unix()/totpPeriod > lastStep {
+			step, valid = at.Unix()/totpPeriod, true
+		}
+	}
+	return step, valid
+}
+
+// newRecoveryCodes returns single use codes that can be used in place of a TOTP code.
+func newRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(buf))
+		codes[i] = code[:4] + "-" + code[4:]
+	}
+	return codes, nil
+}
+
+// verifyMFA checks the TOTP code of an enrolled user, or the recovery code when the
+// code is empty. A matched recovery code is removed so that it can't be used again.
+func (a *Auth) verifyMFA(ctx context.Context, u *user.User, code, recoveryCode string) (bool, error) {
+	if !u.HasMFA() {
+		return false, nil
+	}
+	if code != "" {
+		step, ok := validateTOTP(u.TOTP.Secret, code, time.Now(), u.TOTP.LastStep)
+		if !ok {
+			return false, nil
+		}
+		err := a.es.patchUser(ctx, u.Username, map[string]interface{}{
+			"totp": map[string]interface{}{
+				"last_step": step,
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+		ClearLocalUser(u.Username)
+		return true, nil
+	}
+	recoveryCode = strings.ToLower(strings.TrimSpace(recoveryCode))
+	if recoveryCode == "" {
+		return false, nil
+	}
+	for i, hashedCode := range u.TOTP.RecoveryCodes {
+		if bcrypt.CompareHashAndPassword([]byte(hashedCode), []byte(recoveryCode)) != nil {
+			continue
+		}
+		remaining := make([]string, 0, len(u.TOTP.RecoveryCodes)-1)
+		remaining = append(remaining, u.TOTP.RecoveryCodes[:i]...)
+		remaining = append(remaining, u.TOTP.RecoveryCodes[i+1:]...)
+		err := a.es.patchUser(ctx, u.Username, map[string]interface{}{
+			"totp": map[string]interface{}{
+				"recovery_codes": remaining,
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+		ClearLocalUser(u.Username)
+		return true, nil
+	}
+	return false, nil
+}
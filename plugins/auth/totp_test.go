@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// secret used by the test vectors of RFC 6238
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	Convey("should match the RFC 6238 test vectors", t, func() {
+		vectors := map[int64]string{
+			59:         "287082",
+			1111111109: "081804",
+			1111111111: "050471",
+			1234567890: "005924",
+			2000000000: "279037",
+		}
+		for unix, expected := range vectors {
+			code, err := totpCode(rfcSecret, time.Unix(unix, 0))
+			So(err, ShouldBeNil)
+			So(code, ShouldEqual, expected)
+		}
+	})
+	Convey("should fail with an invalid secret", t, func() {
+		_, err := totpCode("not-base32!", time.Now())
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	Convey("should accept the current code", t, func() {
+		step, ok := validateTOTP(rfcSecret, "050471", now, 0)
+		So(ok, ShouldBeTrue)
+		So(step, ShouldEqual, 1111111111/totpPeriod)
+	})
+	Convey("should accept the code of the adjacent step", t, func() {
+		_, ok := validateTOTP(rfcSecret, "050471", now.Add(30*time.Second), 0)
+		So(ok, ShouldBeTrue)
+	})
+	Convey("should reject the code outside the skew", t, func() {
+		_, ok := validateTOTP(rfcSecret, "050471", now.Add(2*time.Minute), 0)
+		So(ok, ShouldBeFalse)
+	})
+	Convey("should reject the replayed codes", t, func() {
+		step, ok := validateTOTP(rfcSecret, "050471", now, 0)
+		So(ok, ShouldBeTrue)
+		_, ok = validateTOTP(rfcSecret, "050471", now, step)
+		So(ok, ShouldBeFalse)
+		_, ok = validateTOTP(rfcSecret, "050471", now.Add(30*time.Second), step)
+		So(ok, ShouldBeFalse)
+	})
+	Convey("should reject the malformed codes", t, func() {
+		_, ok := validateTOTP(rfcSecret, "", now, 0)
+		So(ok, ShouldBeFalse)
+		_, ok = validateTOTP(rfcSecret, "05047", now, 0)
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	Convey("should generate a usable secret", t, func() {
+		secret, err := newTOTPSecret()
+		So(err, ShouldBeNil)
+		code, err := totpCode(secret, time.Now())
+		So(err, ShouldBeNil)
+		_, ok := validateTOTP(secret, code, time.Now(), 0)
+		So(ok, ShouldBeTrue)
+	})
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	Convey("should build the otpauth uri", t, func() {
+		uri := totpProvisioningURI("ReactiveSearch", "foo", "ABC")
+		So(uri, ShouldEqual, "otpauth://totp/ReactiveSearch:foo?algorithm=SHA1&digits=6&issuer=ReactiveSearch&period=30&secret=ABC")
+	})
+}
@@ -24,8 +24,12 @@ func ClearPassword(username string) {
 	CurrentProcessMutex.Unlock()
 }
 
-// IsPasswordExist checks whether the password in the cache or not
+// IsPasswordExist checks whether the password in the cache or not, an empty
+// password is never considered to be validated
 func IsPasswordExist(username string, password string) bool {
+	if password == "" {
+		return false
+	}
 	CurrentProcessMutex.Lock()
 	cachedPassword, ok := UserToPasswordCache[username]
 	CurrentProcessMutex.Unlock()
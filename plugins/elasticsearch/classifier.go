@@ -0,0 +1,48 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/appbaseio/reactivesearch-api/middleware/classify"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/plugins"
+	"github.com/gorilla/mux"
+)
+
+// newClassifier returns a classifier for the elasticsearch routes, the requests
+// are classified the same way classifyCategory, classifyACL and classifyOp do.
+func newClassifier(esRoutes []plugins.Route) classify.Classifier {
+	router := mux.NewRouter().StrictSlash(true)
+	for _, r := range esRoutes {
+		router.Methods(r.Methods...).Path(r.Path).HandlerFunc(r.HandlerFunc)
+	}
+	return func(req *http.Request) (*classify.Route, bool) {
+		var match mux.RouteMatch
+		if !router.Match(req, &match) || match.MatchErr != nil {
+			return nil, false
+		}
+		template, err := match.Route.GetPathTemplate()
+		if err != nil {
+			return nil, false
+		}
+		routeSpec, ok := routeSpecs[fmt.Sprintf("%s:%s", req.Method, template)]
+		if !ok {
+			return nil, false
+		}
+
+		routeCategory := routeSpec.category
+		// classify streams explicitly
+		if req.Header.Get("X-Request-Category") == "streams" {
+			routeCategory = category.Streams
+		}
+		routeACL := routeSpec.acl
+		return &classify.Route{
+			Name:     routeSpec.name,
+			Category: routeCategory,
+			ACL:      &routeACL,
+			Op:       routeSpec.op,
+			Vars:     match.Vars,
+		}, true
+	}
+}
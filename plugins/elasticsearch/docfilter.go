@@ -0,0 +1,167 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	es7 "github.com/olivere/elastic/v7"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/docfilter"
+	"github.com/appbaseio/reactivesearch-api/util"
+)
+
+// applyDocumentFilter adds the permission filter to the query of the search and
+// count requests, so that the documents outside of the filter are never matched.
+func applyDocumentFilter(req *http.Request, reqACL acl.ACL, filter map[string]interface{}) error {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	if reqACL == acl.Msearch {
+		body, err = docfilter.ApplyToMsearch(body, filter)
+		if err != nil {
+			return err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return nil
+	}
+
+	reqBody := make(map[string]interface{})
+	err = json.NewDecoder(bytes.NewReader(body)).Decode(&reqBody)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	// the lucene query of the `q` param can't be combined with a
+	// request body, it is moved to the body instead
+	params := req.URL.Query()
+	queryString := params.Get("q")
+	if queryString != "" {
+		params.Del("q")
+		req.URL.RawQuery = params.Encode()
+	}
+	// the suggest_field param of elasticsearch 6 adds a suggester as well
+	if params.Get("suggest_field") != "" {
+		return docfilter.ErrSuggest
+	}
+	if err := docfilter.ApplyToSearch(reqBody, filter, queryString); err != nil {
+		return err
+	}
+	body, err = json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+type docRef struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// visibleDocs returns the ids of the given documents of an index that match the filter.
+func visibleDocs(ctx context.Context, index string, ids []string, filter map[string]interface{}) (map[string]bool, error) {
+	query := map[string]interface{}{
+		"size":    len(ids),
+		"_source": false,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{
+						"ids": map[string]interface{}{"values": ids},
+					},
+					filter,
+				},
+			},
+		},
+	}
+	response, err := util.ClusterForIndex(index).Client7().PerformRequest(ctx, es7.PerformRequestOptions{
+		Method: http.MethodPost,
+		Path:   "/" + url.PathEscape(index) + "/_search",
+		Body:   query,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Hits struct {
+			Hits []docRef `json:"hits"`
+		} `json:"hits"`
+	}
+	err = json.Unmarshal(response.Body, &result)
+	if err != nil {
+		return nil, err
+	}
+	visible := make(map[string]bool)
+	for _, hit := range result.Hits.Hits {
+		visible[hit.ID] = true
+	}
+	return visible, nil
+}
+
+// isDocRequest returns true for the requests that address a single document by its id,
+// whether the route is typed or not.
+func isDocRequest(req *http.Request, reqACL acl.ACL) bool {
+	switch reqACL {
+	case acl.Get, acl.Source, acl.Exists:
+		return mux.Vars(req)["id"] != ""
+	}
+	return false
+}
+
+// isUnfiltered returns true for the requests that read the documents or the terms of
+// an index without a query, these can't be restricted by a document filter.
+func isUnfiltered(reqACL acl.ACL) bool {
+	return reqACL == acl.Explain || reqACL == acl.Termvectors || reqACL == acl.Mtermvectors
+}
+
+// isDocVisible checks whether the document requested by a get request matches the filter.
+func isDocVisible(ctx context.Context, index, id string, filter map[string]interface{}) (bool, error) {
+	visible, err := visibleDocs(ctx, index, []string{id}, filter)
+	if err != nil {
+		return false, err
+	}
+	return visible[id], nil
+}
+
+// filterMgetDocs marks the documents of a mget response that don't match the filter as not found.
+func filterMgetDocs(ctx context.Context, response *MGetResponse, filter map[string]interface{}) error {
+	ids := make(map[string][]string)
+	for _, doc := range response.Docs {
+		if found, _ := doc["found"].(bool); !found {
+			continue
+		}
+		index, _ := doc["_index"].(string)
+		id, _ := doc["_id"].(string)
+		ids[index] = append(ids[index], id)
+	}
+	for index, indexIds := range ids {
+		visible, err := visibleDocs(ctx, index, indexIds, filter)
+		if err != nil {
+			return err
+		}
+		for i, doc := range response.Docs {
+			id, _ := doc["_id"].(string)
+			if doc["_index"] != index || visible[id] {
+				continue
+			}
+			hidden := map[string]interface{}{
+				"_index": doc["_index"],
+				"_id":    doc["_id"],
+				"found":  false,
+			}
+			if docType, ok := doc["_type"]; ok {
+				hidden["_type"] = docType
+			}
+			response.Docs[i] = hidden
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+)
+
+func TestIsDocRequest(t *testing.T) {
+	newRequest := func(path string, vars map[string]string) *http.Request {
+		return mux.SetURLVars(httptest.NewRequest(http.MethodGet, path, nil), vars)
+	}
+	Convey("should recognize the typed and the untyped document routes", t, func() {
+		So(isDocRequest(newRequest("/books/_doc/1", map[string]string{"index": "books", "id": "1"}), acl.Get), ShouldBeTrue)
+		So(isDocRequest(newRequest("/books/book/1", map[string]string{"index": "books", "type": "book", "id": "1"}), acl.Get), ShouldBeTrue)
+		So(isDocRequest(newRequest("/books/book/1/_source", map[string]string{"index": "books", "type": "book", "id": "1"}), acl.Source), ShouldBeTrue)
+		So(isDocRequest(newRequest("/books/book/1", map[string]string{"index": "books", "type": "book", "id": "1"}), acl.Exists), ShouldBeTrue)
+	})
+	Convey("should not depend on the index name", t, func() {
+		So(isDocRequest(newRequest("/my_docs/_search", map[string]string{"index": "my_docs"}), acl.Search), ShouldBeFalse)
+		So(isDocRequest(newRequest("/my_docs/_mapping", map[string]string{"index": "my_docs"}), acl.Get), ShouldBeFalse)
+	})
+}
+
+func TestIsUnfiltered(t *testing.T) {
+	Convey("should recognize the explain and the term vectors requests", t, func() {
+		So(isUnfiltered(acl.Explain), ShouldBeTrue)
+		So(isUnfiltered(acl.Termvectors), ShouldBeTrue)
+		So(isUnfiltered(acl.Mtermvectors), ShouldBeTrue)
+		So(isUnfiltered(acl.Search), ShouldBeFalse)
+		So(isUnfiltered(acl.Get), ShouldBeFalse)
+	})
+}
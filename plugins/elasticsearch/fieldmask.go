@@ -0,0 +1,83 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/fieldmask"
+)
+
+// validateFieldMasks rejects the search and msearch requests that aggregate or sort
+// on the masked fields, the request body is restored for the next handlers.
+func validateFieldMasks(req *http.Request, reqACL acl.ACL, masks []fieldmask.Rule) error {
+	if reqACL != acl.Search && reqACL != acl.Msearch {
+		return nil
+	}
+	// the sort param is a comma separated list of field:direction pairs
+	for _, sort := range strings.Split(req.URL.Query().Get("sort"), ",") {
+		field := strings.Split(sort, ":")[0]
+		if field != "" && fieldmask.IsMasked(masks, field) {
+			return fmt.Errorf(`sorting on the masked field "%s" isn't allowed`, field)
+		}
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if reqACL == acl.Msearch {
+		return fieldmask.ValidateMsearch(body, masks)
+	}
+	reqBody := make(map[string]interface{})
+	err = json.NewDecoder(bytes.NewReader(body)).Decode(&reqBody)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return fieldmask.ValidateSearch(reqBody, masks)
+}
+
+// maskFields applies the field masks of the permission to the documents of
+// the search, msearch, get, source and mget responses.
+func maskFields(req *http.Request, reqACL acl.ACL, body []byte, masks []fieldmask.Rule) ([]byte, error) {
+	// HEAD requests don't have a body
+	if len(body) == 0 {
+		return body, nil
+	}
+	switch {
+	case reqACL == acl.Search || reqACL == acl.Msearch:
+		response := make(map[string]interface{})
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+		fieldmask.ApplyToResponse(response, masks)
+		return json.Marshal(response)
+	case reqACL == acl.Get || reqACL == acl.Source:
+		doc := make(map[string]interface{})
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return nil, err
+		}
+		// GET /:index/_source/:id and GET /:index/_doc/:id/_source respond with the source only
+		if strings.Contains(req.URL.Path, "/_source") {
+			fieldmask.ApplyToSource(doc, masks)
+		} else {
+			fieldmask.ApplyToHit(doc, masks)
+		}
+		return json.Marshal(doc)
+	case reqACL == acl.Mget:
+		var mGetResponse MGetResponse
+		if err := json.Unmarshal(body, &mGetResponse); err != nil {
+			return nil, err
+		}
+		for _, doc := range mGetResponse.Docs {
+			fieldmask.ApplyToHit(doc, masks)
+		}
+		return json.Marshal(mGetResponse)
+	}
+	return body, nil
+}
@@ -3,10 +3,13 @@ package elasticsearch
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +19,8 @@ import (
 
 	"github.com/appbaseio/reactivesearch-api/model/acl"
 	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/index"
 	"github.com/appbaseio/reactivesearch-api/model/op"
 	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
 	"github.com/appbaseio/reactivesearch-api/util"
@@ -79,15 +84,39 @@ func (es *elasticsearch) handler() http.HandlerFunc {
 			Params:  params,
 			Headers: headers,
 		}
+		// forward the media types that request the REST API compatibility of elasticsearch 8
+		if accept := r.Header.Get("Accept"); util.IsCompatibleMediaType(accept) {
+			headers.Set("Accept", accept)
+		}
+		if contentType := r.Header.Get("Content-Type"); util.IsCompatibleMediaType(contentType) {
+			requestOptions.ContentType = contentType
+		}
 
 		// convert body to string string as oliver Perform request can accept io.Reader, String, interface
 		body, err := ioutil.ReadAll(r.Body)
 		if len(body) > 0 {
 			requestOptions.Body = string(body)
 		}
+		// route the request to the cluster of its indices, including the ones addressed
+		// in the body, the requests without indices are served by the default cluster
+		// and the cursor continuations by the cluster that opened the cursor
+		cluster, ok := util.ClusterForCursor(requestOptions)
+		if !ok {
+			reqIndices, _ := index.FromContext(ctx)
+			cluster, err = util.ClusterForIndices(index.Addressed(*reqACL, r.URL.Path, reqIndices, body))
+			if err != nil {
+				telemetry.WriteBackErrorWithTelemetry(r, w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 		start := time.Now()
-		response, err := util.GetClient7().PerformRequest(ctx, requestOptions)
+		response, err := util.PerformClusterRequest(ctx, cluster, credential.IDFromContext(ctx), requestOptions, *reqOp == op.Read)
 		log.Println(fmt.Sprintf("TIME TAKEN BY ES: %dms", time.Since(start).Milliseconds()))
+		var unavailableErr *util.UpstreamUnavailableError
+		if errors.As(err, &unavailableErr) {
+			writeUpstreamUnavailable(r, w, unavailableErr)
+			return
+		}
 		if err != nil {
 			log.Errorln(logTag, ": error while sending request :", r.URL.Path, err)
 			if response != nil {
@@ -117,6 +146,13 @@ func (es *elasticsearch) handler() http.HandlerFunc {
 	}
 }
 
+// writeUpstreamUnavailable writes the 503 response of a request that isn't made
+// because the circuit breaker of the upstream is open.
+func writeUpstreamUnavailable(r *http.Request, w http.ResponseWriter, err *util.UpstreamUnavailableError) {
+	w.Header().Set("Retry-After", strconv.FormatInt(err.RetryAfterSeconds(), 10))
+	telemetry.WriteBackTypedErrorWithTelemetry(r, w, "upstream_unavailable", err.Error(), http.StatusServiceUnavailable)
+}
+
 func (es *elasticsearch) healthCheck() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		_, code, err := util.GetClient7().Ping(util.GetESURL()).Do(context.Background())
@@ -128,3 +164,24 @@ func (es *elasticsearch) healthCheck() http.HandlerFunc {
 		util.WriteBackRaw(w, []byte{}, code)
 	}
 }
+
+func (es *elasticsearch) upstreamHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := util.BreakerStatuses()
+		code := http.StatusOK
+		for _, status := range statuses {
+			if status.State == util.BreakerOpen {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		raw, err := json.Marshal(map[string]interface{}{
+			"breakers": statuses,
+		})
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			telemetry.WriteBackErrorWithTelemetry(r, w, "error while marshalling the upstream health", http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, raw, code)
+	}
+}
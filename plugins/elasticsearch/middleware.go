@@ -15,16 +15,20 @@ import (
 
 	"github.com/appbaseio/reactivesearch-api/middleware"
 	"github.com/appbaseio/reactivesearch-api/middleware/classify"
+	"github.com/appbaseio/reactivesearch-api/middleware/concurrency"
 	"github.com/appbaseio/reactivesearch-api/middleware/ratelimiter"
 	"github.com/appbaseio/reactivesearch-api/middleware/validate"
 	"github.com/appbaseio/reactivesearch-api/model/acl"
 	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/docfilter"
 	"github.com/appbaseio/reactivesearch-api/model/index"
 	"github.com/appbaseio/reactivesearch-api/model/op"
 	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/model/querypolicy"
 	"github.com/appbaseio/reactivesearch-api/model/sourcefilter"
 	"github.com/appbaseio/reactivesearch-api/plugins/auth"
 	"github.com/appbaseio/reactivesearch-api/plugins/logs"
+	"github.com/appbaseio/reactivesearch-api/plugins/permissions"
 	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
 	"github.com/appbaseio/reactivesearch-api/util"
 	"github.com/gorilla/mux"
@@ -50,6 +54,7 @@ func list() []middleware.Middleware {
 		classifyOp,
 		classify.Indices(),
 		logs.Recorder(),
+		ratelimiter.LimitUnauthenticated(),
 		auth.BasicAuth(),
 		ratelimiter.Limit(),
 		validate.Sources(),
@@ -59,6 +64,9 @@ func list() []middleware.Middleware {
 		validate.ACL(),
 		validate.Operation(),
 		validate.PermissionExpiry(),
+		validate.PermissionSchedule(),
+		permissions.Quota(),
+		concurrency.Limit(),
 		intercept,
 	}
 }
@@ -149,6 +157,46 @@ func intercept(h http.HandlerFunc) http.HandlerFunc {
 		}
 		isMsearch := *reqACL == acl.Msearch
 		isSearch := *reqACL == acl.Search
+		isCount := *reqACL == acl.Count
+		if reqPermission, err := permission.FromContext(ctx); err == nil && reqPermission.Filter != nil && isUnfiltered(*reqACL) {
+			telemetry.WriteBackErrorWithTelemetry(req, w, docfilter.ErrUnfiltered.Error(), http.StatusBadRequest)
+			return
+		}
+		if (isSearch || isMsearch || isCount) && !strings.Contains(req.URL.Path, "/scroll") {
+			reqPermission, err := permission.FromContext(ctx)
+			// Apply the query policy of the permission, before the document
+			// filter which isn't defined by the request
+			if err == nil && reqPermission.QueryPolicy != nil {
+				err := applyQueryPolicy(req, *reqACL, reqPermission.QueryPolicy)
+				if violation, ok := err.(*querypolicy.Violation); ok {
+					telemetry.WriteBackTypedErrorWithTelemetry(req, w, violation.Type, violation.Message, http.StatusBadRequest)
+					return
+				}
+				if err != nil {
+					log.Errorln(logTag, ":", err)
+					telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			// Apply the document filter of the permission
+			if err == nil && reqPermission.Filter != nil {
+				err := applyDocumentFilter(req, *reqACL, reqPermission.Filter.ToQuery())
+				if err != nil {
+					log.Errorln(logTag, ":", err)
+					telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			// Reject the aggregations and the sorts that would expose the masked fields
+			if err == nil && len(reqPermission.FieldMasks) > 0 {
+				err := validateFieldMasks(req, *reqACL, reqPermission.FieldMasks)
+				if err != nil {
+					log.Errorln(logTag, ":", err)
+					telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+		}
 		if (isSearch || isMsearch) && !strings.Contains(req.URL.Path, "/scroll") {
 			// Apply source filters
 			// /_search/scroll is a special case that doesn't support source filtering
@@ -239,6 +287,37 @@ func intercept(h http.HandlerFunc) http.HandlerFunc {
 		}
 
 		reqPermission, err := permission.FromContext(ctx)
+		if err == nil && result.StatusCode == http.StatusOK && reqPermission.Filter != nil {
+			// Hide the documents that don't match the document filter of the permission
+			if isDocRequest(req, *reqACL) {
+				vars := mux.Vars(req)
+				visible, err := isDocVisible(ctx, vars["index"], vars["id"], reqPermission.Filter.ToQuery())
+				if err != nil {
+					log.Errorln(logTag, ":", err)
+					telemetry.WriteBackErrorWithTelemetry(req, w, "error applying the document filter", http.StatusInternalServerError)
+					return
+				}
+				if !visible {
+					telemetry.WriteBackErrorWithTelemetry(req, w, "document not found", http.StatusNotFound)
+					return
+				}
+			}
+			if *reqACL == acl.Mget {
+				var mGetResponse MGetResponse
+				err := json.Unmarshal(body, &mGetResponse)
+				if err == nil {
+					err = filterMgetDocs(ctx, &mGetResponse, reqPermission.Filter.ToQuery())
+				}
+				if err == nil {
+					body, err = json.Marshal(mGetResponse)
+				}
+				if err != nil {
+					log.Errorln(logTag, ":", err)
+					telemetry.WriteBackErrorWithTelemetry(req, w, "error applying the document filter", http.StatusInternalServerError)
+					return
+				}
+			}
+		}
 		if err == nil && result.StatusCode == http.StatusOK {
 			// Apply Appbase source filtering to the following type of requests
 			// GET /:index/_doc/:id
@@ -314,6 +393,10 @@ func intercept(h http.HandlerFunc) http.HandlerFunc {
 						return
 					}
 					for _, doc := range mGetResponse.Docs {
+						// documents that are not found don't have a source
+						if _, ok := doc["_source"]; !ok {
+							continue
+						}
 						sourceAsMap, ok := doc["_source"].(map[string]interface{})
 						if !ok {
 							errMsg := "unable to type cast source to map[string]interface{}"
@@ -340,6 +423,16 @@ func intercept(h http.HandlerFunc) http.HandlerFunc {
 				}
 			}
 		}
+		if err == nil && result.StatusCode == http.StatusOK && len(reqPermission.FieldMasks) > 0 {
+			// Mask the fields of the returned documents
+			maskedBody, err := maskFields(req, *reqACL, body, reqPermission.FieldMasks)
+			if err != nil {
+				log.Errorln(logTag, ":", err)
+				telemetry.WriteBackErrorWithTelemetry(req, w, "error masking the response fields", http.StatusInternalServerError)
+				return
+			}
+			body = maskedBody
+		}
 		for _, index := range indices {
 			alias := classify.GetIndexAlias(index)
 			if alias != "" {
@@ -0,0 +1,79 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/querypolicy"
+)
+
+// applyQueryPolicy applies the query policy of the permission to the body of a search,
+// msearch or count request. A *querypolicy.Violation is returned if the policy rejects
+// the request, the request body is replaced with the rewritten one otherwise.
+func applyQueryPolicy(req *http.Request, reqACL acl.ACL, policy *querypolicy.Policy) error {
+	// the queries of the search templates are only known once rendered by elasticsearch
+	if isTemplateSearch(req) {
+		return &querypolicy.Violation{
+			Type:    querypolicy.TemplateNotAllowed,
+			Message: "search templates are not allowed by the query policy",
+		}
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	if reqACL == acl.Msearch {
+		body, err = policy.ApplyToMsearch(body)
+		if err != nil {
+			return err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return nil
+	}
+
+	reqBody := make(map[string]interface{})
+	err = json.NewDecoder(bytes.NewReader(body)).Decode(&reqBody)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	params := req.URL.Query()
+	// the size param takes precedence over the size of the body, it is
+	// moved to the body in order to be validated against the policy
+	if size := params.Get("size"); size != "" {
+		value, err := strconv.Atoi(size)
+		if err != nil {
+			return fmt.Errorf(`invalid "size" param: %s`, size)
+		}
+		reqBody["size"] = float64(value)
+		params.Del("size")
+		req.URL.RawQuery = params.Encode()
+	}
+	queryString := params.Get("q")
+	if err := policy.ApplyToSearch(reqBody, queryString); err != nil {
+		return err
+	}
+	if queryString != "" && policy.DisableLeadingWildcards && policy.Action == querypolicy.Rewrite {
+		params.Set("allow_leading_wildcard", "false")
+		req.URL.RawQuery = params.Encode()
+	}
+	if len(body) > 0 || len(reqBody) > 0 {
+		body, err = json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+// isTemplateSearch returns true for the _search/template and _msearch/template requests.
+func isTemplateSearch(req *http.Request) bool {
+	return strings.HasSuffix(strings.TrimSuffix(req.URL.Path, "/"), "search/template")
+}
@@ -0,0 +1,34 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/querypolicy"
+)
+
+func TestApplyQueryPolicy(t *testing.T) {
+	ten := 10
+	policy := &querypolicy.Policy{MaxSize: &ten}
+	Convey("should reject the search templates", t, func() {
+		for _, path := range []string{"/books/_search/template", "/_msearch/template"} {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"id":"books","params":{"size":100000}}`))
+			err := applyQueryPolicy(req, acl.Search, policy)
+			violation, ok := err.(*querypolicy.Violation)
+			So(ok, ShouldBeTrue)
+			So(violation.Type, ShouldEqual, querypolicy.TemplateNotAllowed)
+		}
+	})
+	Convey("should move the size param to the body", t, func() {
+		req := httptest.NewRequest(http.MethodPost, "/books/_search?size=100", strings.NewReader(`{}`))
+		err := applyQueryPolicy(req, acl.Search, policy)
+		violation, ok := err.(*querypolicy.Violation)
+		So(ok, ShouldBeTrue)
+		So(violation.Type, ShouldEqual, querypolicy.MaxSizeExceeded)
+	})
+}
@@ -12,6 +12,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/middleware/classify"
 	"github.com/appbaseio/reactivesearch-api/model/acl"
 	"github.com/appbaseio/reactivesearch-api/model/category"
 	"github.com/appbaseio/reactivesearch-api/model/op"
@@ -26,6 +27,19 @@ var (
 	acls       = make(map[category.Category]map[acl.ACL]bool)
 )
 
+// removedSpecs8 are the APIs removed in elasticsearch 8.
+var removedSpecs8 = []string{
+	"indices.exists_type",
+	"indices.flush_synced",
+	"indices.get_upgrade",
+	"indices.upgrade",
+}
+
+// aclAliases maps the path tokens of the newer APIs to the ACLs of the equivalent APIs.
+var aclAliases = map[string]acl.ACL{
+	"index_template": acl.Template,
+}
+
 type api struct {
 	name     string
 	category category.Category
@@ -51,17 +65,16 @@ type spec struct {
 }
 
 func (es *elasticsearch) preprocess(mw []middleware.Middleware) error {
-	files := make(chan string)
-	apis := make(chan api)
-
 	box := packr.NewBox("./api")
-
-	go fetchSpecFiles(&box, files)
-	go decodeSpecFiles(&box, files, apis)
+	apis := loadSpecs(&box)
+	if util.GetVersion() >= 8 {
+		box8 := packr.NewBox("./api8")
+		apis = specsFor8(apis, loadSpecs(&box8))
+	}
 
 	middlewareFunction := (&chain{}).Wrap
 
-	for api := range apis {
+	for _, api := range apis {
 		for _, path := range api.spec.URL.Paths {
 			if !strings.HasPrefix(path, "/") {
 				path = "/" + path
@@ -116,7 +129,25 @@ func (es *elasticsearch) preprocess(mw []middleware.Middleware) error {
 		HandlerFunc: es.healthCheck(),
 		Description: "Retrieve the cluster health, both appbase.io and Elasticsearch",
 	}
-	routes = append(routes, indexRoute, healthCheckRoute)
+	upstreamHealthRoute := plugins.Route{
+		Name:        "upstream health",
+		Methods:     []string{http.MethodGet},
+		Path:        "/arc/health/upstream",
+		HandlerFunc: middlewareFunction(mw, es.upstreamHealth()),
+		Description: "Retrieve the state of the circuit breakers of the upstream Elasticsearch clusters",
+	}
+	// the upstream hosts are only exposed to the credentials that can read the
+	// cluster health, the route is authorized like the cluster health api
+	for _, method := range upstreamHealthRoute.Methods {
+		routeSpecs[fmt.Sprintf("%s:%s", method, upstreamHealthRoute.Path)] = api{
+			name:     upstreamHealthRoute.Name,
+			category: category.Clusters,
+			acl:      acl.Cluster,
+			op:       op.Read,
+		}
+	}
+	routes = append(routes, indexRoute, healthCheckRoute, upstreamHealthRoute)
+	classify.RegisterClassifier(newClassifier(routes))
 	return nil
 }
 
@@ -124,6 +155,44 @@ func (es *elasticsearch) routes() []plugins.Route {
 	return routes
 }
 
+// loadSpecs decodes the API specs packaged in the box.
+func loadSpecs(box *packr.Box) []api {
+	files := make(chan string)
+	apis := make(chan api)
+
+	go fetchSpecFiles(box, files)
+	go decodeSpecFiles(box, files, apis)
+
+	var specs []api
+	for api := range apis {
+		specs = append(specs, api)
+	}
+	return specs
+}
+
+// specsFor8 returns the API specs of elasticsearch 8. The specs of 8 replace the
+// specs of the APIs that have dropped the mapping types, an API keeps its category
+// and ACL so that the permissions apply the same way. The APIs removed in 8 aren't
+// proxied.
+func specsFor8(apis, apis8 []api) []api {
+	overrides := make(map[string]api)
+	for _, api := range apis8 {
+		overrides[api.name] = api
+	}
+	var specs []api
+	for _, api := range apis {
+		if util.Contains(removedSpecs8, api.name) {
+			continue
+		}
+		if override, ok := overrides[api.name]; ok {
+			api.spec = override.spec
+			api.op = override.op
+		}
+		specs = append(specs, api)
+	}
+	return specs
+}
+
 func fetchSpecFiles(box *packr.Box, files chan<- string) {
 	defer close(files)
 	for _, file := range box.List() {
@@ -207,6 +276,9 @@ func decodeACL(specName string, spec *spec) (*acl.ACL, error) {
 	for _, pathToken := range pathTokens {
 		if strings.HasPrefix(pathToken, "_") {
 			pathToken = strings.TrimPrefix(pathToken, "_")
+			if c, ok := aclAliases[pathToken]; ok {
+				return &c, nil
+			}
 			c, err := acl.FromString(pathToken)
 			if err != nil {
 				return nil, err
@@ -0,0 +1,45 @@
+package elasticsearch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/util"
+	"github.com/gobuffalo/packr"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSpecsFor8(t *testing.T) {
+	box := packr.NewBox("./api")
+	box8 := packr.NewBox("./api8")
+	apis := loadSpecs(&box)
+	apis8 := specsFor8(apis, loadSpecs(&box8))
+	byName := make(map[string]api)
+	for _, api := range apis {
+		byName[api.name] = api
+	}
+
+	Convey("should drop the mapping types from the specs of 8", t, func() {
+		So(len(apis8), ShouldEqual, len(apis)-len(removedSpecs8))
+		for _, api := range apis8 {
+			So(util.Contains(removedSpecs8, api.name), ShouldBeFalse)
+			for _, path := range api.spec.URL.Paths {
+				So(strings.Contains(path, "{type}"), ShouldBeFalse)
+			}
+		}
+	})
+
+	Convey("should keep the category and the ACL of the APIs", t, func() {
+		for _, api := range apis8 {
+			So(api.category, ShouldEqual, byName[api.name].category)
+			So(api.acl, ShouldEqual, byName[api.name].acl)
+			So(api.op, ShouldEqual, byName[api.name].op)
+		}
+	})
+
+	Convey("should classify the composable index templates as templates", t, func() {
+		So(byName["indices.put_index_template"].acl, ShouldEqual, acl.Template)
+		So(byName["indices.get_index_template"].acl, ShouldEqual, byName["indices.get_template"].acl)
+	})
+}
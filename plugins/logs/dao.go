@@ -18,16 +18,17 @@ import (
 
 type elasticsearch struct {
 	indexName string
+	cluster   *util.Cluster
 }
 
 func initPlugin(alias, config string) (*elasticsearch, error) {
 
 	ctx := context.Background()
 
-	var es = &elasticsearch{alias}
+	var es = &elasticsearch{alias, util.ClusterForIndex(alias)}
 
 	// Check if alias exists instead of index and create first index if not exists with `${alias}-000001`
-	res, err := util.GetClient7().Aliases().Do(ctx)
+	res, err := es.cluster.Client7().Aliases().Do(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error while checking if index already exists: %v", err)
 	}
@@ -44,21 +45,21 @@ func initPlugin(alias, config string) (*elasticsearch, error) {
 
 	replicas := util.GetReplicas()
 
-	settings := fmt.Sprintf(config, alias, util.HiddenIndexSettings(), replicas, LogsMappings)
+	settings := fmt.Sprintf(config, alias, es.cluster.HiddenIndexSettings(), replicas, LogsMappings)
 
-	if util.GetVersion() == 6 {
+	if es.cluster.GetVersion() == 6 {
 		mappings := fmt.Sprintf(`{"_doc": %s}`, LogsMappings)
-		settings = fmt.Sprintf(config, alias, util.HiddenIndexSettings(), replicas, mappings)
+		settings = fmt.Sprintf(config, alias, es.cluster.HiddenIndexSettings(), replicas, mappings)
 	}
 	// Meta index doesn't exist, create one
 	indexName := alias + `-000001`
 	// this works for ES6 client as well
-	_, err = util.GetClient7().CreateIndex(indexName).
+	_, err = es.cluster.Client7().CreateIndex(indexName).
 		Body(settings).
 		Do(ctx)
 	if err != nil {
 		log.Errorln(logTag, " : ", fmt.Errorf("error while creating index named \"%s\" %v", indexName, err))
-		isAliasExistsAsIndex, err := util.GetClient7().IndexExists(alias).Do(ctx)
+		isAliasExistsAsIndex, err := es.cluster.Client7().IndexExists(alias).Do(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("error while checking if index already exists: %v", err)
 		}
@@ -114,10 +115,10 @@ func initPlugin(alias, config string) (*elasticsearch, error) {
 func (es *elasticsearch) indexRecord(ctx context.Context, rec record) {
 	bulkIndex := es7.NewBulkIndexRequest().
 		Index(es.indexName).
-		Type("_doc").
+		Type(es.cluster.GetDocType()).
 		Doc(rec)
 
-	_, err := util.GetClient7().Bulk().
+	_, err := es.cluster.Client7().Bulk().
 		Add(bulkIndex).
 		Do(ctx)
 	if err != nil {
@@ -138,7 +139,7 @@ type logsFilter struct {
 }
 
 func (es *elasticsearch) getRawLogs(ctx context.Context, logsFilter logsFilter) ([]byte, error) {
-	switch util.GetVersion() {
+	switch es.cluster.GetVersion() {
 	case 6:
 		return es.getRawLogsES6(ctx, logsFilter)
 	default:
@@ -154,18 +155,18 @@ func (es *elasticsearch) rolloverIndexJob(alias string) {
 		rolloverConfiguration = fmt.Sprintf(rolloverConfig, "30d", 1000000, "10gb")
 	}
 	json.Unmarshal([]byte(rolloverConfiguration), &rolloverConditions)
-	settingsString := fmt.Sprintf(`{%s "index.number_of_shards": 1, "index.number_of_replicas": %d}`, util.HiddenIndexSettings(), util.GetReplicas())
+	settingsString := fmt.Sprintf(`{%s "index.number_of_shards": 1, "index.number_of_replicas": %d}`, es.cluster.HiddenIndexSettings(), util.GetReplicas())
 	settings := make(map[string]interface{})
 	json.Unmarshal([]byte(settingsString), &settings)
 
 	mappingString := LogsMappings
-	if util.GetVersion() == 6 {
+	if es.cluster.GetVersion() == 6 {
 		mappingString = fmt.Sprintf(`{"_doc": %s}`, LogsMappings)
 	}
 
 	mappings := make(map[string]interface{})
 	json.Unmarshal([]byte(mappingString), &mappings)
-	rolloverService, err := es7.NewIndicesRolloverService(util.GetClient7()).
+	rolloverService, err := es7.NewIndicesRolloverService(es.cluster.Client7()).
 		Alias(alias).
 		Conditions(rolloverConditions).
 		Settings(settings).
@@ -193,7 +194,7 @@ func (es *elasticsearch) rolloverIndexJob(alias string) {
 	// -> else do not delete any index
 
 	// cat all the indices starting with `${alias}-Number` pattern
-	indices, err := util.GetClient7().CatIndices().Index(alias + "-*").
+	indices, err := es.cluster.Client7().CatIndices().Index(alias + "-*").
 		Do(ctx)
 	if err != nil {
 		log.Errorln(logTag, ": rollover cronjob error getting indices", err)
@@ -214,7 +215,7 @@ func (es *elasticsearch) rolloverIndexJob(alias string) {
 		rolloverIndices = rolloverIndices[:len(rolloverIndices)-2]
 
 		log.Println(logTag, ": rollover cronjob, indices to delete", rolloverIndices)
-		_, err = util.GetClient7().DeleteIndex(strings.Join(rolloverIndices, ",")).Do(ctx)
+		_, err = es.cluster.Client7().DeleteIndex(strings.Join(rolloverIndices, ",")).Do(ctx)
 		if err != nil {
 			log.Errorln(logTag, ": rollover cronjob, error while deleting indices", err)
 		}
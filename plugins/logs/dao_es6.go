@@ -57,7 +57,7 @@ func (es *elasticsearch) getRawLogsES6(ctx context.Context, logsFilter logsFilte
 		query.Filter(latencyRangeQuery)
 	}
 
-	searchQuery := util.GetClient6().Search(es.indexName).
+	searchQuery := es.cluster.Client6().Search(es.indexName).
 		Query(query).
 		From(logsFilter.Offset).
 		Size(logsFilter.Size)
@@ -57,7 +57,7 @@ func (es *elasticsearch) getRawLogsES7(ctx context.Context, logsFilter logsFilte
 		query.Filter(latencyRangeQuery)
 	}
 
-	searchQuery := util.GetClient7().Search(es.indexName).
+	searchQuery := es.cluster.Client7().Search(es.indexName).
 		Query(query).
 		From(logsFilter.Offset).
 		Size(logsFilter.Size)
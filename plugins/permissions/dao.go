@@ -12,39 +12,50 @@ import (
 )
 
 type elasticsearch struct {
-	indexName string
-	mapping   string
+	indexName     string
+	usageIndex    string
+	templateIndex string
+	mapping       string
 }
 
-func initPlugin(indexName, mapping string) (*elasticsearch, error) {
+func initPlugin(indexName, usageIndex, templateIndex, mapping string) (*elasticsearch, error) {
 	ctx := context.Background()
 
-	es := &elasticsearch{indexName, mapping}
+	es := &elasticsearch{indexName, usageIndex, templateIndex, mapping}
 
+	for _, name := range []string{indexName, usageIndex, templateIndex} {
+		if err := es.createIndex(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	return es, nil
+}
+
+func (es *elasticsearch) createIndex(ctx context.Context, indexName string) error {
 	// Check if the meta index already exists
 	exists, err := util.GetClient7().IndexExists(indexName).
 		Do(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("%s: error while checking if index already exists: %v", logTag, err)
+		return fmt.Errorf("%s: error while checking if index already exists: %v", logTag, err)
 	}
 	if exists {
 		log.Println(logTag, ": index named", indexName, "already exists, skipping...")
-		return es, nil
+		return nil
 	}
 
 	replicas := util.GetReplicas()
-	settings := fmt.Sprintf(mapping, util.HiddenIndexSettings(), replicas)
+	settings := fmt.Sprintf(es.mapping, util.HiddenIndexSettings(), replicas)
 
 	// Create a new meta index
 	_, err = util.GetClient7().CreateIndex(indexName).
 		Body(settings).
 		Do(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("%s: error while creating index named %s: %v", logTag, indexName, err)
+		return fmt.Errorf("%s: error while creating index named %s: %v", logTag, indexName, err)
 	}
 
 	log.Println(logTag, ": successfully created index named", indexName)
-	return es, nil
+	return nil
 }
 
 func applyExpiredField(data []byte) ([]byte, error) {
@@ -158,3 +169,60 @@ func (es *elasticsearch) getRawRolePermission(ctx context.Context, role string)
 		return es.getRawRolePermissionEs7(ctx, role)
 	}
 }
+
+func (es *elasticsearch) getUsage(ctx context.Context, ids []string) (map[string]int64, error) {
+	switch util.GetVersion() {
+	case 6:
+		return es.getUsageEs6(ctx, ids)
+	default:
+		return es.getUsageEs7(ctx, ids)
+	}
+}
+
+// incrementUsage adds the counts of the records to the usage index, it returns the ids
+// of the incremented records along with an error if any of the records failed.
+func (es *elasticsearch) incrementUsage(ctx context.Context, records []usageRecord) ([]string, error) {
+	switch util.GetVersion() {
+	case 6:
+		return es.incrementUsageEs6(ctx, records)
+	default:
+		return es.incrementUsageEs7(ctx, records)
+	}
+}
+
+func (es *elasticsearch) getTemplate(ctx context.Context, name string) (*template, error) {
+	switch util.GetVersion() {
+	case 6:
+		return es.getTemplateEs6(ctx, name)
+	default:
+		return es.getTemplateEs7(ctx, name)
+	}
+}
+
+func (es *elasticsearch) getTemplates(ctx context.Context) ([]template, error) {
+	switch util.GetVersion() {
+	case 6:
+		return es.getTemplatesEs6(ctx)
+	default:
+		return es.getTemplatesEs7(ctx)
+	}
+}
+
+func (es *elasticsearch) putTemplate(ctx context.Context, t template) error {
+	_, err := util.GetClient7().Index().
+		Refresh("wait_for").
+		Index(es.templateIndex).
+		Id(t.Name).
+		BodyJson(t).
+		Do(ctx)
+	return err
+}
+
+func (es *elasticsearch) deleteTemplate(ctx context.Context, name string) error {
+	_, err := util.GetClient7().Delete().
+		Refresh("wait_for").
+		Index(es.templateIndex).
+		Id(name).
+		Do(ctx)
+	return err
+}
@@ -132,3 +132,93 @@ func (es *elasticsearch) patchPermissionEs6(ctx context.Context, username string
 
 	return src, nil
 }
+
+func (es *elasticsearch) getUsageEs6(ctx context.Context, ids []string) (map[string]int64, error) {
+	request := util.GetClient6().Mget()
+	for _, id := range ids {
+		request.Add(es6.NewMultiGetItem().Index(es.usageIndex).Type(typeName).Id(id))
+	}
+	resp, err := request.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]int64)
+	for _, doc := range resp.Docs {
+		if !doc.Found || doc.Source == nil {
+			continue
+		}
+		var record usageRecord
+		err := json.Unmarshal(*doc.Source, &record)
+		if err != nil {
+			return nil, err
+		}
+		usage[doc.Id] = record.Count
+	}
+	return usage, nil
+}
+
+func (es *elasticsearch) incrementUsageEs6(ctx context.Context, records []usageRecord) ([]string, error) {
+	request := util.GetClient6().Bulk()
+	for _, record := range records {
+		request.Add(es6.NewBulkUpdateRequest().
+			Index(es.usageIndex).
+			Type(typeName).
+			Id(record.ID).
+			Script(es6.NewScript(incrementUsageScript).Param("count", record.Count)).
+			Upsert(record))
+	}
+	resp, err := request.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, item := range resp.Succeeded() {
+		ids = append(ids, item.Id)
+	}
+	if resp.Errors {
+		return ids, fmt.Errorf("error while updating the usage of %d quotas", len(resp.Failed()))
+	}
+	return ids, nil
+}
+
+func (es *elasticsearch) getTemplateEs6(ctx context.Context, name string) (*template, error) {
+	response, err := util.GetClient6().Get().
+		Index(es.templateIndex).
+		Type(typeName).
+		Id(name).
+		FetchSource(true).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var t template
+	err = json.Unmarshal(*response.Source, &t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (es *elasticsearch) getTemplatesEs6(ctx context.Context) ([]template, error) {
+	resp, err := util.GetClient6().Search().
+		Index(es.templateIndex).
+		Query(es6.NewMatchAllQuery()).
+		Size(1000).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []template{}
+	for _, hit := range resp.Hits.Hits {
+		var t template
+		err := json.Unmarshal(*hit.Source, &t)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
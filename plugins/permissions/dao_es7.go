@@ -131,3 +131,91 @@ func (es *elasticsearch) patchPermissionEs7(ctx context.Context, username string
 
 	return src, nil
 }
+
+func (es *elasticsearch) getUsageEs7(ctx context.Context, ids []string) (map[string]int64, error) {
+	request := util.GetClient7().Mget()
+	for _, id := range ids {
+		request.Add(es7.NewMultiGetItem().Index(es.usageIndex).Id(id))
+	}
+	resp, err := request.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]int64)
+	for _, doc := range resp.Docs {
+		if !doc.Found {
+			continue
+		}
+		var record usageRecord
+		err := json.Unmarshal(doc.Source, &record)
+		if err != nil {
+			return nil, err
+		}
+		usage[doc.Id] = record.Count
+	}
+	return usage, nil
+}
+
+func (es *elasticsearch) incrementUsageEs7(ctx context.Context, records []usageRecord) ([]string, error) {
+	request := util.GetClient7().Bulk()
+	for _, record := range records {
+		request.Add(es7.NewBulkUpdateRequest().
+			Index(es.usageIndex).
+			Id(record.ID).
+			Script(es7.NewScript(incrementUsageScript).Param("count", record.Count)).
+			Upsert(record))
+	}
+	resp, err := request.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, item := range resp.Succeeded() {
+		ids = append(ids, item.Id)
+	}
+	if resp.Errors {
+		return ids, fmt.Errorf("error while updating the usage of %d quotas", len(resp.Failed()))
+	}
+	return ids, nil
+}
+
+func (es *elasticsearch) getTemplateEs7(ctx context.Context, name string) (*template, error) {
+	response, err := util.GetClient7().Get().
+		Index(es.templateIndex).
+		Id(name).
+		FetchSource(true).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var t template
+	err = json.Unmarshal(response.Source, &t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (es *elasticsearch) getTemplatesEs7(ctx context.Context) ([]template, error) {
+	resp, err := util.GetClient7().Search().
+		Index(es.templateIndex).
+		Query(es7.NewMatchAllQuery()).
+		Size(1000).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []template{}
+	for _, hit := range resp.Hits.Hits {
+		var t template
+		err := json.Unmarshal(hit.Source, &t)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
@@ -0,0 +1,261 @@
+package permissions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/middleware/classify"
+	"github.com/appbaseio/reactivesearch-api/middleware/validate"
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/index"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/util"
+	"github.com/gorilla/mux"
+)
+
+// explainRequest describes the request to be explained for a permission.
+type explainRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	IP      string            `json:"ip,omitempty"`
+}
+
+// explainStep is the outcome of a check performed on the request.
+type explainStep struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Status int    `json:"status,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type explainRoute struct {
+	Name     string            `json:"name"`
+	Category category.Category `json:"category"`
+	ACL      *acl.ACL          `json:"acl,omitempty"`
+	Op       op.Operation      `json:"op"`
+	Indices  []string          `json:"indices"`
+}
+
+type explainResponse struct {
+	Username   string        `json:"username"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	Allowed    bool          `json:"allowed"`
+	RejectedBy string        `json:"rejected_by,omitempty"`
+	Route      *explainRoute `json:"route,omitempty"`
+	Steps      []explainStep `json:"steps"`
+}
+
+func (r *explainResponse) addStep(step explainStep) {
+	if !step.Passed && r.RejectedBy == "" {
+		r.RejectedBy = step.Name
+	}
+	r.Steps = append(r.Steps, step)
+}
+
+// explainMiddleware runs a single middleware on the request, the request as
+// modified by the middleware is returned if the middleware passes it on.
+func explainMiddleware(name string, m middleware.Middleware, req *http.Request) (explainStep, *http.Request) {
+	step := explainStep{Name: name}
+	next := req
+	w := httptest.NewRecorder()
+	m(func(w http.ResponseWriter, r *http.Request) {
+		step.Passed = true
+		next = r
+	})(w, req)
+	if !step.Passed {
+		step.Status = w.Code
+		step.Reason = rejectionReason(w.Body.Bytes())
+	}
+	return step, next
+}
+
+// rejectionReason extracts the message of the response written by a middleware.
+func rejectionReason(body []byte) string {
+	var res struct {
+		Message string `json:"message"`
+		Error   struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+	if res.Error.Message != "" {
+		return res.Error.Message
+	}
+	return res.Message
+}
+
+// newExplainRequest returns the request to be explained, it is never served.
+func newExplainRequest(body explainRequest) (*http.Request, error) {
+	if body.Method == "" {
+		return nil, fmt.Errorf(`"method" is required`)
+	}
+	if !strings.HasPrefix(body.Path, "/") {
+		return nil, fmt.Errorf(`"path" must start with "/"`)
+	}
+	req, err := http.NewRequest(strings.ToUpper(body.Method), body.Path, nil)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid "path": %v`, err)
+	}
+	for key, value := range body.Headers {
+		req.Header.Set(key, value)
+	}
+	if body.IP != "" {
+		if net.ParseIP(body.IP) == nil {
+			return nil, fmt.Errorf(`invalid "ip": %s`, body.IP)
+		}
+		req.RemoteAddr = net.JoinHostPort(body.IP, "0")
+	}
+	// the checks of the explained request must not be recorded
+	req.Header.Set("X-Enable-Telemetry", "false")
+	return req, nil
+}
+
+// explain evaluates the checks the request would go through when made with the
+// permission. The rate limits aren't evaluated and the quotas aren't consumed.
+func (p *permissions) explain(ctx context.Context, reqPermission *permission.Permission, req *http.Request) *explainResponse {
+	res := &explainResponse{
+		Username: reqPermission.Username,
+		Method:   req.Method,
+		Path:     req.URL.RequestURI(),
+		Steps:    []explainStep{},
+	}
+
+	route, ok := classify.Classify(req)
+	if !ok {
+		res.addStep(explainStep{
+			Name:   "classify",
+			Status: http.StatusNotFound,
+			Reason: fmt.Sprintf("no route matches %s %s", req.Method, res.Path),
+		})
+		return res
+	}
+	res.addStep(explainStep{Name: "classify", Passed: true})
+
+	req = mux.SetURLVars(req, route.Vars)
+	reqCredential := credential.Permission
+	reqCtx := credential.NewContext(req.Context(), reqCredential)
+	reqCtx = permission.NewContext(reqCtx, reqPermission)
+	reqCtx = category.NewContext(reqCtx, &route.Category)
+	reqCtx = op.NewContext(reqCtx, &route.Op)
+	if route.ACL != nil {
+		reqCtx = acl.NewContext(reqCtx, route.ACL)
+	}
+	req = req.WithContext(reqCtx)
+
+	_, req = explainMiddleware("indices", classify.Indices(), req)
+	indices, _ := index.FromContext(req.Context())
+	res.Route = &explainRoute{
+		Name:     route.Name,
+		Category: route.Category,
+		ACL:      route.ACL,
+		Op:       route.Op,
+		Indices:  indices,
+	}
+
+	steps := []struct {
+		name string
+		m    middleware.Middleware
+	}{
+		{"sources", validate.Sources()},
+		{"referers", validate.Referers()},
+		{"index_access", validate.Indices()},
+		{"category", validate.Category()},
+		{"acl", validate.ACL()},
+		{"operation", validate.Operation()},
+		{"expiry", validate.PermissionExpiry()},
+		{"schedule", validate.PermissionSchedule()},
+	}
+	for _, s := range steps {
+		if s.name == "acl" && route.ACL == nil {
+			continue
+		}
+		// the later checks are evaluated with the request as modified by the
+		// passed ones, e.g. with the index rules applied to the permission
+		step, next := explainMiddleware(s.name, s.m, req)
+		res.addStep(step)
+		req = next
+	}
+	res.addStep(p.explainQuota(ctx, reqPermission, route))
+
+	res.Allowed = res.RejectedBy == ""
+	return res
+}
+
+// explainQuota checks whether a quota applying to the request is already exhausted.
+func (p *permissions) explainQuota(ctx context.Context, reqPermission *permission.Permission, route *classify.Route) explainStep {
+	step := explainStep{Name: "quota", Passed: true}
+	statuses, err := p.Usage(ctx, reqPermission)
+	if err != nil {
+		log.Errorln(logTag, ":", err)
+		step.Passed = false
+		step.Status = http.StatusServiceUnavailable
+		step.Reason = "unable to verify the quota usage"
+		return step
+	}
+	for _, status := range statuses {
+		if !status.AppliesTo(route.Category) {
+			continue
+		}
+		if status.Metric == permission.Documents && route.Op != op.Write {
+			continue
+		}
+		if status.Remaining <= 0 {
+			step.Passed = false
+			step.Status = http.StatusTooManyRequests
+			step.Reason = fmt.Sprintf(`quota "%s" of %d exceeded`, status.Key(), status.Limit)
+			break
+		}
+	}
+	return step
+}
+
+func (p *permissions) explainPermission() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		username := mux.Vars(req)["username"]
+
+		var body explainRequest
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			msg := "can't parse request body"
+			log.Errorln(logTag, ":", msg, ":", err)
+			util.WriteBackError(w, msg, http.StatusBadRequest)
+			return
+		}
+		explainReq, err := newExplainRequest(body)
+		if err != nil {
+			util.WriteBackError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reqPermission, err := p.es.getPermission(req.Context(), username)
+		if err != nil {
+			msg := fmt.Sprintf(`permission with "username"="%s" not found`, username)
+			log.Errorln(logTag, ":", msg, ":", err)
+			util.WriteBackError(w, msg, http.StatusNotFound)
+			return
+		}
+
+		raw, err := json.Marshal(p.explain(req.Context(), reqPermission, explainReq))
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error while marshalling the explanation", http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, raw, http.StatusOK)
+	}
+}
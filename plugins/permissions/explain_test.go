@@ -0,0 +1,90 @@
+package permissions
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appbaseio/reactivesearch-api/middleware/classify"
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func init() {
+	classify.RegisterClassifier(func(req *http.Request) (*classify.Route, bool) {
+		parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+		if len(parts) != 2 || parts[1] != "_search" {
+			return nil, false
+		}
+		searchACL := acl.Search
+		return &classify.Route{
+			Name:     "search",
+			Category: category.Search,
+			ACL:      &searchACL,
+			Op:       op.Read,
+			Vars:     map[string]string{"index": parts[0]},
+		}, true
+	})
+}
+
+func TestExplain(t *testing.T) {
+	p := &permissions{}
+	reqPermission := &permission.Permission{
+		Username:   "storefront",
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		TTL:        -1,
+		Sources:    []string{"10.0.0.0/8"},
+		Referers:   []string{"*"},
+		Indices:    []string{"products"},
+		Categories: []category.Category{category.Search},
+		ACLs:       []acl.ACL{acl.Search},
+		Ops:        []op.Operation{op.Read},
+	}
+	explain := func(body explainRequest) *explainResponse {
+		req, err := newExplainRequest(body)
+		So(err, ShouldBeNil)
+		return p.explain(req.Context(), reqPermission, req)
+	}
+
+	Convey("should validate the explained request", t, func() {
+		_, err := newExplainRequest(explainRequest{Path: "/products/_search"})
+		So(err, ShouldNotBeNil)
+		_, err = newExplainRequest(explainRequest{Method: "POST", Path: "products/_search"})
+		So(err, ShouldNotBeNil)
+		_, err = newExplainRequest(explainRequest{Method: "POST", Path: "/products/_search", IP: "localhost"})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("should allow the request", t, func() {
+		res := explain(explainRequest{Method: "post", Path: "/products/_search", IP: "10.1.2.3"})
+		So(res.Allowed, ShouldBeTrue)
+		So(res.RejectedBy, ShouldBeEmpty)
+		So(res.Route.Indices, ShouldResemble, []string{"products"})
+		for _, step := range res.Steps {
+			So(step.Passed, ShouldBeTrue)
+		}
+	})
+	Convey("should report the checks rejecting the request", t, func() {
+		res := explain(explainRequest{Method: "POST", Path: "/orders/_search", IP: "192.168.1.1"})
+		So(res.Allowed, ShouldBeFalse)
+		So(res.RejectedBy, ShouldEqual, "sources")
+		var rejected []string
+		for _, step := range res.Steps {
+			if !step.Passed {
+				rejected = append(rejected, step.Name)
+				So(step.Status, ShouldEqual, http.StatusUnauthorized)
+				So(step.Reason, ShouldNotBeEmpty)
+			}
+		}
+		So(rejected, ShouldResemble, []string{"sources", "index_access"})
+	})
+	Convey("should report the requests not matching any route", t, func() {
+		res := explain(explainRequest{Method: "GET", Path: "/products/_unknown/route", IP: "10.1.2.3"})
+		So(res.Allowed, ShouldBeFalse)
+		So(res.RejectedBy, ShouldEqual, "classify")
+		So(res.Route, ShouldBeNil)
+	})
+}
@@ -33,6 +33,39 @@ func (p *permissions) getPermission() http.HandlerFunc {
 	}
 }
 
+func (p *permissions) getPermissionUsage() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+		username := vars["username"]
+
+		reqPermission, err := p.es.getPermission(req.Context(), username)
+		if err != nil {
+			msg := fmt.Sprintf(`permission with "username"="%s" not found`, username)
+			log.Errorln(logTag, ":", msg, ":", err)
+			util.WriteBackError(w, msg, http.StatusNotFound)
+			return
+		}
+
+		quotas, err := p.Usage(req.Context(), reqPermission)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "unable to fetch the permission usage", http.StatusServiceUnavailable)
+			return
+		}
+		response := map[string]interface{}{
+			"username": username,
+			"quotas":   quotas,
+		}
+		raw, err := json.Marshal(response)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error while marshalling the permission usage", http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, raw, http.StatusOK)
+	}
+}
+
 func (p *permissions) postPermission(opts ...permission.Options) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		creator, _, _ := req.BasicAuth()
@@ -65,48 +98,7 @@ func (p *permissions) postPermission(opts ...permission.Options) http.HandlerFun
 			return
 		}
 
-		if permissionBody.Owner != "" {
-			permissionOptions = append(permissionOptions, permission.SetOwner(permissionBody.Owner))
-		}
-		if permissionBody.Ops != nil {
-			permissionOptions = append(permissionOptions, permission.SetOps(permissionBody.Ops))
-		}
-		if permissionBody.Role != "" {
-			permissionOptions = append(permissionOptions, permission.SetRole(permissionBody.Role))
-		}
-		if permissionBody.Categories != nil {
-			permissionOptions = append(permissionOptions, permission.SetCategories(permissionBody.Categories))
-		}
-		if permissionBody.ACLs != nil {
-			permissionOptions = append(permissionOptions, permission.SetACLs(permissionBody.ACLs))
-		}
-		if permissionBody.Sources != nil {
-			permissionOptions = append(permissionOptions, permission.SetSources(permissionBody.Sources))
-		}
-		if permissionBody.Referers != nil {
-			permissionOptions = append(permissionOptions, permission.SetReferers(permissionBody.Referers))
-		}
-		if permissionBody.Includes != nil {
-			permissionOptions = append(permissionOptions, permission.SetIncludes(permissionBody.Includes))
-		}
-		if permissionBody.Excludes != nil {
-			permissionOptions = append(permissionOptions, permission.SetExcludes(permissionBody.Excludes))
-		}
-		if permissionBody.Indices != nil {
-			permissionOptions = append(permissionOptions, permission.SetIndices(permissionBody.Indices))
-		}
-		if permissionBody.Limits != nil {
-			permissionOptions = append(permissionOptions, permission.SetLimits(permissionBody.Limits, *reqUser.IsAdmin))
-		}
-		if permissionBody.Description != "" {
-			permissionOptions = append(permissionOptions, permission.SetDescription(permissionBody.Description))
-		}
-		if permissionBody.ReactiveSearchConfig != nil {
-			permissionOptions = append(permissionOptions, permission.SetReactivesearchConfig(*permissionBody.ReactiveSearchConfig))
-		}
-		if permissionBody.TTL != 0 {
-			permissionOptions = append(permissionOptions, permission.SetTTL(permissionBody.TTL))
-		}
+		permissionOptions = append(permissionOptions, permissionOptionsFor(permissionBody, *reqUser.IsAdmin)...)
 
 		var newPermission *permission.Permission
 		if *reqUser.IsAdmin {
@@ -158,6 +150,72 @@ func (p *permissions) postPermission(opts ...permission.Options) http.HandlerFun
 	}
 }
 
+// permissionOptionsFor returns the options to create a permission with the fields set in the body.
+func permissionOptionsFor(permissionBody permission.Permission, isAdmin bool) []permission.Options {
+	var opts []permission.Options
+	if permissionBody.Owner != "" {
+		opts = append(opts, permission.SetOwner(permissionBody.Owner))
+	}
+	if permissionBody.Ops != nil {
+		opts = append(opts, permission.SetOps(permissionBody.Ops))
+	}
+	if permissionBody.Role != "" {
+		opts = append(opts, permission.SetRole(permissionBody.Role))
+	}
+	if permissionBody.Categories != nil {
+		opts = append(opts, permission.SetCategories(permissionBody.Categories))
+	}
+	if permissionBody.ACLs != nil {
+		opts = append(opts, permission.SetACLs(permissionBody.ACLs))
+	}
+	if permissionBody.Sources != nil {
+		opts = append(opts, permission.SetSources(permissionBody.Sources))
+	}
+	if permissionBody.Referers != nil {
+		opts = append(opts, permission.SetReferers(permissionBody.Referers))
+	}
+	if permissionBody.Includes != nil {
+		opts = append(opts, permission.SetIncludes(permissionBody.Includes))
+	}
+	if permissionBody.Excludes != nil {
+		opts = append(opts, permission.SetExcludes(permissionBody.Excludes))
+	}
+	if permissionBody.Indices != nil {
+		opts = append(opts, permission.SetIndices(permissionBody.Indices))
+	}
+	if permissionBody.Limits != nil {
+		opts = append(opts, permission.SetLimits(permissionBody.Limits, isAdmin))
+	}
+	if permissionBody.Description != "" {
+		opts = append(opts, permission.SetDescription(permissionBody.Description))
+	}
+	if permissionBody.ReactiveSearchConfig != nil {
+		opts = append(opts, permission.SetReactivesearchConfig(*permissionBody.ReactiveSearchConfig))
+	}
+	if permissionBody.TTL != 0 {
+		opts = append(opts, permission.SetTTL(permissionBody.TTL))
+	}
+	if permissionBody.Filter != nil {
+		opts = append(opts, permission.SetFilter(permissionBody.Filter))
+	}
+	if permissionBody.IndexRules != nil {
+		opts = append(opts, permission.SetIndexRules(permissionBody.IndexRules))
+	}
+	if permissionBody.FieldMasks != nil {
+		opts = append(opts, permission.SetFieldMasks(permissionBody.FieldMasks))
+	}
+	if permissionBody.Schedule != nil {
+		opts = append(opts, permission.SetSchedule(permissionBody.Schedule))
+	}
+	if permissionBody.Quotas != nil {
+		opts = append(opts, permission.SetQuotas(permissionBody.Quotas))
+	}
+	if permissionBody.QueryPolicy != nil {
+		opts = append(opts, permission.SetQueryPolicy(permissionBody.QueryPolicy))
+	}
+	return opts
+}
+
 func (p *permissions) patchPermission() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		vars := mux.Vars(req)
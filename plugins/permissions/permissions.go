@@ -1,9 +1,12 @@
 package permissions
 
 import (
+	"fmt"
 	"os"
 	"sync"
+	"time"
 
+	"github.com/robfig/cron"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/appbaseio/reactivesearch-api/middleware"
@@ -17,6 +20,12 @@ const (
 	typeName                  = "_doc"
 	envEsURL                  = "ES_CLUSTER_URL"
 	envPermissionEsIndex      = "PERMISSIONS_ES_INDEX"
+	defaultUsageEsIndex       = ".permissions_usage"
+	envUsageEsIndex           = "PERMISSIONS_USAGE_ES_INDEX"
+	defaultTemplatesEsIndex   = ".permission_templates"
+	envTemplatesEsIndex       = "PERMISSION_TEMPLATES_ES_INDEX"
+	defaultUsageFlushInterval = "10s"
+	envUsageFlushInterval     = "QUOTA_FLUSH_INTERVAL"
 	settings                  = `{ "settings" : { %s "index.number_of_shards" : 1, "index.number_of_replicas" : %d } }`
 )
 
@@ -48,9 +57,19 @@ func (p *permissions) InitFunc() error {
 		indexName = defaultPermissionsEsIndex
 	}
 
+	usageIndex := os.Getenv(envUsageEsIndex)
+	if usageIndex == "" {
+		usageIndex = defaultUsageEsIndex
+	}
+
+	templateIndex := os.Getenv(envTemplatesEsIndex)
+	if templateIndex == "" {
+		templateIndex = defaultTemplatesEsIndex
+	}
+
 	// initialize the dao
 	var err error
-	p.es, err = initPlugin(indexName, settings)
+	p.es, err = initPlugin(indexName, usageIndex, templateIndex, settings)
 	if err != nil {
 		return err
 	}
@@ -61,6 +80,18 @@ func (p *permissions) InitFunc() error {
 	}
 	util.AddSyncScript(s)
 
+	// Write the quota usage to the usage index periodically
+	flushInterval := os.Getenv(envUsageFlushInterval)
+	if flushInterval == "" {
+		flushInterval = defaultUsageFlushInterval
+	}
+	if _, err := time.ParseDuration(flushInterval); err != nil {
+		return fmt.Errorf("%s: invalid value for %s: %v", logTag, envUsageFlushInterval, err)
+	}
+	cronjob := cron.New()
+	cronjob.AddFunc("@every "+flushInterval, p.flushUsage)
+	cronjob.Start()
+
 	return nil
 }
 
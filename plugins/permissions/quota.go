@@ -0,0 +1,377 @@
+package permissions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
+	"github.com/appbaseio/reactivesearch-api/util"
+)
+
+const incrementUsageScript = "ctx._source.count += params.count"
+
+// usageRecord is the usage of a permission quota for a period, as stored in the usage index.
+type usageRecord struct {
+	ID          string                 `json:"-"`
+	Username    string                 `json:"username"`
+	Quota       string                 `json:"quota"`
+	Metric      permission.QuotaMetric `json:"metric"`
+	Period      permission.QuotaPeriod `json:"period"`
+	PeriodStart time.Time              `json:"period_start"`
+	Count       int64                  `json:"count"`
+}
+
+// QuotaStatus is the usage of a quota in the current period.
+type QuotaStatus struct {
+	permission.Quota
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+type usageCounter struct {
+	record  usageRecord
+	pending int64
+	// reserved is the usage of the requests in flight, it is only added to
+	// the pending usage once the request succeeds
+	reserved int64
+	loaded   bool
+	resetAt  time.Time
+}
+
+// usageTracker counts the usage of the quotas in memory, the counts are
+// periodically flushed to the usage index which is the source of truth
+// shared by all the instances.
+type usageTracker struct {
+	sync.Mutex
+	counters map[string]*usageCounter
+}
+
+var usage = &usageTracker{counters: make(map[string]*usageCounter)}
+
+func usageID(username string, quota permission.Quota, now time.Time) string {
+	return fmt.Sprintf("%s:%s:%s", username, quota.Key(), quota.PeriodStart(now).Format("2006-01-02"))
+}
+
+// counter returns the counter of the quota for the current period, the
+// persisted usage is fetched from the usage index if not loaded yet.
+func (p *permissions) counter(ctx context.Context, username string, quota permission.Quota, now time.Time) (*usageCounter, error) {
+	id := usageID(username, quota, now)
+	usage.Lock()
+	c, ok := usage.counters[id]
+	if !ok {
+		c = &usageCounter{
+			record: usageRecord{
+				ID:          id,
+				Username:    username,
+				Quota:       quota.Key(),
+				Metric:      quota.Metric,
+				Period:      quota.Period,
+				PeriodStart: quota.PeriodStart(now),
+			},
+			resetAt: quota.PeriodEnd(now),
+		}
+		usage.counters[id] = c
+	}
+	loaded := c.loaded
+	usage.Unlock()
+
+	if !loaded {
+		counts, err := p.es.getUsage(ctx, []string{id})
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch the quota usage: %v", err)
+		}
+		usage.Lock()
+		if !c.loaded {
+			c.record.Count = counts[id]
+			c.loaded = true
+		}
+		usage.Unlock()
+	}
+	return c, nil
+}
+
+// Usage returns the usage of the quotas of a permission in the current periods.
+func (p *permissions) Usage(ctx context.Context, reqPermission *permission.Permission) ([]QuotaStatus, error) {
+	now := time.Now()
+	statuses := []QuotaStatus{}
+	for _, quota := range reqPermission.Quotas {
+		c, err := p.counter(ctx, reqPermission.Username, quota, now)
+		if err != nil {
+			return nil, err
+		}
+		usage.Lock()
+		used := c.record.Count + c.pending + c.reserved
+		usage.Unlock()
+		statuses = append(statuses, newQuotaStatus(quota, used, c.resetAt))
+	}
+	return statuses, nil
+}
+
+func newQuotaStatus(quota permission.Quota, used int64, resetAt time.Time) QuotaStatus {
+	remaining := quota.Limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return QuotaStatus{
+		Quota:     quota,
+		Used:      used,
+		Remaining: remaining,
+		ResetAt:   resetAt,
+	}
+}
+
+// flushUsage writes the pending usage to the usage index and refreshes the
+// counters with the usage recorded by the other instances.
+func (p *permissions) flushUsage() {
+	ctx := context.Background()
+	now := time.Now()
+	var records []usageRecord
+	usage.Lock()
+	for id, c := range usage.counters {
+		if c.pending > 0 {
+			record := c.record
+			record.Count = c.pending
+			records = append(records, record)
+		} else if !now.Before(c.resetAt) {
+			// the period is over and there is nothing left to flush
+			delete(usage.counters, id)
+		}
+	}
+	usage.Unlock()
+	if len(records) == 0 {
+		return
+	}
+
+	// only the records that were incremented are removed from the pending usage,
+	// the failed ones are retried with the next flush
+	ids, err := p.es.incrementUsage(ctx, records)
+	if err != nil {
+		log.Errorln(logTag, ": unable to update the quota usage:", err)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	flushed := make(map[string]bool)
+	for _, id := range ids {
+		flushed[id] = true
+	}
+	counts, err := p.es.getUsage(ctx, ids)
+	usage.Lock()
+	defer usage.Unlock()
+	for _, record := range records {
+		c, ok := usage.counters[record.ID]
+		if !ok || !flushed[record.ID] {
+			continue
+		}
+		c.pending -= record.Count
+		if err == nil {
+			c.record.Count = counts[record.ID]
+		} else {
+			c.record.Count += record.Count
+		}
+	}
+	if err != nil {
+		log.Errorln(logTag, ": unable to fetch the quota usage:", err)
+	}
+}
+
+// Quota returns a middleware that enforces the usage quotas of a permission.
+func Quota() middleware.Middleware {
+	return Instance().quota
+}
+
+func (p *permissions) quota(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		reqCredential, err := credential.FromContext(ctx)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if reqCredential != credential.Permission {
+			h(w, req)
+			return
+		}
+
+		errMsg := "An error occurred while validating the quota"
+		reqPermission, err := permission.FromContext(ctx)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			telemetry.WriteBackErrorWithTelemetry(req, w, errMsg, http.StatusInternalServerError)
+			return
+		}
+		if len(reqPermission.Quotas) == 0 {
+			h(w, req)
+			return
+		}
+		reqCategory, err := category.FromContext(ctx)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			telemetry.WriteBackErrorWithTelemetry(req, w, errMsg, http.StatusInternalServerError)
+			return
+		}
+		reqOp, err := op.FromContext(ctx)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			telemetry.WriteBackErrorWithTelemetry(req, w, errMsg, http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+		var quotas []permission.Quota
+		var counters []*usageCounter
+		var amounts []int64
+		docs := int64(-1)
+		for _, quota := range reqPermission.Quotas {
+			if !quota.AppliesTo(*reqCategory) {
+				continue
+			}
+			amount := int64(1)
+			if quota.Metric == permission.Documents {
+				if *reqOp != op.Write {
+					continue
+				}
+				if docs < 0 {
+					docs, err = countDocs(req)
+					if err != nil {
+						log.Errorln(logTag, ":", err)
+						telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusBadRequest)
+						return
+					}
+				}
+				amount = docs
+			}
+			c, err := p.counter(ctx, reqPermission.Username, quota, now)
+			if err != nil {
+				// the usage can't be verified, the request isn't allowed to exceed the quota
+				log.Errorln(logTag, ":", err)
+				telemetry.WriteBackErrorWithTelemetry(req, w, "Unable to verify the quota usage", http.StatusServiceUnavailable)
+				return
+			}
+			quotas = append(quotas, quota)
+			counters = append(counters, c)
+			amounts = append(amounts, amount)
+		}
+		if len(quotas) == 0 {
+			h(w, req)
+			return
+		}
+
+		// the usage of the request is reserved if none of the quotas is exceeded, so
+		// that the concurrent requests can't exceed them, and it is counted once
+		// the request succeeds
+		usage.Lock()
+		var status *QuotaStatus
+		exceeded := false
+		for i, quota := range quotas {
+			used := counters[i].record.Count + counters[i].pending + counters[i].reserved
+			if used+amounts[i] > quota.Limit {
+				s := newQuotaStatus(quota, used, counters[i].resetAt)
+				status, exceeded = &s, true
+				break
+			}
+			s := newQuotaStatus(quota, used+amounts[i], counters[i].resetAt)
+			if status == nil || s.Remaining < status.Remaining {
+				status = &s
+			}
+		}
+		if !exceeded {
+			for i := range quotas {
+				counters[i].reserved += amounts[i]
+			}
+		}
+		usage.Unlock()
+
+		w.Header().Set("X-Quota-Limit", strconv.FormatInt(status.Limit, 10))
+		w.Header().Set("X-Quota-Remaining", strconv.FormatInt(status.Remaining, 10))
+		w.Header().Set("X-Quota-Reset", strconv.FormatInt(status.ResetAt.Unix(), 10))
+		if exceeded {
+			msg := fmt.Sprintf(`quota "%s" of %d exceeded`, status.Key(), status.Limit)
+			util.WriteBackMessage(w, msg, http.StatusTooManyRequests)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, req)
+		succeeded := sw.status < http.StatusMultipleChoices
+		usage.Lock()
+		for i := range quotas {
+			counters[i].reserved -= amounts[i]
+			if succeeded {
+				counters[i].pending += amounts[i]
+			}
+		}
+		usage.Unlock()
+	}
+}
+
+// statusWriter records the status code written by the handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush sends the buffered data to the client, if the wrapped writer supports it.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// countDocs returns the number of documents written by a request, which is
+// the number of actions for the bulk requests.
+func countDocs(req *http.Request) (int64, error) {
+	reqACL, err := acl.FromContext(req.Context())
+	if err != nil || *reqACL != acl.Bulk {
+		return 1, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return 0, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var count int64
+	isSource := false
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if isSource {
+			isSource = false
+			continue
+		}
+		var action map[string]json.RawMessage
+		if err := json.Unmarshal(line, &action); err != nil {
+			return 0, fmt.Errorf("can't parse the bulk body: %v", err)
+		}
+		count++
+		// all the actions except delete are followed by a source
+		_, isDelete := action["delete"]
+		isSource = !isDelete
+	}
+	return count, nil
+}
@@ -0,0 +1,144 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appbaseio/reactivesearch-api/model/acl"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCountDocs(t *testing.T) {
+	Convey("should count the actions of a bulk request", t, func() {
+		body := `{"index":{"_id":"1"}}
+{"title":"one"}
+{"delete":{"_id":"2"}}
+{"update":{"_id":"3"}}
+{"doc":{"title":"three"}}
+`
+		req, _ := http.NewRequest(http.MethodPost, "/_bulk", strings.NewReader(body))
+		bulk := acl.Bulk
+		req = req.WithContext(acl.NewContext(req.Context(), &bulk))
+		count, err := countDocs(req)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 3)
+	})
+	Convey("should count a single document for the other requests", t, func() {
+		req, _ := http.NewRequest(http.MethodPut, "/books/_doc/1", strings.NewReader(`{"title":"one"}`))
+		count, err := countDocs(req)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+	})
+}
+
+// fakeUsageService increments the usage of the records except the failing ones.
+type fakeUsageService struct {
+	permissionService
+	counts      map[string]int64
+	failing     map[string]bool
+	unavailable bool
+}
+
+func (s *fakeUsageService) incrementUsage(ctx context.Context, records []usageRecord) ([]string, error) {
+	var ids []string
+	for _, record := range records {
+		if s.failing[record.ID] {
+			continue
+		}
+		s.counts[record.ID] += record.Count
+		ids = append(ids, record.ID)
+	}
+	if len(ids) < len(records) {
+		return ids, errors.New("bulk failure")
+	}
+	return ids, nil
+}
+
+func (s *fakeUsageService) getUsage(ctx context.Context, ids []string) (map[string]int64, error) {
+	if s.unavailable {
+		return nil, errors.New("usage index unavailable")
+	}
+	counts := make(map[string]int64)
+	for _, id := range ids {
+		counts[id] = s.counts[id]
+	}
+	return counts, nil
+}
+
+func TestFlushUsage(t *testing.T) {
+	Convey("should keep the pending usage of the failed records only", t, func() {
+		counters := usage.counters
+		defer func() { usage.counters = counters }()
+		resetAt := time.Now().Add(time.Hour)
+		usage.counters = map[string]*usageCounter{
+			"ok":     {record: usageRecord{ID: "ok"}, pending: 2, loaded: true, resetAt: resetAt},
+			"failed": {record: usageRecord{ID: "failed"}, pending: 3, loaded: true, resetAt: resetAt},
+		}
+		service := &fakeUsageService{counts: map[string]int64{}, failing: map[string]bool{"failed": true}}
+		p := &permissions{es: service}
+
+		p.flushUsage()
+		So(usage.counters["ok"].pending, ShouldEqual, 0)
+		So(usage.counters["ok"].record.Count, ShouldEqual, 2)
+		So(usage.counters["failed"].pending, ShouldEqual, 3)
+		So(usage.counters["failed"].record.Count, ShouldEqual, 0)
+
+		delete(service.failing, "failed")
+		p.flushUsage()
+		So(usage.counters["ok"].record.Count, ShouldEqual, 2)
+		So(usage.counters["failed"].pending, ShouldEqual, 0)
+		So(usage.counters["failed"].record.Count, ShouldEqual, 3)
+	})
+}
+
+func TestQuota(t *testing.T) {
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/books/_search", nil)
+		reqCategory := category.Search
+		reqOp := op.Read
+		ctx := credential.NewContext(req.Context(), credential.Permission)
+		ctx = permission.NewContext(ctx, &permission.Permission{
+			Username: "foo",
+			Quotas:   []permission.Quota{{Metric: permission.Requests, Period: permission.Day, Limit: 1}},
+		})
+		ctx = category.NewContext(ctx, &reqCategory)
+		ctx = op.NewContext(ctx, &reqOp)
+		return req.WithContext(ctx)
+	}
+	serve := func(p *permissions, status int) int {
+		w := httptest.NewRecorder()
+		p.quota(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(status)
+		})(w, newRequest())
+		return w.Code
+	}
+
+	Convey("should only count the successful requests", t, func() {
+		counters := usage.counters
+		defer func() { usage.counters = counters }()
+		usage.counters = make(map[string]*usageCounter)
+		p := &permissions{es: &fakeUsageService{counts: map[string]int64{}}}
+
+		So(serve(p, http.StatusInternalServerError), ShouldEqual, http.StatusInternalServerError)
+		So(serve(p, http.StatusOK), ShouldEqual, http.StatusOK)
+		So(serve(p, http.StatusOK), ShouldEqual, http.StatusTooManyRequests)
+	})
+
+	Convey("should reject the requests when the usage can't be fetched", t, func() {
+		counters := usage.counters
+		defer func() { usage.counters = counters }()
+		usage.counters = make(map[string]*usageCounter)
+		p := &permissions{es: &fakeUsageService{unavailable: true}}
+
+		So(serve(p, http.StatusOK), ShouldEqual, http.StatusServiceUnavailable)
+	})
+}
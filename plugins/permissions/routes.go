@@ -4,10 +4,22 @@ import (
 	"net/http"
 
 	"github.com/appbaseio/reactivesearch-api/plugins"
+	"github.com/appbaseio/reactivesearch-api/plugins/audit"
+	"github.com/gorilla/mux"
 )
 
 func (p *permissions) routes() []plugins.Route {
 	middleware := (&chain{}).Wrap
+	auditPermission := audit.Recorder(audit.Target{
+		Type: audit.TargetPermission,
+		ID:   permissionID,
+		Load: p.es.getRawPermission,
+	})
+	auditRole := audit.Recorder(audit.Target{
+		Type: audit.TargetRole,
+		ID:   audit.VarID("name"),
+		Load: p.es.getRawRolePermission,
+	})
 	routes := []plugins.Route{
 		{
 			Name:        "Get permission",
@@ -16,25 +28,39 @@ func (p *permissions) routes() []plugins.Route {
 			HandlerFunc: middleware(p.getPermission()),
 			Description: "Returns the permission with {username}",
 		},
+		{
+			Name:        "Get permission usage",
+			Methods:     []string{http.MethodGet},
+			Path:        "/_permission/{username}/usage",
+			HandlerFunc: middleware(p.getPermissionUsage()),
+			Description: "Returns the usage of the quotas of the permission with {username}",
+		},
+		{
+			Name:        "Explain permission access",
+			Methods:     []string{http.MethodPost},
+			Path:        "/_permission/{username}/_explain",
+			HandlerFunc: middleware(p.explainPermission()),
+			Description: "Explains whether a request would be allowed with the permission with {username}",
+		},
 		{
 			Name:        "Create permission",
 			Methods:     []string{http.MethodPost},
 			Path:        "/_permission",
-			HandlerFunc: middleware(p.postPermission()),
+			HandlerFunc: middleware(auditPermission(p.postPermission())),
 			Description: "Creates a new permission",
 		},
 		{
 			Name:        "Patch permission",
 			Methods:     []string{http.MethodPatch},
 			Path:        "/_permission/{username}",
-			HandlerFunc: middleware(p.patchPermission()),
+			HandlerFunc: middleware(auditPermission(p.patchPermission())),
 			Description: "Updates the permission with {username}",
 		},
 		{
 			Name:        "Delete permission",
 			Methods:     []string{http.MethodDelete},
 			Path:        "/_permission/{username}",
-			HandlerFunc: middleware(p.deletePermission()),
+			HandlerFunc: middleware(auditPermission(p.deletePermission())),
 			Description: "Deletes the permission with {username}",
 		},
 		{
@@ -58,13 +84,57 @@ func (p *permissions) routes() []plugins.Route {
 			HandlerFunc: middleware(p.getPermissions()),
 			Description: "Returns all the permissions for a particular index",
 		},
+		{
+			Name:        "Create/Update/Revoke permissions in bulk",
+			Methods:     []string{http.MethodPost},
+			Path:        "/_permissions/_bulk",
+			HandlerFunc: middleware(p.bulkPermissions()),
+			Description: "Creates, updates or revokes the permissions from the permission templates",
+		},
+		{
+			Name:        "Get permission templates",
+			Methods:     []string{http.MethodGet},
+			Path:        "/_permission_templates",
+			HandlerFunc: middleware(p.getTemplates()),
+			Description: "Returns all the permission templates",
+		},
+		{
+			Name:        "Get permission template",
+			Methods:     []string{http.MethodGet},
+			Path:        "/_permission_template/{name}",
+			HandlerFunc: middleware(p.getTemplate()),
+			Description: "Returns the permission template with {name}",
+		},
+		{
+			Name:        "Create/Update permission template",
+			Methods:     []string{http.MethodPut},
+			Path:        "/_permission_template/{name}",
+			HandlerFunc: middleware(p.putTemplate()),
+			Description: "Creates or replaces the permission template with {name}",
+		},
+		{
+			Name:        "Delete permission template",
+			Methods:     []string{http.MethodDelete},
+			Path:        "/_permission_template/{name}",
+			HandlerFunc: middleware(p.deleteTemplate()),
+			Description: "Deletes the permission template with {name}",
+		},
 		{
 			Name:        "Create/Read/Update/Delete permission by role",
 			Methods:     []string{http.MethodPost, http.MethodGet, http.MethodPatch, http.MethodDelete},
 			Path:        "/_role/{name}",
-			HandlerFunc: middleware(p.role()),
+			HandlerFunc: middleware(auditRole(p.role())),
 			Description: "CRUD the permission with role {name}",
 		},
 	}
 	return routes
 }
+
+// permissionID returns the username of the permission from the route, or
+// from the response for the permissions being created.
+func permissionID(req *http.Request, res []byte) string {
+	if username := mux.Vars(req)["username"]; username != "" {
+		return username
+	}
+	return audit.ResponseUsernameID(req, res)
+}
@@ -16,4 +16,10 @@ type permissionService interface {
 	getRawOwnerPermissions(ctx context.Context, owner string) ([]byte, error)
 	getRawRolePermission(ctx context.Context, role string) ([]byte, error)
 	checkRoleExists(ctx context.Context, role string) (bool, error)
+	getUsage(ctx context.Context, ids []string) (map[string]int64, error)
+	incrementUsage(ctx context.Context, records []usageRecord) ([]string, error)
+	getTemplate(ctx context.Context, name string) (*template, error)
+	getTemplates(ctx context.Context) ([]template, error)
+	putTemplate(ctx context.Context, t template) error
+	deleteTemplate(ctx context.Context, name string) error
 }
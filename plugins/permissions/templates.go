@@ -0,0 +1,362 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	"github.com/appbaseio/reactivesearch-api/model/user"
+	"github.com/appbaseio/reactivesearch-api/plugins/audit"
+	"github.com/appbaseio/reactivesearch-api/plugins/auth"
+	"github.com/appbaseio/reactivesearch-api/util"
+	"github.com/gorilla/mux"
+)
+
+const maxBulkItems = 1000
+
+var templateName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// template is a named permission body used to provision the permissions in bulk.
+type template struct {
+	Name       string          `json:"name"`
+	Permission json.RawMessage `json:"permission"`
+	Creator    string          `json:"creator"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// bulkItem creates, updates or revokes a permission, the indices, referers
+// and description override the ones defined by the template.
+type bulkItem struct {
+	Action      string   `json:"action"`
+	Template    string   `json:"template,omitempty"`
+	Username    string   `json:"username,omitempty"`
+	Indices     []string `json:"indices,omitempty"`
+	Referers    []string `json:"referers,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+type bulkRequest struct {
+	Template string     `json:"template"`
+	Items    []bulkItem `json:"items"`
+}
+
+type bulkResult struct {
+	Action     string                 `json:"action"`
+	Username   string                 `json:"username,omitempty"`
+	Status     int                    `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	Permission *permission.Permission `json:"permission,omitempty"`
+}
+
+// templateBody returns the permission body of the template with the overrides of the item.
+func templateBody(t *template, item bulkItem) (permission.Permission, error) {
+	var body permission.Permission
+	if err := json.Unmarshal(t.Permission, &body); err != nil {
+		return body, fmt.Errorf(`can't parse the permission of template "%s": %v`, t.Name, err)
+	}
+	if item.Indices != nil {
+		body.Indices = item.Indices
+	}
+	if item.Referers != nil {
+		body.Referers = item.Referers
+	}
+	if item.Description != "" {
+		body.Description = item.Description
+	}
+	return body, nil
+}
+
+// newPermission creates a permission of the request user with the fields set in the body.
+func newPermission(reqUser *user.User, body permission.Permission) (*permission.Permission, error) {
+	opts := permissionOptionsFor(body, *reqUser.IsAdmin)
+	if *reqUser.IsAdmin {
+		return permission.NewAdmin(reqUser.Username, opts...)
+	}
+	return permission.New(reqUser.Username, opts...)
+}
+
+// syncPermission clears the cached permission on all the machines.
+func syncPermission(method, username string) error {
+	if !util.ShouldProxyToACCAPI() {
+		auth.ClearLocalUser(username)
+		return nil
+	}
+	res, err := util.ProxyACCAPI(util.ProxyConfig{
+		Method: method,
+		URL:    "/_permission/" + username,
+		Body:   nil,
+	})
+	if err != nil {
+		return err
+	}
+	// Failed to update all nodes
+	if res != nil {
+		return fmt.Errorf("error encountered updating permission, status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (p *permissions) getTemplate() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		name := mux.Vars(req)["name"]
+		t, err := p.es.getTemplate(req.Context(), name)
+		if err != nil {
+			msg := fmt.Sprintf(`permission template with "name"="%s" not found`, name)
+			log.Errorln(logTag, ":", msg, ":", err)
+			util.WriteBackError(w, msg, http.StatusNotFound)
+			return
+		}
+		raw, err := json.Marshal(t)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error while marshalling the permission template", http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, raw, http.StatusOK)
+	}
+}
+
+func (p *permissions) getTemplates() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		templates, err := p.es.getTemplates(req.Context())
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "an error occurred while fetching the permission templates", http.StatusInternalServerError)
+			return
+		}
+		raw, err := json.Marshal(templates)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error while marshalling the permission templates", http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, raw, http.StatusOK)
+	}
+}
+
+func (p *permissions) putTemplate() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		name := mux.Vars(req)["name"]
+		if !templateName.MatchString(name) {
+			util.WriteBackError(w, "template name can only contain letters, digits, hyphens and underscores", http.StatusBadRequest)
+			return
+		}
+		reqUser, err := user.FromContext(req.Context())
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			msg := "can't read request body"
+			log.Errorln(logTag, ":", msg, ":", err)
+			util.WriteBackError(w, msg, http.StatusBadRequest)
+			return
+		}
+		var permissionBody permission.Permission
+		err = json.Unmarshal(body, &permissionBody)
+		if err != nil {
+			msg := "can't parse request body"
+			log.Errorln(logTag, ":", msg, ":", err)
+			util.WriteBackError(w, msg, http.StatusBadRequest)
+			return
+		}
+		if permissionBody.Username != "" || permissionBody.Password != "" || permissionBody.Creator != "" || permissionBody.Role != "" {
+			util.WriteBackError(w, "permission template cannot define the username, password, creator or role", http.StatusBadRequest)
+			return
+		}
+		// the template must produce a valid permission
+		if _, err := newPermission(reqUser, permissionBody); err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		t := template{
+			Name:       name,
+			Permission: body,
+			Creator:    reqUser.Username,
+			CreatedAt:  time.Now().Format(time.RFC3339),
+		}
+		if err := p.es.putTemplate(req.Context(), t); err != nil {
+			msg := fmt.Sprintf(`an error occurred while saving the permission template "%s"`, name)
+			log.Errorln(logTag, ":", msg, ":", err)
+			util.WriteBackError(w, msg, http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackMessage(w, fmt.Sprintf(`permission template "%s" saved`, name), http.StatusOK)
+	}
+}
+
+func (p *permissions) deleteTemplate() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		name := mux.Vars(req)["name"]
+		if err := p.es.deleteTemplate(req.Context(), name); err != nil {
+			msg := fmt.Sprintf(`permission template with "name"="%s" not found`, name)
+			log.Errorln(logTag, ":", msg, ":", err)
+			util.WriteBackError(w, msg, http.StatusNotFound)
+			return
+		}
+		util.WriteBackMessage(w, fmt.Sprintf(`permission template "%s" deleted`, name), http.StatusOK)
+	}
+}
+
+func (p *permissions) bulkPermissions() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		reqUser, err := user.FromContext(ctx)
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var body bulkRequest
+		err = json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			msg := "can't parse request body"
+			log.Errorln(logTag, ":", msg, ":", err)
+			util.WriteBackError(w, msg, http.StatusBadRequest)
+			return
+		}
+		if len(body.Items) == 0 || len(body.Items) > maxBulkItems {
+			util.WriteBackError(w, fmt.Sprintf("bulk request must have between 1 and %d items", maxBulkItems), http.StatusBadRequest)
+			return
+		}
+
+		templates := make(map[string]*template)
+		results := make([]bulkResult, len(body.Items))
+		hasErrors := false
+		for i, item := range body.Items {
+			result := p.bulkItem(req, reqUser, body.Template, item, templates)
+			if result.Status != http.StatusOK {
+				hasErrors = true
+			}
+			results[i] = result
+		}
+
+		raw, err := json.Marshal(map[string]interface{}{
+			"errors": hasErrors,
+			"items":  results,
+		})
+		if err != nil {
+			log.Errorln(logTag, ":", err)
+			util.WriteBackError(w, "error while marshalling the bulk response", http.StatusInternalServerError)
+			return
+		}
+		util.WriteBackRaw(w, raw, http.StatusOK)
+	}
+}
+
+// bulkItem executes an item of a bulk request, the templates are cached across the items.
+func (p *permissions) bulkItem(req *http.Request, reqUser *user.User, defaultTemplate string, item bulkItem, templates map[string]*template) bulkResult {
+	ctx := req.Context()
+	result := bulkResult{Action: item.Action, Username: item.Username}
+	fail := func(status int, err error) bulkResult {
+		result.Status = status
+		result.Error = err.Error()
+		return result
+	}
+
+	switch item.Action {
+	case "create", "update", "revoke":
+	default:
+		return fail(http.StatusBadRequest, fmt.Errorf(`invalid action "%s", must be one of "create", "update" or "revoke"`, item.Action))
+	}
+	if item.Action != "create" && item.Username == "" {
+		return fail(http.StatusBadRequest, fmt.Errorf(`"username" is required to %s a permission`, item.Action))
+	}
+	if item.Action == "create" && item.Username != "" {
+		return fail(http.StatusBadRequest, fmt.Errorf(`"username" can't be set to create a permission`))
+	}
+
+	var body permission.Permission
+	if item.Action == "create" || item.Action == "update" {
+		name := item.Template
+		if name == "" {
+			name = defaultTemplate
+		}
+		if name == "" {
+			return fail(http.StatusBadRequest, fmt.Errorf(`"template" is required to %s a permission`, item.Action))
+		}
+		t, ok := templates[name]
+		if !ok {
+			var err error
+			t, err = p.es.getTemplate(ctx, name)
+			if err != nil {
+				return fail(http.StatusNotFound, fmt.Errorf(`permission template with "name"="%s" not found`, name))
+			}
+			templates[name] = t
+		}
+		var err error
+		body, err = templateBody(t, item)
+		if err != nil {
+			return fail(http.StatusInternalServerError, err)
+		}
+	}
+
+	var before []byte
+	if item.Action != "create" {
+		before, _ = p.es.getRawPermission(ctx, item.Username)
+	}
+
+	switch item.Action {
+	case "create":
+		created, err := newPermission(reqUser, body)
+		if err != nil {
+			return fail(http.StatusBadRequest, err)
+		}
+		if _, err := p.es.postPermission(ctx, *created); err != nil {
+			log.Errorln(logTag, ":", err)
+			return fail(http.StatusInternalServerError, fmt.Errorf("an error occurred while creating the permission"))
+		}
+		result.Username = created.Username
+		result.Permission = created
+	case "update":
+		if _, err := newPermission(reqUser, body); err != nil {
+			return fail(http.StatusBadRequest, err)
+		}
+		patch, err := body.GetPatch(false)
+		if err != nil {
+			return fail(http.StatusBadRequest, err)
+		}
+		if _, err := p.es.patchPermission(ctx, item.Username, patch); err != nil {
+			return fail(http.StatusNotFound, fmt.Errorf(`permission with "username"="%s" not found`, item.Username))
+		}
+		if err := syncPermission(http.MethodPatch, item.Username); err != nil {
+			log.Errorln(logTag, ":", err)
+			return fail(http.StatusInternalServerError, err)
+		}
+	case "revoke":
+		if _, err := p.es.deletePermission(ctx, item.Username); err != nil {
+			return fail(http.StatusNotFound, fmt.Errorf(`permission with "username"="%s" not found`, item.Username))
+		}
+		if err := syncPermission(http.MethodDelete, item.Username); err != nil {
+			log.Errorln(logTag, ":", err)
+			return fail(http.StatusInternalServerError, err)
+		}
+	}
+	result.Status = http.StatusOK
+
+	event := audit.Event{Action: audit.Update, TargetType: audit.TargetPermission, Target: result.Username}
+	var after []byte
+	switch item.Action {
+	case "create":
+		event.Action = audit.Create
+		after, _ = p.es.getRawPermission(ctx, result.Username)
+	case "update":
+		after, _ = p.es.getRawPermission(ctx, result.Username)
+	case "revoke":
+		event.Action = audit.Delete
+	}
+	audit.Record(req, event, before, after)
+	return result
+}
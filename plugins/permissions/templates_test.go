@@ -0,0 +1,33 @@
+package permissions
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTemplateBody(t *testing.T) {
+	storefront := &template{
+		Name:       "storefront",
+		Permission: []byte(`{"categories":["search"],"indices":["products"],"referers":["*"],"description":"storefront key"}`),
+	}
+	Convey("should use the permission of the template", t, func() {
+		body, err := templateBody(storefront, bulkItem{Action: "create"})
+		So(err, ShouldBeNil)
+		So(body.Indices, ShouldResemble, []string{"products"})
+		So(body.Referers, ShouldResemble, []string{"*"})
+		So(body.Description, ShouldEqual, "storefront key")
+	})
+	Convey("should apply the overrides of the item", t, func() {
+		body, err := templateBody(storefront, bulkItem{
+			Action:      "create",
+			Indices:     []string{"products-eu"},
+			Referers:    []string{"https://shop.example.eu"},
+			Description: "eu storefront",
+		})
+		So(err, ShouldBeNil)
+		So(body.Indices, ShouldResemble, []string{"products-eu"})
+		So(body.Referers, ShouldResemble, []string{"https://shop.example.eu"})
+		So(body.Description, ShouldEqual, "eu storefront")
+	})
+}
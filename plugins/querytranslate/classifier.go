@@ -0,0 +1,33 @@
+package querytranslate
+
+import (
+	"net/http"
+
+	"github.com/appbaseio/reactivesearch-api/middleware/classify"
+	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/op"
+	"github.com/appbaseio/reactivesearch-api/plugins"
+	"github.com/gorilla/mux"
+)
+
+// newClassifier returns a classifier for the reactivesearch routes served with
+// the middleware chain, the requests are classified the same way classifyCategory
+// and classifyOp do.
+func newClassifier(searchRoutes []plugins.Route) classify.Classifier {
+	router := mux.NewRouter().StrictSlash(true)
+	for _, r := range searchRoutes {
+		router.Methods(r.Methods...).Path(r.Path).HandlerFunc(r.HandlerFunc)
+	}
+	return func(req *http.Request) (*classify.Route, bool) {
+		var match mux.RouteMatch
+		if !router.Match(req, &match) || match.MatchErr != nil {
+			return nil, false
+		}
+		return &classify.Route{
+			Name:     "reactivesearch",
+			Category: category.ReactiveSearch,
+			Op:       op.Read,
+			Vars:     match.Vars,
+		}, true
+	}
+}
@@ -3,14 +3,19 @@ package querytranslate
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/appbaseio/reactivesearch-api/middleware/classify"
+	"github.com/appbaseio/reactivesearch-api/middleware/concurrency"
+	"github.com/appbaseio/reactivesearch-api/model/fieldmask"
 	"github.com/appbaseio/reactivesearch-api/model/index"
+	"github.com/appbaseio/reactivesearch-api/model/permission"
 	"github.com/appbaseio/reactivesearch-api/util"
 	"github.com/buger/jsonparser"
 	"github.com/gorilla/mux"
@@ -31,7 +36,21 @@ func (r *QueryTranslate) search() http.HandlerFunc {
 		defer req.Body.Close()
 		reqURL := "/" + vars["index"] + "/_msearch"
 		start := time.Now()
-		httpRes, err := makeESRequest(ctx, reqURL, http.MethodPost, reqBody)
+		httpRes, err := makeESRequest(ctx, strings.Split(vars["index"], ","), reqURL, http.MethodPost, reqBody)
+		if err == concurrency.ErrShed {
+			concurrency.WriteBackShed(req, w)
+			return
+		}
+		if err == util.ErrMixedClusters {
+			util.WriteBackError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var unavailableErr *util.UpstreamUnavailableError
+		if errors.As(err, &unavailableErr) {
+			w.Header().Set("Retry-After", strconv.FormatInt(unavailableErr.RetryAfterSeconds(), 10))
+			util.WriteBackTypedError(w, "upstream_unavailable", unavailableErr.Error(), http.StatusServiceUnavailable)
+			return
+		}
 		if err != nil {
 			msg := err.Error()
 			log.Errorln(logTag, ":", err)
@@ -57,6 +76,22 @@ func (r *QueryTranslate) search() http.HandlerFunc {
 			util.WriteBackError(w, msg, http.StatusInternalServerError)
 			return
 		}
+		// mask the fields of the permission before the hits are used, so
+		// that the suggestions are extracted from the masked values too
+		reqPermission, err := permission.FromContext(req.Context())
+		if err == nil && httpRes.StatusCode == http.StatusOK && len(reqPermission.FieldMasks) > 0 {
+			response := make(map[string]interface{})
+			err := json.Unmarshal(httpRes.Body, &response)
+			if err == nil {
+				fieldmask.ApplyToResponse(response, reqPermission.FieldMasks)
+				httpRes.Body, err = json.Marshal(response)
+			}
+			if err != nil {
+				log.Errorln(logTag, ":", err)
+				util.WriteBackError(w, "error masking the response fields", http.StatusInternalServerError)
+				return
+			}
+		}
 
 		queryIds := getQueryIds(*rsAPIRequest)
 
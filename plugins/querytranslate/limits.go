@@ -0,0 +1,195 @@
+package querytranslate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+)
+
+// Types of the errors returned when a request exceeds the limits of the reactivesearch config.
+const (
+	MaxSizeExceeded            = "max_size_exceeded"
+	MaxAggregationSizeExceeded = "max_aggregation_size_exceeded"
+	MaxQueriesExceeded         = "max_queries_exceeded"
+	MaxFromExceeded            = "max_from_exceeded"
+	QueryTypeNotAllowed        = "query_type_not_allowed"
+	DataFieldNotAllowed        = "data_field_not_allowed"
+	CustomQueryNotAllowed      = "custom_query_not_allowed"
+	DefaultQueryNotAllowed     = "default_query_not_allowed"
+	MaxHighlightFieldsExceeded = "max_highlight_fields_exceeded"
+	MaxReactDepthExceeded      = "max_react_depth_exceeded"
+)
+
+// limitError is returned when a request exceeds a limit of the reactivesearch config.
+type limitError struct {
+	Type    string
+	Message string
+}
+
+func (e *limitError) Error() string {
+	return e.Message
+}
+
+func newLimitError(errType string, format string, a ...interface{}) *limitError {
+	return &limitError{Type: errType, Message: fmt.Sprintf(format, a...)}
+}
+
+// validateLimits validates the request against the limits of the reactivesearch config.
+func validateLimits(config *permission.ReactiveSearchConfig, body *RSQuery) *limitError {
+	if config.MaxQueries != nil && len(body.Query) > *config.MaxQueries {
+		return newLimitError(MaxQueriesExceeded, "maximum allowed number of queries is %d", *config.MaxQueries)
+	}
+	for _, query := range body.Query {
+		if err := validateQueryLimits(config, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateQueryLimits(config *permission.ReactiveSearchConfig, query Query) *limitError {
+	// Note: Query DSL validation is handled by noss
+	if config.MaxSize != nil {
+		// validate size from defaultQuery if present
+		if size, ok := intFromQuery(query.DefaultQuery, "size"); ok && size > *config.MaxSize {
+			return newLimitError(MaxSizeExceeded, "maximum allowed size is %d", *config.MaxSize)
+		}
+		if query.Size != nil && *query.Size > *config.MaxSize {
+			return newLimitError(MaxSizeExceeded, "maximum allowed size is %d", *config.MaxSize)
+		}
+	}
+
+	if config.MaxAggregationSize != nil {
+		// validate size from defaultQuery if present
+		if query.DefaultQuery != nil {
+			size := getSizeFromQuery(query.DefaultQuery, "size")
+			if size != nil {
+				if sizeAsFloat, ok := (*size).(float64); ok && int(sizeAsFloat) > *config.MaxAggregationSize {
+					return newLimitError(MaxAggregationSizeExceeded, "maximum allowed aggregation size is %d", *config.MaxAggregationSize)
+				}
+			}
+		}
+		if query.AggregationSize != nil && *query.AggregationSize > *config.MaxAggregationSize {
+			return newLimitError(MaxAggregationSizeExceeded, "maximum allowed aggregation size is %d", *config.MaxAggregationSize)
+		}
+	}
+
+	if config.MaxFrom != nil {
+		if from, ok := intFromQuery(query.DefaultQuery, "from"); ok && from > *config.MaxFrom {
+			return newLimitError(MaxFromExceeded, "maximum allowed from is %d", *config.MaxFrom)
+		}
+		if query.From != nil && *query.From > *config.MaxFrom {
+			return newLimitError(MaxFromExceeded, "maximum allowed from is %d", *config.MaxFrom)
+		}
+	}
+
+	if len(config.AllowedQueryTypes) > 0 {
+		allowed := false
+		for _, queryType := range config.AllowedQueryTypes {
+			if queryType == query.Type.String() {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return newLimitError(QueryTypeNotAllowed, `query type "%s" is not allowed, allowed query types are %s`,
+				query.Type.String(), strings.Join(config.AllowedQueryTypes, ", "))
+		}
+	}
+
+	if len(config.AllowedDataFields) > 0 {
+		for _, dataField := range NormalizedDataFields(query.DataField, []float64{}) {
+			if !matchesFieldPatterns(config.AllowedDataFields, dataField.Field) {
+				return newLimitError(DataFieldNotAllowed, `data field "%s" is not allowed`, dataField.Field)
+			}
+		}
+	}
+
+	if config.DisableCustomQuery != nil && *config.DisableCustomQuery && query.CustomQuery != nil {
+		return newLimitError(CustomQueryNotAllowed, "customQuery is not allowed")
+	}
+	if config.DisableDefaultQuery != nil && *config.DisableDefaultQuery && query.DefaultQuery != nil {
+		return newLimitError(DefaultQueryNotAllowed, "defaultQuery is not allowed")
+	}
+
+	if config.MaxHighlightFields != nil {
+		if count := highlightFieldsCount(query); count > *config.MaxHighlightFields {
+			return newLimitError(MaxHighlightFieldsExceeded, "maximum allowed number of highlighted fields is %d", *config.MaxHighlightFields)
+		}
+	}
+
+	if config.MaxReactDepth != nil && query.React != nil {
+		if depth := reactDepth(*query.React); depth > *config.MaxReactDepth {
+			return newLimitError(MaxReactDepthExceeded, "maximum allowed react depth is %d", *config.MaxReactDepth)
+		}
+	}
+	return nil
+}
+
+// intFromQuery returns the integer value of a top level key of the query.
+func intFromQuery(query *map[string]interface{}, key string) (int, bool) {
+	if query == nil {
+		return 0, false
+	}
+	value, ok := (*query)[key].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(value), true
+}
+
+// matchesFieldPatterns checks whether the field or one of its parent fields
+// matches one of the patterns, `*` matches any sequence of characters.
+func matchesFieldPatterns(patterns []string, field string) bool {
+	for _, pattern := range patterns {
+		expr := "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + `(\..+)?$`
+		if matched, _ := regexp.MatchString(expr, field); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightFieldsCount returns the number of fields highlighted by the query,
+// the fields are resolved the same way as the query is translated.
+func highlightFieldsCount(query Query) int {
+	if query.Highlight == nil || !*query.Highlight {
+		return 0
+	}
+	highlightConfig := query.HighlightConfig
+	if highlightConfig == nil {
+		highlightConfig = query.CustomHighlight
+	}
+	if highlightConfig != nil {
+		if fields, ok := (*highlightConfig)["fields"].(map[string]interface{}); ok {
+			return len(fields)
+		}
+	}
+	if len(query.HighlightField) > 0 {
+		return len(query.HighlightField)
+	}
+	return len(NormalizedDataFields(query.DataField, []float64{}))
+}
+
+// reactDepth returns the nesting level of the and/or/not clauses of the react prop.
+func reactDepth(react interface{}) int {
+	depth := 0
+	switch value := react.(type) {
+	case map[string]interface{}:
+		for _, v := range value {
+			if d := reactDepth(v); d > depth {
+				depth = d
+			}
+		}
+		depth++
+	case []interface{}:
+		for _, v := range value {
+			if d := reactDepth(v); d > depth {
+				depth = d
+			}
+		}
+	}
+	return depth
+}
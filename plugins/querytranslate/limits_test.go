@@ -0,0 +1,64 @@
+package querytranslate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/appbaseio/reactivesearch-api/model/permission"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func limitErrorType(config permission.ReactiveSearchConfig, query string) string {
+	var body RSQuery
+	err := json.Unmarshal([]byte(query), &body)
+	So(err, ShouldBeNil)
+	if err := validateLimits(&config, &body); err != nil {
+		return err.Type
+	}
+	return ""
+}
+
+func TestValidateLimits(t *testing.T) {
+	two := 2
+	enabled := true
+	Convey("should limit the sizes", t, func() {
+		config := permission.ReactiveSearchConfig{MaxSize: &two, MaxFrom: &two}
+		So(limitErrorType(config, `{"query":[{"id":"search","size":2,"from":2}]}`), ShouldBeEmpty)
+		So(limitErrorType(config, `{"query":[{"id":"search","size":3}]}`), ShouldEqual, MaxSizeExceeded)
+		So(limitErrorType(config, `{"query":[{"id":"search","defaultQuery":{"size":3}}]}`), ShouldEqual, MaxSizeExceeded)
+		So(limitErrorType(config, `{"query":[{"id":"search","from":3}]}`), ShouldEqual, MaxFromExceeded)
+		So(limitErrorType(config, `{"query":[{"id":"search","defaultQuery":{"from":3}}]}`), ShouldEqual, MaxFromExceeded)
+	})
+	Convey("should limit the number of queries", t, func() {
+		config := permission.ReactiveSearchConfig{MaxQueries: &two}
+		So(limitErrorType(config, `{"query":[{"id":"a"},{"id":"b"}]}`), ShouldBeEmpty)
+		So(limitErrorType(config, `{"query":[{"id":"a"},{"id":"b"},{"id":"c"}]}`), ShouldEqual, MaxQueriesExceeded)
+	})
+	Convey("should restrict the query types and the data fields", t, func() {
+		config := permission.ReactiveSearchConfig{
+			AllowedQueryTypes: []string{"search", "term"},
+			AllowedDataFields: []string{"title", "author_*"},
+		}
+		So(limitErrorType(config, `{"query":[{"id":"a","dataField":["title.search","author_name"]},{"id":"b","type":"term","dataField":"title"}]}`), ShouldBeEmpty)
+		So(limitErrorType(config, `{"query":[{"id":"a","type":"range","dataField":"title"}]}`), ShouldEqual, QueryTypeNotAllowed)
+		So(limitErrorType(config, `{"query":[{"id":"a","dataField":[{"field":"price","weight":2}]}]}`), ShouldEqual, DataFieldNotAllowed)
+		So(limitErrorType(config, `{"query":[{"id":"a","dataField":"subtitle"}]}`), ShouldEqual, DataFieldNotAllowed)
+	})
+	Convey("should forbid the custom and default queries", t, func() {
+		config := permission.ReactiveSearchConfig{DisableCustomQuery: &enabled, DisableDefaultQuery: &enabled}
+		So(limitErrorType(config, `{"query":[{"id":"a","customQuery":{"query":{"match_all":{}}}}]}`), ShouldEqual, CustomQueryNotAllowed)
+		So(limitErrorType(config, `{"query":[{"id":"a","defaultQuery":{"query":{"match_all":{}}}}]}`), ShouldEqual, DefaultQueryNotAllowed)
+	})
+	Convey("should limit the highlighted fields", t, func() {
+		config := permission.ReactiveSearchConfig{MaxHighlightFields: &two}
+		So(limitErrorType(config, `{"query":[{"id":"a","dataField":["a","b","c"]}]}`), ShouldBeEmpty)
+		So(limitErrorType(config, `{"query":[{"id":"a","highlight":true,"dataField":["a","b","c"],"highlightField":["a"]}]}`), ShouldBeEmpty)
+		So(limitErrorType(config, `{"query":[{"id":"a","highlight":true,"dataField":["a","b","c"]}]}`), ShouldEqual, MaxHighlightFieldsExceeded)
+		So(limitErrorType(config, `{"query":[{"id":"a","highlight":true,"highlightConfig":{"fields":{"a":{},"b":{},"c":{}}}}]}`), ShouldEqual, MaxHighlightFieldsExceeded)
+	})
+	Convey("should limit the react depth", t, func() {
+		config := permission.ReactiveSearchConfig{MaxReactDepth: &two}
+		So(limitErrorType(config, `{"query":[{"id":"a","react":{"and":["b",{"or":["c","d"]}]}}]}`), ShouldBeEmpty)
+		So(limitErrorType(config, `{"query":[{"id":"a","react":{"and":{"or":{"not":"b"}}}}]}`), ShouldEqual, MaxReactDepthExceeded)
+	})
+}
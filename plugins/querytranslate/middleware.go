@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/appbaseio/reactivesearch-api/util/iplookup"
@@ -15,12 +14,15 @@ import (
 	"github.com/appbaseio/reactivesearch-api/middleware/ratelimiter"
 	"github.com/appbaseio/reactivesearch-api/middleware/validate"
 	"github.com/appbaseio/reactivesearch-api/model/category"
+	"github.com/appbaseio/reactivesearch-api/model/docfilter"
+	"github.com/appbaseio/reactivesearch-api/model/fieldmask"
 	"github.com/appbaseio/reactivesearch-api/model/op"
 	"github.com/appbaseio/reactivesearch-api/model/permission"
 	"github.com/appbaseio/reactivesearch-api/model/request"
 	"github.com/appbaseio/reactivesearch-api/model/trackplugin"
 	"github.com/appbaseio/reactivesearch-api/plugins/auth"
 	"github.com/appbaseio/reactivesearch-api/plugins/logs"
+	"github.com/appbaseio/reactivesearch-api/plugins/permissions"
 	"github.com/appbaseio/reactivesearch-api/plugins/telemetry"
 	log "github.com/sirupsen/logrus"
 )
@@ -44,6 +46,7 @@ func list() []middleware.Middleware {
 		classify.Indices(),
 		saveRequestToCtx, // middleware to save the request body in context
 		logs.Recorder(),
+		ratelimiter.LimitUnauthenticated(),
 		auth.BasicAuth(),
 		ratelimiter.Limit(),
 		validate.Sources(),
@@ -52,6 +55,8 @@ func list() []middleware.Middleware {
 		validate.Category(),
 		validate.Operation(),
 		validate.PermissionExpiry(),
+		validate.PermissionSchedule(),
+		permissions.Quota(),
 		applySourceFiltering,
 	}
 }
@@ -143,68 +148,9 @@ func queryTranslate(h http.HandlerFunc) http.HandlerFunc {
 			log.Warnln(logTag, ":", err)
 		}
 		if reqPermission != nil && reqPermission.ReactiveSearchConfig != nil {
-			for _, query := range body.Query {
-				// Note: Query DSL validation is handled by noss
-				// validate query size
-				if reqPermission.ReactiveSearchConfig.MaxSize != nil {
-					// validate size from defaultQuery if present
-					if query.DefaultQuery != nil {
-						defaultQuery := *query.DefaultQuery
-						if defaultQuery["size"] != nil {
-							sizeAsFloat, ok := defaultQuery["size"].(float64)
-							if ok {
-								intSize := int(sizeAsFloat)
-								querySize := &intSize
-								// throw error if query size is greater than specified size
-								if querySize != nil && *querySize > *reqPermission.ReactiveSearchConfig.MaxSize {
-									errorMsg := "maximum allowed size is " + strconv.Itoa(*reqPermission.ReactiveSearchConfig.MaxSize)
-									telemetry.WriteBackErrorWithTelemetry(req, w, errorMsg, http.StatusBadRequest)
-									return
-								}
-							}
-						}
-					}
-
-					if query.Size != nil {
-						querySize := query.Size
-						// throw error if query size is greater than specified size
-						if querySize != nil && *querySize > *reqPermission.ReactiveSearchConfig.MaxSize {
-							errorMsg := "maximum allowed size is " + strconv.Itoa(*reqPermission.ReactiveSearchConfig.MaxSize)
-							telemetry.WriteBackErrorWithTelemetry(req, w, errorMsg, http.StatusBadRequest)
-							return
-						}
-					}
-				}
-
-				// validate aggregation size
-				if reqPermission.ReactiveSearchConfig.MaxAggregationSize != nil {
-					// validate size from defaultQuery if present
-					if query.DefaultQuery != nil {
-						size := getSizeFromQuery(query.DefaultQuery, "size")
-						if size != nil {
-							sizeAsFloat, ok := (*size).(float64)
-							if ok {
-								sizeAsInt := int(sizeAsFloat)
-								aggsSize := &sizeAsInt
-								// throw error if query size is greater than specified size
-								if aggsSize != nil && *aggsSize > *reqPermission.ReactiveSearchConfig.MaxAggregationSize {
-									errorMsg := "maximum allowed aggregation size is " + strconv.Itoa(*reqPermission.ReactiveSearchConfig.MaxAggregationSize)
-									telemetry.WriteBackErrorWithTelemetry(req, w, errorMsg, http.StatusBadRequest)
-									return
-								}
-							}
-						}
-					}
-					if query.AggregationSize != nil {
-						aggsSize := query.AggregationSize
-						// throw error if query size is greater than specified size
-						if aggsSize != nil && *aggsSize > *reqPermission.ReactiveSearchConfig.MaxAggregationSize {
-							errorMsg := "maximum allowed aggregation size is " + strconv.Itoa(*reqPermission.ReactiveSearchConfig.MaxAggregationSize)
-							telemetry.WriteBackErrorWithTelemetry(req, w, errorMsg, http.StatusBadRequest)
-							return
-						}
-					}
-				}
+			if err := validateLimits(reqPermission.ReactiveSearchConfig, body); err != nil {
+				telemetry.WriteBackTypedErrorWithTelemetry(req, w, err.Type, err.Message, http.StatusBadRequest)
+				return
 			}
 		}
 
@@ -226,6 +172,28 @@ func queryTranslate(h http.HandlerFunc) http.HandlerFunc {
 			telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		// Reject the aggregations and the sorts that would expose the masked fields
+		if reqPermission != nil && len(reqPermission.FieldMasks) > 0 {
+			if err := fieldmask.ValidateMsearch([]byte(msearchQuery), reqPermission.FieldMasks); err != nil {
+				log.Errorln(logTag, ":", err)
+				telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		// Apply the document filter of the permission
+		if reqPermission != nil && reqPermission.Filter != nil {
+			filteredQuery, err := docfilter.ApplyToMsearch([]byte(msearchQuery), reqPermission.Filter.ToQuery())
+			if err == docfilter.ErrSuggest || err == docfilter.ErrGlobalAggregation {
+				telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				log.Errorln(logTag, ":", err)
+				telemetry.WriteBackErrorWithTelemetry(req, w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			msearchQuery = string(filteredQuery)
+		}
 		// Update the request body to the parsed query
 		req.Body = ioutil.NopCloser(strings.NewReader(msearchQuery))
 
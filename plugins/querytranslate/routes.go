@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/appbaseio/reactivesearch-api/middleware"
+	"github.com/appbaseio/reactivesearch-api/middleware/classify"
 	"github.com/appbaseio/reactivesearch-api/plugins"
 )
 
@@ -72,5 +73,6 @@ func (px *QueryTranslate) preprocess(mw []middleware.Middleware) error {
 		HandlerFunc: middlewareFunction(mw, px.validate()),
 		Description: "A proxy route to handle search request based on the query props.",
 	})
+	classify.RegisterClassifier(newClassifier(routes))
 	return nil
 }
@@ -11,6 +11,8 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/appbaseio/reactivesearch-api/middleware/concurrency"
+	"github.com/appbaseio/reactivesearch-api/model/credential"
 	"github.com/appbaseio/reactivesearch-api/util"
 	"github.com/bbalet/stopwords"
 	pluralize "github.com/gertd/go-pluralize"
@@ -688,15 +690,24 @@ func isNilInterface(c interface{}) bool {
 	return c == nil || reflect.ValueOf(c).IsNil()
 }
 
-// Makes the elasticsearch requests
-func makeESRequest(ctx context.Context, url, method string, reqBody []byte) (*es7.Response, error) {
-	esClient := util.GetClient7()
+// Makes the elasticsearch requests to the cluster of the indices
+func makeESRequest(ctx context.Context, indices []string, url, method string, reqBody []byte) (*es7.Response, error) {
+	cluster, err := util.ClusterForIndices(indices)
+	if err != nil {
+		return nil, err
+	}
+	release, err := concurrency.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	requestOptions := es7.PerformRequestOptions{
 		Method: method,
 		Path:   url,
 		Body:   string(reqBody),
 	}
-	response, err := esClient.PerformRequest(ctx, requestOptions)
+	// the search requests are reads, served by the read cluster if configured
+	response, err := util.PerformClusterRequest(ctx, cluster, credential.IDFromContext(ctx), requestOptions, true)
 	if err != nil {
 		log.Errorln("Error while making request: ", err)
 		return response, err
@@ -75,6 +75,15 @@ func WriteBackErrorWithTelemetry(req *http.Request, w http.ResponseWriter, err s
 	Instance().recorderError(respRecorder, req)
 }
 
+// WriteBackTypedErrorWithTelemetry writes back the error with its type and records the telemetry.
+func WriteBackTypedErrorWithTelemetry(req *http.Request, w http.ResponseWriter, errType string, err string, code int) {
+	util.WriteBackTypedError(w, errType, err, code)
+	respRecorder := httptest.NewRecorder()
+	respRecorder.Code = code
+	// call telemetry directly
+	Instance().recorderError(respRecorder, req)
+}
+
 // records the telemetry for handlers
 func (t *Telemetry) recorderError(w *httptest.ResponseRecorder, r *http.Request) {
 	if util.IsTelemetryEnabled &&
@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 
 	"github.com/appbaseio/reactivesearch-api/util"
+	es6 "gopkg.in/olivere/elastic.v6"
 )
 
 func (es *elasticsearch) getRawUsersEs6(ctx context.Context) ([]byte, error) {
 	response, err := util.GetClient6().Search().
 		Index(es.indexName).
+		FetchSourceContext(es6.NewFetchSourceContext(true).Exclude(secretFields...)).
 		Size(1000).
 		Do(ctx)
 
@@ -49,7 +51,7 @@ func (es *elasticsearch) getRawUserEs6(ctx context.Context, username string) ([]
 		Index(es.indexName).
 		Type(typeName).
 		Id(username).
-		FetchSource(true).
+		FetchSourceContext(es6.NewFetchSourceContext(true).Exclude(secretFields...)).
 		Do(ctx)
 	if err != nil {
 		return nil, err
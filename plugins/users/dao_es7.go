@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 
 	"github.com/appbaseio/reactivesearch-api/util"
+	es7 "github.com/olivere/elastic/v7"
 )
 
 func (es *elasticsearch) getRawUsersEs7(ctx context.Context) ([]byte, error) {
 	response, err := util.GetClient7().Search().
 		Index(es.indexName).
+		FetchSourceContext(es7.NewFetchSourceContext(true).Exclude(secretFields...)).
 		Size(1000).
 		Do(ctx)
 
@@ -47,7 +49,7 @@ func (es *elasticsearch) getRawUserEs7(ctx context.Context, username string) ([]
 	response, err := util.GetClient7().Get().
 		Index(es.indexName).
 		Id(username).
-		FetchSource(true).
+		FetchSourceContext(es7.NewFetchSourceContext(true).Exclude(secretFields...)).
 		Do(ctx)
 	if err != nil {
 		return nil, err
@@ -22,7 +22,11 @@ func (u *Users) getUser() http.HandlerFunc {
 
 		// check the request context
 		if reqUser, err := user.FromContext(ctx); err == nil {
-			rawUser, err := json.Marshal(*reqUser)
+			ctxUser := *reqUser
+			if ctxUser.TOTP != nil {
+				ctxUser.TOTP = &user.TOTP{Enabled: reqUser.TOTP.Enabled}
+			}
+			rawUser, err := json.Marshal(ctxUser)
 			if err != nil {
 				msg := "error parsing the context user object"
 				log.Errorln(logTag, ":", msg, ":", err)
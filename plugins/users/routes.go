@@ -4,10 +4,18 @@ import (
 	"net/http"
 
 	"github.com/appbaseio/reactivesearch-api/plugins"
+	"github.com/appbaseio/reactivesearch-api/plugins/audit"
 )
 
 func (u *Users) routes() []plugins.Route {
 	middleware := (&chain{}).Wrap
+	auditUser := func(id func(req *http.Request, res []byte) string) func(http.HandlerFunc) http.HandlerFunc {
+		return audit.Recorder(audit.Target{
+			Type: audit.TargetUser,
+			ID:   id,
+			Load: u.es.getRawUser,
+		})
+	}
 	routes := []plugins.Route{
 		{
 			Name:        "Get user",
@@ -34,35 +42,35 @@ func (u *Users) routes() []plugins.Route {
 			Name:        "Post user",
 			Methods:     []string{http.MethodPost},
 			Path:        "/_user",
-			HandlerFunc: middleware(hasUserAccess(u.postUser())),
+			HandlerFunc: middleware(hasUserAccess(auditUser(audit.ResponseUsernameID)(u.postUser()))),
 			Description: "Creates a new user",
 		},
 		{
 			Name:        "Patch user",
 			Methods:     []string{http.MethodPatch},
 			Path:        "/_user",
-			HandlerFunc: middleware(u.patchUser()),
+			HandlerFunc: middleware(auditUser(audit.RequestUsernameID)(u.patchUser())),
 			Description: "Modifies the user",
 		},
 		{
 			Name:        "Patch user with {username}",
 			Methods:     []string{http.MethodPatch},
 			Path:        "/_user/{username}",
-			HandlerFunc: middleware(hasUserAccess(u.patchUserWithUsername())),
+			HandlerFunc: middleware(hasUserAccess(auditUser(audit.VarID("username"))(u.patchUserWithUsername()))),
 			Description: "Modifies the user with {username}",
 		},
 		{
 			Name:        "Delete user",
 			Methods:     []string{http.MethodDelete},
 			Path:        "/_user",
-			HandlerFunc: middleware(u.deleteUser()),
+			HandlerFunc: middleware(auditUser(audit.RequestUsernameID)(u.deleteUser())),
 			Description: "Deletes the user",
 		},
 		{
 			Name:        "Delete user with {username}",
 			Methods:     []string{http.MethodDelete},
 			Path:        "/_user/{username}",
-			HandlerFunc: middleware(hasUserAccess(u.deleteUserWithUsername())),
+			HandlerFunc: middleware(hasUserAccess(auditUser(audit.VarID("username"))(u.deleteUserWithUsername()))),
 			Description: "Deletes the user with {username}",
 		},
 	}
@@ -18,6 +18,9 @@ const (
 	settings            = `{ "settings" : { %s "index.number_of_shards" : 1, "index.number_of_replicas" : %d } }`
 )
 
+// secretFields are excluded from the user documents returned by the user routes.
+var secretFields = []string{"totp.secret", "totp.recovery_codes"}
+
 var (
 	singleton *Users
 	once      sync.Once
@@ -0,0 +1,299 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	es7 "github.com/olivere/elastic/v7"
+)
+
+const (
+	envBreakerThreshold     = "ES_BREAKER_THRESHOLD"
+	envBreakerCooldown      = "ES_BREAKER_COOLDOWN"
+	envRetryMax             = "ES_RETRY_MAX"
+	envRetryBackoff         = "ES_RETRY_BACKOFF"
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+	defaultRetryMax         = 2
+	defaultRetryBackoff     = 100 * time.Millisecond
+)
+
+// States of a circuit breaker.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// UpstreamUnavailableError is returned while the circuit breaker of the upstream is open.
+type UpstreamUnavailableError struct {
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+func (e *UpstreamUnavailableError) Error() string {
+	return fmt.Sprintf("upstream %s is unavailable, retry after %ds", e.Upstream, e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds returns the number of seconds after which the request can be retried.
+func (e *UpstreamUnavailableError) RetryAfterSeconds() int64 {
+	seconds := int64(math.Ceil(e.RetryAfter.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// CircuitBreaker stops the requests to an upstream after consecutive failures,
+// a single request is let through to probe the upstream once the cooldown ends.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	name      string
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+// BreakerStatus represents the state of a circuit breaker.
+type BreakerStatus struct {
+	Upstream            string     `json:"upstream"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*CircuitBreaker)
+)
+
+// NewCircuitBreaker returns a breaker that opens after threshold consecutive failures.
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+// GetBreaker returns the circuit breaker of the upstream, configured by the env vars.
+func GetBreaker(upstream string) *CircuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[upstream]
+	if !ok {
+		b = NewCircuitBreaker(upstream, getEnvInt(envBreakerThreshold, defaultBreakerThreshold),
+			getEnvDuration(envBreakerCooldown, defaultBreakerCooldown))
+		breakers[upstream] = b
+	}
+	return b
+}
+
+// BreakerStatuses returns the state of the circuit breakers sorted by the upstream.
+func BreakerStatuses() []BreakerStatus {
+	breakersMu.Lock()
+	list := make([]*CircuitBreaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(list))
+	for _, b := range list {
+		statuses = append(statuses, b.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Upstream < statuses[j].Upstream
+	})
+	return statuses
+}
+
+// Allow returns an error if the request must not be made to the upstream.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		elapsed := time.Since(b.openedAt)
+		if elapsed < b.cooldown {
+			return &UpstreamUnavailableError{Upstream: b.name, RetryAfter: b.cooldown - elapsed}
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return &UpstreamUnavailableError{Upstream: b.name, RetryAfter: time.Second}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record records the outcome of an allowed request.
+func (b *CircuitBreaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if success {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		if b.state != BreakerOpen {
+			log.Warnln("[breaker] :", b.name, "is unavailable after", b.failures, "consecutive failures, opening the circuit")
+		}
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// abandon releases the probe of an allowed request without an outcome.
+func (b *CircuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Status returns the state of the breaker.
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := BreakerStatus{
+		Upstream:            b.name,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// isUpstreamFailure returns whether the outcome of a request counts as a failure
+// of the upstream, i.e. a 502, 503 or 504 status, a timeout or a connection error.
+// The other 5xx statuses are caused by the requests, e.g. a failed script, and
+// don't tell whether the upstream is available.
+func isUpstreamFailure(res *es7.Response, err error) bool {
+	if res != nil {
+		switch res.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	if err == nil {
+		return false
+	}
+	// the requests cancelled by the clients aren't failures of the upstream
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return es7.IsConnErr(err) || es7.IsTimeout(err)
+}
+
+// ESUpstream returns the name of the upstream elasticsearch cluster, without the credentials.
+func ESUpstream() string {
+	parsed, err := url.Parse(GetESURL())
+	if err != nil {
+		return "elasticsearch"
+	}
+	return parsed.Host
+}
+
+// PerformESRequest performs the request with the circuit breaker of the upstream,
+// the idempotent requests are retried with a jittered exponential backoff on
+// the upstream failures. The scroll requests are never retried since each of
+// them advances the cursor, a retry could skip a page. An *UpstreamUnavailableError
+// is returned while the circuit breaker is open.
+func PerformESRequest(ctx context.Context, client *es7.Client, upstream string, options es7.PerformRequestOptions, idempotent bool) (*es7.Response, error) {
+	breaker := GetBreaker(upstream)
+	retries := 0
+	if idempotent && !isCursorRequest(options) {
+		retries = getEnvInt(envRetryMax, defaultRetryMax)
+	}
+	backoff := getEnvDuration(envRetryBackoff, defaultRetryBackoff)
+
+	for attempt := 0; ; attempt++ {
+		if err := breaker.Allow(); err != nil {
+			return nil, err
+		}
+		res, err := client.PerformRequest(ctx, options)
+		failed := isUpstreamFailure(res, err)
+		if err != nil && errors.Is(err, context.Canceled) {
+			breaker.abandon()
+		} else {
+			breaker.Record(!failed)
+		}
+		if !failed || attempt >= retries {
+			return res, err
+		}
+		// full jitter, see https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+		wait := time.Duration(rand.Int63n(int64(backoff) << uint(attempt)))
+		log.Warnln("[breaker] :", upstream, "failed, retrying", options.Method, options.Path, "in", wait)
+		select {
+		case <-ctx.Done():
+			return res, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// isCursorRequest returns true for the requests that open or advance a scroll cursor.
+func isCursorRequest(options es7.PerformRequestOptions) bool {
+	if strings.Contains(options.Path, "_search/scroll") {
+		return true
+	}
+	return options.Params.Get("scroll") != ""
+}
+
+func getEnvInt(env string, defaultValue int) int {
+	value := os.Getenv(env)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Errorln(env, "must be a non-negative integer, using the default value")
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvDuration(env string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(env)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Errorln(env, "must be a valid duration, using the default value")
+		return defaultValue
+	}
+	return d
+}
@@ -0,0 +1,136 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	es7 "github.com/olivere/elastic/v7"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("should open after the consecutive failures", t, func() {
+		b := NewCircuitBreaker("es", 2, time.Hour)
+		So(b.Allow(), ShouldBeNil)
+		b.Record(false)
+		So(b.Allow(), ShouldBeNil)
+		b.Record(true)
+		b.Record(false)
+		So(b.Status().State, ShouldEqual, BreakerClosed)
+		b.Record(false)
+		So(b.Status().State, ShouldEqual, BreakerOpen)
+
+		var unavailableErr *UpstreamUnavailableError
+		So(errors.As(b.Allow(), &unavailableErr), ShouldBeTrue)
+		So(unavailableErr.RetryAfterSeconds(), ShouldBeGreaterThan, 1)
+	})
+
+	Convey("should probe the upstream once the cooldown ends", t, func() {
+		b := NewCircuitBreaker("es", 1, time.Millisecond)
+		b.Record(false)
+		time.Sleep(2 * time.Millisecond)
+		So(b.Allow(), ShouldBeNil)
+		So(b.Status().State, ShouldEqual, BreakerHalfOpen)
+		// only a single probe is let through
+		So(b.Allow(), ShouldNotBeNil)
+		b.Record(false)
+		So(b.Status().State, ShouldEqual, BreakerOpen)
+
+		time.Sleep(2 * time.Millisecond)
+		So(b.Allow(), ShouldBeNil)
+		b.Record(true)
+		So(b.Status().State, ShouldEqual, BreakerClosed)
+		So(b.Allow(), ShouldBeNil)
+	})
+}
+
+func TestPerformESRequest(t *testing.T) {
+	var calls, failures int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"took":1}`))
+	}))
+	defer server.Close()
+	client, err := es7.NewClient(es7.SetURL(server.URL), es7.SetSniff(false), es7.SetHealthcheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(envRetryBackoff, "1ms")
+	defer os.Unsetenv(envRetryBackoff)
+	options := es7.PerformRequestOptions{Method: http.MethodGet, Path: "/books/_search"}
+	ctx := context.Background()
+
+	Convey("should retry the idempotent requests", t, func() {
+		atomic.StoreInt32(&calls, 0)
+		atomic.StoreInt32(&failures, 2)
+		res, err := PerformESRequest(ctx, client, "retry", options, true)
+		So(err, ShouldBeNil)
+		So(res.StatusCode, ShouldEqual, http.StatusOK)
+		So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+		So(GetBreaker("retry").Status().State, ShouldEqual, BreakerClosed)
+	})
+
+	Convey("should not retry the other requests", t, func() {
+		atomic.StoreInt32(&calls, 0)
+		atomic.StoreInt32(&failures, 1)
+		res, err := PerformESRequest(ctx, client, "no-retry", options, false)
+		So(err, ShouldNotBeNil)
+		So(res.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+		So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+	})
+
+	Convey("should not retry the scroll requests", t, func() {
+		scrolls := []es7.PerformRequestOptions{
+			{Method: http.MethodPost, Path: "/_search/scroll"},
+			{Method: http.MethodGet, Path: "/books/_search", Params: url.Values{"scroll": []string{"1m"}}},
+		}
+		for _, scroll := range scrolls {
+			atomic.StoreInt32(&calls, 0)
+			atomic.StoreInt32(&failures, 1)
+			res, err := PerformESRequest(ctx, client, "scroll", scroll, true)
+			So(err, ShouldNotBeNil)
+			So(res.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+		}
+	})
+
+	Convey("should not make the requests while the breaker is open", t, func() {
+		atomic.StoreInt32(&calls, 0)
+		atomic.StoreInt32(&failures, 100)
+		for i := 0; i < 2; i++ {
+			PerformESRequest(ctx, client, "open", options, true)
+		}
+		So(GetBreaker("open").Status().State, ShouldEqual, BreakerOpen)
+		calls := atomic.LoadInt32(&calls)
+		So(calls, ShouldEqual, defaultBreakerThreshold)
+
+		_, err := PerformESRequest(ctx, client, "open", options, true)
+		var unavailableErr *UpstreamUnavailableError
+		So(errors.As(err, &unavailableErr), ShouldBeTrue)
+		So(unavailableErr.Upstream, ShouldEqual, "open")
+		So(BreakerStatuses(), ShouldNotBeEmpty)
+	})
+	Convey("should only count the unavailable upstream statuses as failures", t, func() {
+		for _, code := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+			So(isUpstreamFailure(&es7.Response{StatusCode: code}, nil), ShouldBeTrue)
+		}
+		for _, code := range []int{http.StatusOK, http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented} {
+			So(isUpstreamFailure(&es7.Response{StatusCode: code}, nil), ShouldBeFalse)
+		}
+		So(isUpstreamFailure(nil, context.DeadlineExceeded), ShouldBeTrue)
+		So(isUpstreamFailure(nil, context.Canceled), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,366 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	es7 "github.com/olivere/elastic/v7"
+	log "github.com/sirupsen/logrus"
+	es6 "gopkg.in/olivere/elastic.v6"
+)
+
+const (
+	envClusters      = "ES_CLUSTERS"
+	envClusterRoutes = "ES_CLUSTER_ROUTES"
+	envReadCluster   = "ES_READ_CLUSTER"
+	// DefaultCluster is the name of the cluster configured by ES_CLUSTER_URL,
+	// the indices that don't match a route are served by it.
+	DefaultCluster = "default"
+)
+
+// ErrMixedClusters is returned when the indices of a request are routed to different clusters.
+var ErrMixedClusters = errors.New("the indices of a request must be routed to the same cluster")
+
+// Cluster is an upstream elasticsearch cluster registered in ES_CLUSTERS.
+type Cluster struct {
+	Name     string `json:"-"`
+	URL      string `json:"url"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Version is the version of the distribution of the cluster, it is
+	// retrieved from the cluster along with the distribution when not configured.
+	Version string `json:"version,omitempty"`
+	// Distribution is either elasticsearch (default) or opensearch.
+	Distribution string `json:"distribution,omitempty"`
+	// ReadCluster is the name of the replica cluster that serves the read requests.
+	ReadCluster string `json:"read_cluster,omitempty"`
+
+	read        *Cluster
+	clientInit  sync.Once
+	client7     *es7.Client
+	client6     *es6.Client
+	versionInit sync.Once
+}
+
+// ClusterRoute routes the indices matching the index pattern to a cluster.
+type ClusterRoute struct {
+	Index   string `json:"index"`
+	Cluster string `json:"cluster"`
+}
+
+type clusterRegistry struct {
+	clusters map[string]*Cluster
+	routes   []ClusterRoute
+}
+
+var (
+	registryInit sync.Once
+	registry     *clusterRegistry
+)
+
+func getRegistry() *clusterRegistry {
+	registryInit.Do(func() {
+		var err error
+		registry, err = newClusterRegistry(os.Getenv(envClusters), os.Getenv(envClusterRoutes), os.Getenv(envReadCluster))
+		if err != nil {
+			log.Fatal("Error encountered: ", err)
+		}
+	})
+	return registry
+}
+
+// newClusterRegistry parses the clusters and the routes, the routes and the read
+// clusters must only refer to the registered clusters or to the default cluster.
+func newClusterRegistry(clusters, routes, readCluster string) (*clusterRegistry, error) {
+	r := &clusterRegistry{
+		clusters: map[string]*Cluster{
+			DefaultCluster: {Name: DefaultCluster, ReadCluster: readCluster},
+		},
+	}
+	if clusters != "" {
+		var parsed map[string]*Cluster
+		if err := json.Unmarshal([]byte(clusters), &parsed); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %v", envClusters, err)
+		}
+		for name, cluster := range parsed {
+			if name == DefaultCluster {
+				return nil, fmt.Errorf("invalid value for %s: the %q cluster is configured by ES_CLUSTER_URL", envClusters, DefaultCluster)
+			}
+			if cluster == nil || cluster.URL == "" {
+				return nil, fmt.Errorf("invalid value for %s: url of the cluster %q must be set", envClusters, name)
+			}
+			if cluster.Distribution != "" && cluster.Distribution != DistributionElasticsearch && cluster.Distribution != DistributionOpenSearch {
+				return nil, fmt.Errorf("invalid value for %s: distribution of the cluster %q must be %s or %s", envClusters, name, DistributionElasticsearch, DistributionOpenSearch)
+			}
+			cluster.Name = name
+			r.clusters[name] = cluster
+		}
+	}
+	for _, cluster := range r.clusters {
+		if cluster.ReadCluster == "" {
+			continue
+		}
+		read, ok := r.clusters[cluster.ReadCluster]
+		if !ok || read == cluster {
+			return nil, fmt.Errorf("invalid read cluster %q of the cluster %q", cluster.ReadCluster, cluster.Name)
+		}
+		cluster.read = read
+	}
+	if routes != "" {
+		if err := json.Unmarshal([]byte(routes), &r.routes); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %v", envClusterRoutes, err)
+		}
+		for _, route := range r.routes {
+			if route.Index == "" {
+				return nil, fmt.Errorf("invalid value for %s: index pattern of a route must be set", envClusterRoutes)
+			}
+			if _, ok := r.clusters[route.Cluster]; !ok {
+				return nil, fmt.Errorf("invalid value for %s: cluster %q isn't registered", envClusterRoutes, route.Cluster)
+			}
+		}
+	}
+	return r, nil
+}
+
+// forIndex returns the cluster of the first route matching the index.
+func (r *clusterRegistry) forIndex(index string) *Cluster {
+	for _, route := range r.routes {
+		if matchIndexPattern(route.Index, index) {
+			return r.clusters[route.Cluster]
+		}
+	}
+	return r.clusters[DefaultCluster]
+}
+
+// matchIndexPattern matches the whole index against a pattern where * matches any
+// sequence of characters, unlike ValidateIndex the dots of the system indices
+// aren't treated as wildcards.
+func matchIndexPattern(pattern, index string) bool {
+	expr := "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$"
+	matched, err := regexp.MatchString(expr, index)
+	return err == nil && matched
+}
+
+func (r *clusterRegistry) forIndices(indices []string) (*Cluster, error) {
+	cluster := r.clusters[DefaultCluster]
+	for i, index := range indices {
+		indexCluster := r.forIndex(index)
+		if i > 0 && indexCluster != cluster {
+			return nil, ErrMixedClusters
+		}
+		// a wildcard expression spans the clusters of all the routes it overlaps
+		if index == "_all" || strings.Contains(index, "*") {
+			for _, route := range r.routes {
+				if r.clusters[route.Cluster] != indexCluster && overlapIndexPatterns(route.Index, index) {
+					return nil, ErrMixedClusters
+				}
+			}
+		}
+		cluster = indexCluster
+	}
+	return cluster, nil
+}
+
+// overlapIndexPatterns returns true if an index could match both the route pattern
+// and the wildcard expression of a request. Only the literal prefixes of the patterns
+// are compared, the overlap is assumed when one of them is a prefix of the other.
+func overlapIndexPatterns(pattern, expr string) bool {
+	if expr == "_all" {
+		return true
+	}
+	if !strings.Contains(pattern, "*") {
+		return matchIndexPattern(expr, pattern)
+	}
+	patternPrefix := strings.SplitN(pattern, "*", 2)[0]
+	exprPrefix := strings.SplitN(expr, "*", 2)[0]
+	return strings.HasPrefix(patternPrefix, exprPrefix) || strings.HasPrefix(exprPrefix, patternPrefix)
+}
+
+// GetCluster returns the registered cluster with the given name.
+func GetCluster(name string) (*Cluster, bool) {
+	cluster, ok := getRegistry().clusters[name]
+	return cluster, ok
+}
+
+// Clusters returns the registered clusters sorted by their names.
+func Clusters() []*Cluster {
+	var clusters []*Cluster
+	for _, cluster := range getRegistry().clusters {
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+	return clusters
+}
+
+// ClusterForIndex returns the cluster that serves the index, the default cluster
+// is returned when no route matches the index.
+func ClusterForIndex(index string) *Cluster {
+	return getRegistry().forIndex(index)
+}
+
+// ClusterForIndices returns the cluster that serves all the indices, ErrMixedClusters
+// is returned when the indices are routed to different clusters.
+func ClusterForIndices(indices []string) (*Cluster, error) {
+	return getRegistry().forIndices(indices)
+}
+
+// IsDefault returns true for the cluster configured by ES_CLUSTER_URL.
+func (c *Cluster) IsDefault() bool {
+	return c.Name == DefaultCluster
+}
+
+// GetURL returns the url of the cluster with escaped auth.
+func (c *Cluster) GetURL() string {
+	if c.IsDefault() {
+		return GetESURL()
+	}
+	return c.URL
+}
+
+// Upstream returns the name of the upstream used by the circuit breaker of the cluster.
+func (c *Cluster) Upstream() string {
+	if c.IsDefault() {
+		return ESUpstream()
+	}
+	parsed, err := url.Parse(c.URL)
+	if err != nil {
+		return c.Name
+	}
+	return parsed.Host
+}
+
+// Client7 returns the es7 client of the cluster
+func (c *Cluster) Client7() *es7.Client {
+	if c.IsDefault() {
+		return GetClient7()
+	}
+	c.initClients()
+	return c.client7
+}
+
+// Client6 returns the es6 client of the cluster
+func (c *Cluster) Client6() *es6.Client {
+	if c.IsDefault() {
+		return GetClient6()
+	}
+	c.initClients()
+	return c.client6
+}
+
+func (c *Cluster) initClients() {
+	c.clientInit.Do(func() {
+		var err error
+		// the wrappers log with the standard logger, their embedded logger isn't
+		// used and must not be copied
+		wrappedLoggerDebug := &WrapKitLoggerDebug{}
+		wrappedLoggerError := &WrapKitLoggerError{}
+
+		c.client7, err = es7.NewClient(
+			es7.SetURL(c.URL),
+			es7.SetBasicAuth(c.Username, c.Password),
+			es7.SetRetrier(NewRetrier()),
+			es7.SetSniff(isSniffingEnabled()),
+			es7.SetHttpClient(HTTPClient()),
+			es7.SetErrorLog(wrappedLoggerError),
+			es7.SetInfoLog(wrappedLoggerDebug),
+			es7.SetTraceLog(wrappedLoggerDebug),
+		)
+		if err != nil {
+			log.Fatal("Error encountered: ", fmt.Errorf("error while initializing elastic v7 client of the cluster %s: %v", c.Name, err))
+		}
+		c.client6, err = es6.NewClient(
+			es6.SetURL(c.URL),
+			es6.SetBasicAuth(c.Username, c.Password),
+			es6.SetRetrier(NewRetrier()),
+			es6.SetSniff(isSniffingEnabled()),
+			es6.SetHttpClient(HTTPClient()),
+			es6.SetErrorLog(wrappedLoggerError),
+			es6.SetInfoLog(wrappedLoggerDebug),
+			es6.SetTraceLog(wrappedLoggerDebug),
+		)
+		if err != nil {
+			log.Fatal("Error encountered: ", fmt.Errorf("error while initializing elastic v6 client of the cluster %s: %v", c.Name, err))
+		}
+	})
+}
+
+func (c *Cluster) loadVersion() {
+	c.versionInit.Do(func() {
+		if c.Version != "" {
+			if c.Distribution != DistributionOpenSearch {
+				c.Distribution = DistributionElasticsearch
+			}
+			return
+		}
+		info, err := fetchVersionInfo(c.Client7())
+		if err != nil {
+			log.Fatal("Error encountered: ", fmt.Errorf("error while retrieving the elastic version of the cluster %s: %v", c.Name, err))
+		}
+		c.Version = info.Number
+		c.Distribution = info.distribution()
+	})
+}
+
+// GetSemanticVersion returns the es version of the cluster, the opensearch
+// clusters are mapped to the es version they are compatible with
+func (c *Cluster) GetSemanticVersion() string {
+	if c.IsDefault() {
+		return GetSemanticVersion()
+	}
+	c.loadVersion()
+	return compatibleVersion(c.Distribution, c.Version)
+}
+
+// GetDistribution returns the distribution of the cluster
+func (c *Cluster) GetDistribution() string {
+	if c.IsDefault() {
+		return GetDistribution()
+	}
+	c.loadVersion()
+	return c.Distribution
+}
+
+// GetDocType returns the type of the documents in the bulk and search requests
+// made to the cluster
+func (c *Cluster) GetDocType() string {
+	if c.IsDefault() {
+		return GetDocType()
+	}
+	c.loadVersion()
+	return docType(c.Distribution, c.Version)
+}
+
+// GetVersion returns the major es version of the cluster
+func (c *Cluster) GetVersion() int {
+	if c.IsDefault() {
+		return GetVersion()
+	}
+	major, _ := strconv.Atoi(strings.Split(c.GetSemanticVersion(), ".")[0])
+	return major
+}
+
+// HiddenIndexSettings to set plugin indices of the cluster as hidden index
+func (c *Cluster) HiddenIndexSettings() string {
+	return hiddenIndexSettings(c.GetSemanticVersion())
+}
+
+// initClusters instantiates the clients of the registered clusters
+func initClusters() {
+	for _, cluster := range Clusters() {
+		if cluster.IsDefault() {
+			continue
+		}
+		cluster.initClients()
+		log.Println("clients instantiated for the cluster", cluster.Name, ", elastic search version is", cluster.GetSemanticVersion(), ", distribution is", cluster.GetDistribution(), cluster.Version)
+	}
+}
@@ -0,0 +1,75 @@
+package util
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClusterRegistry(t *testing.T) {
+	clusters := `{"logs": {"url": "http://logs:9200"}, "catalog": {"url": "http://catalog:9200", "username": "elastic", "password": "secret"}}`
+	routes := `[{"index": ".logs*", "cluster": "logs"}, {"index": "products", "cluster": "catalog"}, {"index": "products-*", "cluster": "catalog"}]`
+
+	Convey("should route the indices to the first matching cluster", t, func() {
+		r, err := newClusterRegistry(clusters, routes, "")
+		So(err, ShouldBeNil)
+		So(r.forIndex(".logs").Name, ShouldEqual, "logs")
+		So(r.forIndex(".logs-000001").Name, ShouldEqual, "logs")
+		So(r.forIndex("products").Name, ShouldEqual, "catalog")
+		So(r.forIndex("products-2021").Name, ShouldEqual, "catalog")
+		So(r.forIndex("my-products").Name, ShouldEqual, DefaultCluster)
+		So(r.forIndex("alogs").Name, ShouldEqual, DefaultCluster)
+		So(r.clusters["catalog"].Username, ShouldEqual, "elastic")
+	})
+
+	Convey("should route the indices of a request to a single cluster", t, func() {
+		r, err := newClusterRegistry(clusters, routes, "")
+		So(err, ShouldBeNil)
+		cluster, err := r.forIndices(nil)
+		So(err, ShouldBeNil)
+		So(cluster.IsDefault(), ShouldBeTrue)
+		cluster, err = r.forIndices([]string{"products", "products-2021"})
+		So(err, ShouldBeNil)
+		So(cluster.Name, ShouldEqual, "catalog")
+		_, err = r.forIndices([]string{"products", ".logs"})
+		So(err, ShouldEqual, ErrMixedClusters)
+	})
+
+	Convey("should reject the wildcard expressions spanning several clusters", t, func() {
+		r, err := newClusterRegistry(clusters, routes, "")
+		So(err, ShouldBeNil)
+		cluster, err := r.forIndices([]string{"products-*"})
+		So(err, ShouldBeNil)
+		So(cluster.Name, ShouldEqual, "catalog")
+		cluster, err = r.forIndices([]string{"books*"})
+		So(err, ShouldBeNil)
+		So(cluster.IsDefault(), ShouldBeTrue)
+		for _, expr := range []string{"*", "_all", "prod*", ".l*"} {
+			_, err = r.forIndices([]string{expr})
+			So(err, ShouldEqual, ErrMixedClusters)
+		}
+	})
+
+	Convey("should resolve the read clusters", t, func() {
+		r, err := newClusterRegistry(`{"replica": {"url": "http://replica:9200"}, "logs": {"url": "http://logs:9200", "read_cluster": "replica"}}`, "", "replica")
+		So(err, ShouldBeNil)
+		So(r.clusters[DefaultCluster].readTarget(""), ShouldEqual, r.clusters["replica"])
+		So(r.clusters["logs"].readTarget(""), ShouldEqual, r.clusters["replica"])
+		So(r.clusters["replica"].readTarget(""), ShouldEqual, r.clusters["replica"])
+	})
+
+	Convey("should reject the invalid configurations", t, func() {
+		_, err := newClusterRegistry(`{"default": {"url": "http://es:9200"}}`, "", "")
+		So(err, ShouldNotBeNil)
+		_, err = newClusterRegistry(`{"logs": {}}`, "", "")
+		So(err, ShouldNotBeNil)
+		_, err = newClusterRegistry(clusters, `[{"index": "books", "cluster": "books"}]`, "")
+		So(err, ShouldNotBeNil)
+		_, err = newClusterRegistry("", `[{"cluster": "default"}]`, "")
+		So(err, ShouldNotBeNil)
+		_, err = newClusterRegistry("", "", "replica")
+		So(err, ShouldNotBeNil)
+		_, err = newClusterRegistry("", "", DefaultCluster)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"net/http"
+	"strings"
+)
+
+// compatibleHeaders are the headers that request the REST API compatibility of
+// elasticsearch 8, e.g. application/vnd.elasticsearch+json;compatible-with=7
+var compatibleHeaders = []string{"Accept", "Content-Type"}
+
+// IsCompatibleMediaType returns true if the media type requests a compatible version of the REST API.
+func IsCompatibleMediaType(mediaType string) bool {
+	return strings.Contains(mediaType, "compatible-with")
+}
+
+// compatibleTransport keeps a single value of the compatibility headers, the
+// es clients always set the json media type which elasticsearch would reject
+// along with a compatible media type.
+type compatibleTransport struct {
+	next http.RoundTripper
+}
+
+func (t *compatibleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var compatible map[string]string
+	for _, header := range compatibleHeaders {
+		values := req.Header.Values(header)
+		if len(values) < 2 {
+			continue
+		}
+		for _, value := range values {
+			if IsCompatibleMediaType(value) {
+				if compatible == nil {
+					compatible = make(map[string]string)
+				}
+				compatible[header] = value
+				break
+			}
+		}
+	}
+	if compatible == nil {
+		return t.next.RoundTrip(req)
+	}
+	// a round tripper must not modify the request
+	req = req.Clone(req.Context())
+	for header, value := range compatible {
+		req.Header.Set(header, value)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	es7 "github.com/olivere/elastic/v7"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompatibleTransport(t *testing.T) {
+	var accept, contentType []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Values("Accept")
+		contentType = r.Header.Values("Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	client, err := es7.NewClient(
+		es7.SetURL(server.URL),
+		es7.SetSniff(false),
+		es7.SetHealthcheck(false),
+		es7.SetHttpClient(&http.Client{Transport: &compatibleTransport{http.DefaultTransport}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compatible := "application/vnd.elasticsearch+json;compatible-with=7"
+
+	Convey("should send a single compatible media type", t, func() {
+		_, err := client.PerformRequest(context.Background(), es7.PerformRequestOptions{
+			Method:      http.MethodPost,
+			Path:        "/books/_search",
+			Body:        `{}`,
+			ContentType: compatible,
+			Headers:     http.Header{"Accept": []string{compatible}},
+		})
+		So(err, ShouldBeNil)
+		So(accept, ShouldResemble, []string{compatible})
+		So(contentType, ShouldResemble, []string{compatible})
+	})
+
+	Convey("should keep the other media types", t, func() {
+		_, err := client.PerformRequest(context.Background(), es7.PerformRequestOptions{
+			Method: http.MethodPost,
+			Path:   "/books/_search",
+			Body:   `{}`,
+		})
+		So(err, ShouldBeNil)
+		So(accept, ShouldResemble, []string{"application/json"})
+		So(contentType, ShouldResemble, []string{"application/json"})
+	})
+}
@@ -0,0 +1,206 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	es7 "github.com/olivere/elastic/v7"
+)
+
+// defaultCursorKeepAlive is used when the keep alive of a cursor isn't set or can't be parsed.
+const defaultCursorKeepAlive = 5 * time.Minute
+
+var openCursors = newCursorTracker()
+
+// cursorTracker remembers the clusters that serve the scroll and the point in time
+// cursors, a cursor only exists on the cluster that opened it so its continuations
+// must be sent there, whether it is a read cluster or not.
+type cursorTracker struct {
+	mu        sync.Mutex
+	cursors   map[string]cursor
+	lastSweep time.Time
+}
+
+type cursor struct {
+	cluster *Cluster
+	expiry  time.Time
+}
+
+func newCursorTracker() *cursorTracker {
+	return &cursorTracker{cursors: make(map[string]cursor)}
+}
+
+// record remembers the cluster of the cursor until its keep alive expires.
+func (t *cursorTracker) record(id string, cluster *Cluster, keepAlive time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.cursors[id] = cursor{cluster: cluster, expiry: now.Add(keepAlive)}
+	// drop the expired cursors at most once per default keep alive
+	if now.Sub(t.lastSweep) < defaultCursorKeepAlive {
+		return
+	}
+	for k, c := range t.cursors {
+		if now.After(c.expiry) {
+			delete(t.cursors, k)
+		}
+	}
+	t.lastSweep = now
+}
+
+// get returns the cluster of the cursor, if it hasn't expired.
+func (t *cursorTracker) get(id string) (*Cluster, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.cursors[id]
+	if !ok || time.Now().After(c.expiry) {
+		return nil, false
+	}
+	return c.cluster, true
+}
+
+// ClusterForCursor returns the cluster that serves the scroll or the point in time
+// cursor continued or closed by the request, false is returned for the other requests.
+func ClusterForCursor(options es7.PerformRequestOptions) (*Cluster, bool) {
+	id, _, _ := requestCursor(options)
+	if id == "" {
+		return nil, false
+	}
+	return openCursors.get(id)
+}
+
+// recordCursor remembers the cluster that served the cursor opened or continued by
+// the request, so that the next continuations are sent to it.
+func recordCursor(cluster *Cluster, options es7.PerformRequestOptions, res *es7.Response) {
+	if res == nil {
+		return
+	}
+	_, isCursor, keepAlive := requestCursor(options)
+	if !isCursor {
+		return
+	}
+	var body struct {
+		ScrollID string `json:"_scroll_id"`
+		PitID    string `json:"pit_id"`
+		ID       string `json:"id"`
+	}
+	if json.Unmarshal(res.Body, &body) != nil {
+		return
+	}
+	// the point in time of elasticsearch is opened with the _pit api and returned as
+	// the id, the searches and the point in time api of opensearch return the pit_id
+	if !strings.HasSuffix(options.Path, "/_pit") {
+		body.ID = ""
+	}
+	for _, id := range []string{body.ScrollID, body.PitID, body.ID} {
+		if id != "" {
+			openCursors.record(id, cluster, keepAlive)
+		}
+	}
+}
+
+// cursorBody is the part of a request body that refers to a cursor.
+type cursorBody struct {
+	ScrollID interface{} `json:"scroll_id"`
+	Scroll   string      `json:"scroll"`
+	ID       interface{} `json:"id"`
+	PitID    interface{} `json:"pit_id"`
+	Pit      struct {
+		ID        string `json:"id"`
+		KeepAlive string `json:"keep_alive"`
+	} `json:"pit"`
+}
+
+// requestCursor returns the id of the cursor continued or closed by the request, if
+// any, whether the request relates to a cursor and the keep alive of the cursor.
+func requestCursor(options es7.PerformRequestOptions) (string, bool, time.Duration) {
+	isScroll := strings.Contains(options.Path, "_search/scroll")
+	isPit := strings.HasSuffix(options.Path, "/_pit") || strings.Contains(options.Path, "_search/point_in_time")
+	var body cursorBody
+	raw := requestBody(options.Body)
+	// the searches only relate to a point in time if their body refers to it
+	if isScroll || isPit || (strings.HasSuffix(options.Path, "_search") && bytes.Contains(raw, []byte(`"pit"`))) {
+		json.Unmarshal(raw, &body)
+	}
+	keepAlive := options.Params.Get("scroll")
+	for _, value := range []string{options.Params.Get("keep_alive"), body.Scroll, body.Pit.KeepAlive} {
+		if keepAlive == "" {
+			keepAlive = value
+		}
+	}
+	isCursor := isScroll || isPit || body.Pit.ID != "" || options.Params.Get("scroll") != ""
+
+	var id string
+	switch {
+	case isScroll:
+		if i := strings.Index(options.Path, "_search/scroll/"); i >= 0 {
+			id, _ = url.PathUnescape(options.Path[i+len("_search/scroll/"):])
+		} else if options.Params.Get("scroll_id") != "" {
+			id = options.Params.Get("scroll_id")
+		} else {
+			id = firstCursorID(body.ScrollID)
+		}
+	case isPit:
+		// the delete requests may close several cursors of the same cluster
+		id = firstCursorID(body.ID)
+		if id == "" {
+			id = firstCursorID(body.PitID)
+		}
+	default:
+		id = body.Pit.ID
+	}
+	// the ids of the clear scroll requests are comma separated
+	return strings.SplitN(id, ",", 2)[0], isCursor, parseKeepAlive(keepAlive)
+}
+
+// firstCursorID returns the first id of a string or an array of ids.
+func firstCursorID(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			id, _ := v[0].(string)
+			return id
+		}
+	}
+	return ""
+}
+
+// requestBody returns the body of the request options as bytes.
+func requestBody(body interface{}) []byte {
+	switch v := body.(type) {
+	case nil:
+		return nil
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	case json.RawMessage:
+		return v
+	default:
+		raw, _ := json.Marshal(v)
+		return raw
+	}
+}
+
+// parseKeepAlive parses an elasticsearch time value, e.g. 1m or 2d.
+func parseKeepAlive(value string) time.Duration {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err == nil && days > 0 {
+			return time.Duration(days) * 24 * time.Hour
+		}
+		return defaultCursorKeepAlive
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return defaultCursorKeepAlive
+	}
+	return d
+}
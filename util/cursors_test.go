@@ -0,0 +1,80 @@
+package util
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	es7 "github.com/olivere/elastic/v7"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClusterForCursor(t *testing.T) {
+	logs := &Cluster{Name: "logs"}
+
+	Convey("should send the scroll continuations to the cluster that opened the scroll", t, func() {
+		open := es7.PerformRequestOptions{Method: http.MethodPost, Path: "/.logs/_search", Params: url.Values{"scroll": {"1m"}}}
+		recordCursor(logs, open, &es7.Response{Body: []byte(`{"_scroll_id":"cursor-1","hits":{}}`)})
+
+		continuations := []es7.PerformRequestOptions{
+			{Method: http.MethodPost, Path: "/_search/scroll", Body: `{"scroll":"1m","scroll_id":"cursor-1"}`},
+			{Method: http.MethodGet, Path: "/_search/scroll/cursor-1"},
+			{Method: http.MethodGet, Path: "/_search/scroll", Params: url.Values{"scroll_id": {"cursor-1"}}},
+			{Method: http.MethodDelete, Path: "/_search/scroll", Body: `{"scroll_id":["cursor-1"]}`},
+		}
+		for _, options := range continuations {
+			cluster, ok := ClusterForCursor(options)
+			So(ok, ShouldBeTrue)
+			So(cluster, ShouldEqual, logs)
+		}
+	})
+
+	Convey("should send the point in time searches to the cluster that opened the point in time", t, func() {
+		open := es7.PerformRequestOptions{Method: http.MethodPost, Path: "/.logs/_pit", Params: url.Values{"keep_alive": {"1m"}}}
+		recordCursor(logs, open, &es7.Response{Body: []byte(`{"id":"pit-1"}`)})
+		openSearch := es7.PerformRequestOptions{Method: http.MethodPost, Path: "/.logs/_search/point_in_time", Params: url.Values{"keep_alive": {"1m"}}}
+		recordCursor(logs, openSearch, &es7.Response{Body: []byte(`{"pit_id":"pit-2"}`)})
+
+		continuations := []es7.PerformRequestOptions{
+			{Method: http.MethodPost, Path: "/_search", Body: `{"pit":{"id":"pit-1","keep_alive":"1m"},"size":10}`},
+			{Method: http.MethodDelete, Path: "/_pit", Body: `{"id":"pit-1"}`},
+			{Method: http.MethodPost, Path: "/_search", Body: `{"pit":{"id":"pit-2"}}`},
+			{Method: http.MethodDelete, Path: "/_search/point_in_time", Body: `{"pit_id":["pit-2"]}`},
+		}
+		for _, options := range continuations {
+			cluster, ok := ClusterForCursor(options)
+			So(ok, ShouldBeTrue)
+			So(cluster, ShouldEqual, logs)
+		}
+	})
+
+	Convey("should not record the ids of the other responses", t, func() {
+		search := es7.PerformRequestOptions{Method: http.MethodPost, Path: "/.logs/_search"}
+		recordCursor(logs, search, &es7.Response{Body: []byte(`{"id":"not-a-cursor","pit_id":"not-a-pit"}`)})
+		_, ok := openCursors.get("not-a-pit")
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("should not route the other requests", t, func() {
+		_, ok := ClusterForCursor(es7.PerformRequestOptions{Method: http.MethodPost, Path: "/_search/scroll", Body: `{"scroll_id":"unknown"}`})
+		So(ok, ShouldBeFalse)
+		_, ok = ClusterForCursor(es7.PerformRequestOptions{Method: http.MethodPost, Path: "/.logs/_search"})
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("should forget the cursors once their keep alive expires", t, func() {
+		tracker := newCursorTracker()
+		tracker.record("cursor-2", logs, time.Millisecond)
+		time.Sleep(2 * time.Millisecond)
+		_, ok := tracker.get("cursor-2")
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("should parse the keep alive", t, func() {
+		So(parseKeepAlive("30s"), ShouldEqual, 30*time.Second)
+		So(parseKeepAlive("2d"), ShouldEqual, 48*time.Hour)
+		So(parseKeepAlive(""), ShouldEqual, defaultCursorKeepAlive)
+	})
+}
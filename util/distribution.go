@@ -0,0 +1,82 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	v "github.com/hashicorp/go-version"
+	es7 "github.com/olivere/elastic/v7"
+)
+
+// Distributions of the upstream clusters.
+const (
+	DistributionElasticsearch = "elasticsearch"
+	DistributionOpenSearch    = "opensearch"
+)
+
+// openSearchCompatibleVersion is the elasticsearch version opensearch is forked from,
+// the opensearch APIs are handled as the APIs of this version.
+const openSearchCompatibleVersion = "7.10.2"
+
+// versionInfo is the version returned by the root endpoint of the cluster.
+type versionInfo struct {
+	Number       string `json:"number"`
+	Distribution string `json:"distribution"`
+}
+
+// fetchVersionInfo retrieves the version of the cluster from its root endpoint,
+// the opensearch clusters also return their distribution.
+func fetchVersionInfo(client *es7.Client) (*versionInfo, error) {
+	response, err := client.PerformRequest(context.Background(), es7.PerformRequestOptions{
+		Method: http.MethodGet,
+		Path:   "/",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		Version versionInfo `json:"version"`
+	}
+	if err := json.Unmarshal(response.Body, &info); err != nil {
+		return nil, err
+	}
+	return &info.Version, nil
+}
+
+func (info *versionInfo) distribution() string {
+	if info.Distribution == DistributionOpenSearch {
+		return DistributionOpenSearch
+	}
+	return DistributionElasticsearch
+}
+
+// compatibleVersion returns the elasticsearch version with the APIs of the cluster.
+func (info *versionInfo) compatibleVersion() string {
+	return compatibleVersion(info.distribution(), info.Number)
+}
+
+func compatibleVersion(distribution, distributionVersion string) string {
+	if distribution == DistributionOpenSearch {
+		return openSearchCompatibleVersion
+	}
+	return distributionVersion
+}
+
+// docType returns the type of the documents in the bulk and search requests,
+// the mapping types are removed in elasticsearch 8 and opensearch 2.
+func docType(distribution, distributionVersion string) string {
+	removedIn := "8.0.0"
+	if distribution == DistributionOpenSearch {
+		removedIn = "2.0.0"
+	}
+	current, err := v.NewVersion(distributionVersion)
+	if err != nil {
+		return "_doc"
+	}
+	removedVersion, _ := v.NewVersion(removedIn)
+	if current.GreaterThanOrEqual(removedVersion) {
+		return ""
+	}
+	return "_doc"
+}
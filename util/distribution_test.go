@@ -0,0 +1,64 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	es7 "github.com/olivere/elastic/v7"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFetchVersionInfo(t *testing.T) {
+	var root string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(root))
+	}))
+	defer server.Close()
+	client, err := es7.NewClient(es7.SetURL(server.URL), es7.SetSniff(false), es7.SetHealthcheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("should detect the elasticsearch clusters", t, func() {
+		root = `{"version": {"number": "7.10.2", "build_flavor": "default"}}`
+		info, err := fetchVersionInfo(client)
+		So(err, ShouldBeNil)
+		So(info.distribution(), ShouldEqual, DistributionElasticsearch)
+		So(info.compatibleVersion(), ShouldEqual, "7.10.2")
+	})
+
+	Convey("should map the opensearch versions to the compatible elasticsearch version", t, func() {
+		root = `{"version": {"distribution": "opensearch", "number": "2.11.0"}}`
+		info, err := fetchVersionInfo(client)
+		So(err, ShouldBeNil)
+		So(info.Number, ShouldEqual, "2.11.0")
+		So(info.distribution(), ShouldEqual, DistributionOpenSearch)
+		So(info.compatibleVersion(), ShouldEqual, openSearchCompatibleVersion)
+	})
+}
+
+func TestDocType(t *testing.T) {
+	Convey("should drop the mapping types once they are removed", t, func() {
+		So(docType(DistributionElasticsearch, "6.8.0"), ShouldEqual, "_doc")
+		So(docType(DistributionElasticsearch, "7.10.2"), ShouldEqual, "_doc")
+		So(docType(DistributionElasticsearch, "8.1.0"), ShouldEqual, "")
+		So(docType(DistributionOpenSearch, "1.3.0"), ShouldEqual, "_doc")
+		So(docType(DistributionOpenSearch, "2.0.0"), ShouldEqual, "")
+	})
+
+	Convey("should use the configured version of a cluster", t, func() {
+		r, err := newClusterRegistry(`{"search": {"url": "http://search:9200", "distribution": "opensearch", "version": "2.11.0"}}`, "", "")
+		So(err, ShouldBeNil)
+		cluster := r.clusters["search"]
+		So(cluster.GetSemanticVersion(), ShouldEqual, openSearchCompatibleVersion)
+		So(cluster.GetVersion(), ShouldEqual, 7)
+		So(cluster.GetDocType(), ShouldEqual, "")
+		So(cluster.HiddenIndexSettings(), ShouldNotBeEmpty)
+
+		_, err = newClusterRegistry(`{"search": {"url": "http://search:9200", "distribution": "solr"}}`, "", "")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -1,9 +1,13 @@
 package util
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,6 +20,8 @@ import (
 
 var version int
 var semanticVersion string
+var distribution string
+var distributionVersion string
 
 var (
 	clientInit sync.Once
@@ -68,17 +74,7 @@ func GetESURL() string {
 func GetVersion() int {
 	// Get the version if not present
 	if version == 0 {
-		esVersion, err := client7.ElasticsearchVersion(GetESURL())
-		if err != nil {
-			log.Fatal("Error encountered: ", fmt.Errorf("error while retrieving the elastic version: %v", err))
-		}
-		var splitStr = strings.Split(esVersion, ".")
-		if len(splitStr) > 0 && splitStr[0] != "" {
-			version, _ = strconv.Atoi(splitStr[0])
-			if err != nil {
-				log.Errorln("Error encountered: error while calculating the elastic version", err)
-			}
-		}
+		loadVersion()
 	}
 	return version
 }
@@ -87,19 +83,63 @@ func GetVersion() int {
 func GetSemanticVersion() string {
 	// Get the version if not present
 	if semanticVersion == "" {
-		esVersion, err := client7.ElasticsearchVersion(GetESURL())
+		loadVersion()
+	}
+	return semanticVersion
+}
+
+// GetDistribution returns the distribution of the cluster, i.e. elasticsearch or opensearch
+func GetDistribution() string {
+	if distribution == "" {
+		loadVersion()
+	}
+	return distribution
+}
+
+// GetDistributionVersion returns the version of the distribution, it differs
+// from the es version for the opensearch clusters
+func GetDistributionVersion() string {
+	if distributionVersion == "" {
+		loadVersion()
+	}
+	return distributionVersion
+}
+
+// IsOpenSearch returns true if the cluster is an opensearch cluster
+func IsOpenSearch() bool {
+	return GetDistribution() == DistributionOpenSearch
+}
+
+// GetDocType returns the type of the documents in the bulk and search requests,
+// it is empty for the versions that have removed the mapping types
+func GetDocType() string {
+	return docType(GetDistribution(), GetDistributionVersion())
+}
+
+func loadVersion() {
+	info, err := fetchVersionInfo(client7)
+	if err != nil {
+		log.Fatal("Error encountered: ", fmt.Errorf("error while retrieving the elastic version: %v", err))
+	}
+	distribution = info.distribution()
+	distributionVersion = info.Number
+	semanticVersion = info.compatibleVersion()
+	var splitStr = strings.Split(semanticVersion, ".")
+	if len(splitStr) > 0 && splitStr[0] != "" {
+		version, err = strconv.Atoi(splitStr[0])
 		if err != nil {
-			log.Fatal("Error encountered: ", fmt.Errorf("error while retrieving the elastic version: %v", err))
-		} else {
-			semanticVersion = esVersion
+			log.Errorln("Error encountered: error while calculating the elastic version", err)
 		}
 	}
-	return semanticVersion
 }
 
 // HiddenIndexSettings to set plugin indices as hidden index
 func HiddenIndexSettings() string {
-	esVersion, _ := v.NewVersion(GetSemanticVersion())
+	return hiddenIndexSettings(GetSemanticVersion())
+}
+
+func hiddenIndexSettings(semver string) string {
+	esVersion, _ := v.NewVersion(semver)
 	hiddenIndexVersion, _ := v.NewVersion("7.7.0")
 	if esVersion.GreaterThanOrEqual(hiddenIndexVersion) {
 		return `"index.hidden": true,`
@@ -172,6 +212,45 @@ func NewClient() {
 		// Get the ES version
 		GetVersion()
 
-		log.Println("clients instantiated, elastic search version is", version)
+		log.Println("clients instantiated, elastic search version is", version, ", distribution is", distribution, GetDistributionVersion())
+		// Initialize the clients of the other clusters
+		initClusters()
 	})
 }
+
+// ResolveIndices replaces the aliases and the index patterns with the concrete indices
+// they point to, each of them is returned once. The index rules and the index limits
+// are only applied to the concrete indices.
+func ResolveIndices(ctx context.Context, indices []string) ([]string, error) {
+	// the indices of a request are resolved by the cluster that serves them
+	names := make(map[*Cluster][]string)
+	for _, index := range indices {
+		cluster := ClusterForIndex(index)
+		names[cluster] = append(names[cluster], url.PathEscape(index))
+	}
+	seen := make(map[string]bool)
+	var resolved []string
+	for cluster, clusterNames := range names {
+		response, err := cluster.Client7().PerformRequest(ctx, es7.PerformRequestOptions{
+			Method: http.MethodGet,
+			Path:   "/" + strings.Join(clusterNames, ",") + "/_alias",
+			Params: url.Values{"ignore_unavailable": {"true"}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// the response is keyed by the concrete indices
+		var aliases map[string]json.RawMessage
+		if err := json.Unmarshal(response.Body, &aliases); err != nil {
+			return nil, err
+		}
+		for index := range aliases {
+			if !seen[index] {
+				seen[index] = true
+				resolved = append(resolved, index)
+			}
+		}
+	}
+	sort.Strings(resolved)
+	return resolved, nil
+}
@@ -0,0 +1,104 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	es7 "github.com/olivere/elastic/v7"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	envReadStickiness     = "ES_READ_STICKINESS"
+	defaultReadStickiness = 5 * time.Second
+)
+
+var recentWrites = newWriteTracker()
+
+// writeTracker remembers the credentials that have recently written to a cluster,
+// their reads are served by the primary cluster until the replica catches up.
+type writeTracker struct {
+	mu        sync.Mutex
+	writes    map[string]time.Time
+	lastSweep time.Time
+}
+
+func newWriteTracker() *writeTracker {
+	return &writeTracker{writes: make(map[string]time.Time)}
+}
+
+// record makes the key sticky until the given time.
+func (t *writeTracker) record(key string, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writes[key] = until
+	// drop the expired keys at most once per stickiness window
+	now := time.Now()
+	if now.Sub(t.lastSweep) < until.Sub(now) {
+		return
+	}
+	for k, expiry := range t.writes {
+		if now.After(expiry) {
+			delete(t.writes, k)
+		}
+	}
+	t.lastSweep = now
+}
+
+// isSticky returns true if the key has written within the stickiness window.
+func (t *writeTracker) isSticky(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.writes[key]
+	return ok && time.Now().Before(until)
+}
+
+// readTarget returns the cluster that serves the read requests of the credential.
+func (c *Cluster) readTarget(credentialID string) *Cluster {
+	if c.read == nil {
+		return c
+	}
+	if credentialID != "" && recentWrites.isSticky(c.Name+":"+credentialID) {
+		return c
+	}
+	return c.read
+}
+
+// PerformClusterRequest performs the request on the cluster with PerformESRequest. The
+// read requests are served by the read cluster, if configured, unless the credential
+// has written to the cluster within ES_READ_STICKINESS. The reads fall back to the
+// cluster if the read cluster is unavailable. The scroll and the point in time
+// continuations are always served by the cluster that opened the cursor.
+func PerformClusterRequest(ctx context.Context, cluster *Cluster, credentialID string, options es7.PerformRequestOptions, read bool) (*es7.Response, error) {
+	if pinned, ok := ClusterForCursor(options); ok {
+		res, err := PerformESRequest(ctx, pinned.Client7(), pinned.Upstream(), options, read)
+		recordCursor(pinned, options, res)
+		return res, err
+	}
+
+	if !read {
+		res, err := PerformESRequest(ctx, cluster.Client7(), cluster.Upstream(), options, false)
+		recordCursor(cluster, options, res)
+		if credentialID != "" {
+			stickiness := getEnvDuration(envReadStickiness, defaultReadStickiness)
+			recentWrites.record(cluster.Name+":"+credentialID, time.Now().Add(stickiness))
+		}
+		return res, err
+	}
+
+	target := cluster.readTarget(credentialID)
+	if target != cluster {
+		res, err := PerformESRequest(ctx, target.Client7(), target.Upstream(), options, true)
+		var unavailableErr *UpstreamUnavailableError
+		if !errors.As(err, &unavailableErr) && !isUpstreamFailure(res, err) {
+			recordCursor(target, options, res)
+			return res, err
+		}
+		log.Warnln("[readsplit] : read cluster", target.Name, "is unavailable, falling back to", cluster.Name)
+	}
+	res, err := PerformESRequest(ctx, cluster.Client7(), cluster.Upstream(), options, true)
+	recordCursor(cluster, options, res)
+	return res, err
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	es7 "github.com/olivere/elastic/v7"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testCluster returns a cluster served by the handler that counts its requests.
+func testCluster(t *testing.T, name string, calls *int32, status *int32) (*Cluster, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(atomic.LoadInt32(status)))
+		w.Write([]byte(`{"took":1}`))
+	}))
+	client, err := es7.NewClient(es7.SetURL(server.URL), es7.SetSniff(false), es7.SetHealthcheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster := &Cluster{Name: name, URL: server.URL}
+	cluster.clientInit.Do(func() {
+		cluster.client7 = client
+	})
+	return cluster, server.Close
+}
+
+func TestPerformClusterRequest(t *testing.T) {
+	var primaryCalls, replicaCalls int32
+	primaryStatus, replicaStatus := int32(http.StatusOK), int32(http.StatusOK)
+	primary, closePrimary := testCluster(t, "primary", &primaryCalls, &primaryStatus)
+	defer closePrimary()
+	replica, closeReplica := testCluster(t, "replica", &replicaCalls, &replicaStatus)
+	defer closeReplica()
+	primary.read = replica
+
+	os.Setenv(envRetryMax, "0")
+	defer os.Unsetenv(envRetryMax)
+	ctx := context.Background()
+	search := es7.PerformRequestOptions{Method: http.MethodGet, Path: "/books/_search"}
+	index := es7.PerformRequestOptions{Method: http.MethodPost, Path: "/books/_doc", Body: `{}`}
+	reset := func() {
+		atomic.StoreInt32(&primaryCalls, 0)
+		atomic.StoreInt32(&replicaCalls, 0)
+	}
+
+	Convey("should send the reads to the read cluster and the writes to the primary", t, func() {
+		reset()
+		_, err := PerformClusterRequest(ctx, primary, "reader", search, true)
+		So(err, ShouldBeNil)
+		_, err = PerformClusterRequest(ctx, primary, "reader", index, false)
+		So(err, ShouldBeNil)
+		So(atomic.LoadInt32(&replicaCalls), ShouldEqual, 1)
+		So(atomic.LoadInt32(&primaryCalls), ShouldEqual, 1)
+	})
+
+	Convey("should read from the primary after a write of the same credential", t, func() {
+		reset()
+		_, err := PerformClusterRequest(ctx, primary, "writer", index, false)
+		So(err, ShouldBeNil)
+		_, err = PerformClusterRequest(ctx, primary, "writer", search, true)
+		So(err, ShouldBeNil)
+		So(atomic.LoadInt32(&primaryCalls), ShouldEqual, 2)
+		_, err = PerformClusterRequest(ctx, primary, "other", search, true)
+		So(err, ShouldBeNil)
+		So(atomic.LoadInt32(&replicaCalls), ShouldEqual, 1)
+	})
+
+	Convey("should fall back to the primary when the read cluster is down", t, func() {
+		reset()
+		atomic.StoreInt32(&replicaStatus, http.StatusServiceUnavailable)
+		defer atomic.StoreInt32(&replicaStatus, http.StatusOK)
+		res, err := PerformClusterRequest(ctx, primary, "fallback", search, true)
+		So(err, ShouldBeNil)
+		So(res.StatusCode, ShouldEqual, http.StatusOK)
+		So(atomic.LoadInt32(&replicaCalls), ShouldEqual, 1)
+		So(atomic.LoadInt32(&primaryCalls), ShouldEqual, 1)
+	})
+
+	Convey("should continue the cursors on the cluster that opened them", t, func() {
+		reset()
+		open := es7.PerformRequestOptions{Method: http.MethodGet, Path: "/books/_search", Params: url.Values{"scroll": {"1m"}}}
+		recordCursor(replica, open, &es7.Response{Body: []byte(`{"_scroll_id":"replica-scroll"}`)})
+		scroll := es7.PerformRequestOptions{Method: http.MethodPost, Path: "/_search/scroll", Body: `{"scroll_id":"replica-scroll"}`}
+
+		// the writes of the credential don't move the cursor to the primary
+		_, err := PerformClusterRequest(ctx, primary, "scroller", index, false)
+		So(err, ShouldBeNil)
+		_, err = PerformClusterRequest(ctx, primary, "scroller", scroll, true)
+		So(err, ShouldBeNil)
+		So(atomic.LoadInt32(&replicaCalls), ShouldEqual, 1)
+
+		// nor the failures of the read cluster
+		atomic.StoreInt32(&replicaStatus, http.StatusServiceUnavailable)
+		defer atomic.StoreInt32(&replicaStatus, http.StatusOK)
+		_, err = PerformClusterRequest(ctx, primary, "scroller", scroll, true)
+		So(err, ShouldNotBeNil)
+		So(atomic.LoadInt32(&primaryCalls), ShouldEqual, 1)
+	})
+}
+
+func TestWriteTracker(t *testing.T) {
+	Convey("should drop the expired writes", t, func() {
+		tracker := newWriteTracker()
+		tracker.record("expired", time.Now().Add(-time.Second))
+		So(tracker.isSticky("expired"), ShouldBeFalse)
+		tracker.record("recent", time.Now().Add(time.Minute))
+		So(tracker.isSticky("recent"), ShouldBeTrue)
+		So(tracker.writes, ShouldNotContainKey, "expired")
+	})
+}
@@ -96,6 +96,23 @@ func WriteBackError(w http.ResponseWriter, err string, code int) {
 	json.NewEncoder(w).Encode(msg)
 }
 
+// WriteBackTypedError writes the given error message as a json response to the response
+// writer, the error type allows the clients to identify the error without parsing the message.
+func WriteBackTypedError(w http.ResponseWriter, errType string, err string, code int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	msg := map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"status":  http.StatusText(code),
+			"type":    errType,
+			"message": err,
+		},
+	}
+	json.NewEncoder(w).Encode(msg)
+}
+
 // WriteBackRaw writes the given json encoded bytes to the response writer.
 func WriteBackRaw(w http.ResponseWriter, raw []byte, code int) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -233,7 +250,7 @@ func HTTPClient() *http.Client {
 		}
 		var netClient = &http.Client{
 			Timeout:   time.Minute * 2,
-			Transport: netTransport,
+			Transport: &compatibleTransport{netTransport},
 		}
 		client = netClient
 	})